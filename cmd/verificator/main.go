@@ -2,11 +2,12 @@ package main
 
 import (
 	"context"
+	"io"
 	"log/slog"
+	"net/mail"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
 	"github.com/udisondev/learn-go/internal/email"
 	"github.com/udisondev/learn-go/pkg/config"
@@ -50,123 +51,56 @@ func main() {
 	// Initialize email queue
 	queue := email.NewQueue(db)
 
-	// Initialize SMTP client
-	smtpClient, err := email.NewSMTPClient(&cfg.Email)
+	// Initialize mailer (EMAIL_DRIVER: smtp, api, outbox, memory)
+	mailer, err := email.NewMailer(&cfg.Email)
 	if err != nil {
-		slog.Error("Failed to create SMTP client", "error", err)
+		slog.Error("Failed to create mailer", "error", err)
 		os.Exit(1)
 	}
 
-	// Initialize email sender with templates
-	sender, err := email.NewSender(smtpClient, "web/templates/email")
+	// Initialize email sender with templates and inline images
+	from := (&mail.Address{Name: cfg.Email.FromName, Address: cfg.Email.From}).String()
+	sender, err := email.NewSender(mailer, from, "web/templates/email", "web/static/images/gophers")
 	if err != nil {
 		slog.Error("Failed to create email sender", "error", err)
 		os.Exit(1)
 	}
 
+	// SMTP keeps pooled connections open - QUIT them on exit
+	if closer, ok := mailer.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// Initialize worker pool
+	pool, err := email.NewPool(queue, sender, cfg.EmailQueue)
+	if err != nil {
+		slog.Error("Failed to create email worker pool", "error", err)
+		os.Exit(1)
+	}
+
 	slog.Info("Email worker initialized",
-		"smtp_host", cfg.Email.Host,
-		"smtp_port", cfg.Email.Port,
-		"poll_interval", cfg.Executor.PollInterval,
+		"pool_id", pool.ID(),
+		"driver", cfg.Email.Driver,
+		"workers", cfg.EmailQueue.Workers,
+		"batch_size", cfg.EmailQueue.BatchSize,
+		"poll_interval", cfg.EmailQueue.PollInterval,
+		"lease", cfg.EmailQueue.Lease,
 	)
 
 	// Setup graceful shutdown
-	// WHY: Allow worker to finish current task before exiting
-	// HOW: Listen for SIGINT/SIGTERM and cancel context
+	// WHY: Allow workers to finish in-flight emails before exiting
+	// HOW: Listen for SIGINT/SIGTERM and cancel context, the pool drains
+	// within EMAIL_QUEUE_SHUTDOWN_TIMEOUT
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
 	go func() {
 		<-sigChan
-		slog.Info("Shutdown signal received, finishing current task...")
+		slog.Info("Shutdown signal received, finishing in-flight emails...")
 		cancel()
 	}()
 
-	// Main processing loop
-	// WHY: Continuously poll for new tasks and process them
-	// HOW: Use ticker with configurable interval to check for tasks
-	slog.Info("Starting task processing loop")
-
-	ticker := time.NewTicker(cfg.Executor.PollInterval)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case <-ctx.Done():
-			slog.Info("Worker stopped gracefully")
-			return
-
-		case <-ticker.C:
-			// Process one task
-			if err := processNextTask(ctx, queue, sender); err != nil {
-				slog.Error("Error processing task", "error", err)
-			}
-		}
-	}
-}
-
-// processNextTask dequeues and processes a single email task
-// WHY: Separates task processing logic for better testability
-// HOW: Dequeue → Send → Mark completed/failed
-func processNextTask(ctx context.Context, queue *email.Queue, sender *email.Sender) error {
-	// Dequeue next task
-	task, err := queue.Dequeue(ctx)
-	if err != nil {
-		return err
-	}
-
-	// No tasks available
-	if task == nil {
-		return nil
-	}
-
-	slog.Info("Processing email task",
-		"task_id", task.ID,
-		"email_type", task.EmailType.String(),
-		"recipient", task.RecipientEmail,
-		"attempt", task.Attempts,
-	)
-
-	// Send email
-	if err := sender.Send(ctx, task); err != nil {
-		// Email sending failed - mark for retry
-		slog.Error("Failed to send email",
-			"task_id", task.ID,
-			"error", err,
-			"attempts", task.Attempts,
-			"max_attempts", task.MaxAttempts,
-		)
-
-		if markErr := queue.MarkFailed(ctx, task.ID, task.Attempts, task.MaxAttempts, err.Error()); markErr != nil {
-			slog.Error("Failed to mark task as failed", "task_id", task.ID, "error", markErr)
-		}
-
-		if task.Attempts >= task.MaxAttempts {
-			slog.Warn("Task permanently failed after max attempts",
-				"task_id", task.ID,
-				"attempts", task.Attempts,
-			)
-		} else {
-			slog.Info("Task will be retried",
-				"task_id", task.ID,
-				"next_attempt", task.Attempts+1,
-			)
-		}
-
-		return err
-	}
-
-	// Email sent successfully - mark as completed
-	if err := queue.MarkCompleted(ctx, task.ID); err != nil {
-		slog.Error("Failed to mark task as completed", "task_id", task.ID, "error", err)
-		return err
-	}
-
-	slog.Info("Email sent successfully",
-		"task_id", task.ID,
-		"email_type", task.EmailType.String(),
-		"recipient", task.RecipientEmail,
-	)
-
-	return nil
+	slog.Info("Starting email worker pool")
+	pool.Run(ctx)
+	slog.Info("Worker stopped gracefully")
 }
@@ -0,0 +1,21 @@
+package apitoken
+
+import "context"
+
+// ctxKey is a type-safe context key for the current access token
+type ctxKey struct{}
+
+// WithCtx adds access token of the request to context
+// WHY: Scope checks need to know the request was made with a token,
+// cookie-authenticated requests have no token and full access
+// HOW: Set by Bearer middleware together with user.WithCtx
+func WithCtx(ctx context.Context, t *Token) context.Context {
+	return context.WithValue(ctx, ctxKey{}, t)
+}
+
+// FromCtx retrieves access token from context
+// Returns (token, true) if request is authenticated by a bearer token, (nil, false) otherwise
+func FromCtx(ctx context.Context) (*Token, bool) {
+	t, ok := ctx.Value(ctxKey{}).(*Token)
+	return t, ok
+}
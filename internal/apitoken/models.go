@@ -0,0 +1,51 @@
+package apitoken
+
+//go:generate go-enum --names --nocase
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// Scope limits what a personal access token can do
+// - read: profile, progress and submissions history
+// - submit: run and submit exercise solutions
+// ENUM(read, submit)
+type Scope int
+
+var (
+	// ErrTokenNotFound - token doesn't exist or belongs to another user
+	ErrTokenNotFound = errors.New("access token not found")
+
+	// ErrInvalidToken - bearer token is unknown, revoked or expired
+	ErrInvalidToken = errors.New("invalid access token")
+
+	// ErrTooManyTokens - user reached the tokens limit
+	ErrTooManyTokens = errors.New("too many access tokens")
+)
+
+// Token is a personal access token as shown on the profile page
+// The secret itself is never stored, only its hash and a short prefix
+// to help users tell tokens apart
+type Token struct {
+	ID         int64
+	UserID     int64
+	Name       string
+	Prefix     string // first characters of the token, e.g. "lgo_3f9a1c"
+	Scopes     []Scope
+	ExpiresAt  *time.Time // nil - never expires
+	LastUsedAt *time.Time
+	LastUsedIP string
+	CreatedAt  time.Time
+}
+
+// HasScope reports whether the token grants the scope
+func (t *Token) HasScope(scope Scope) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Expired reports whether the token is no longer valid at now
+func (t *Token) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
@@ -0,0 +1,73 @@
+// Code generated by go-enum DO NOT EDIT.
+// Version: v0.9.1
+
+// Built By: go install
+
+package apitoken
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// ScopeRead is a Scope of type Read.
+	ScopeRead Scope = iota
+	// ScopeSubmit is a Scope of type Submit.
+	ScopeSubmit
+)
+
+var ErrInvalidScope = fmt.Errorf("not a valid Scope, try [%s]", strings.Join(_ScopeNames, ", "))
+
+const _ScopeName = "readsubmit"
+
+var _ScopeNames = []string{
+	_ScopeName[0:4],
+	_ScopeName[4:10],
+}
+
+// ScopeNames returns a list of possible string values of Scope.
+func ScopeNames() []string {
+	tmp := make([]string, len(_ScopeNames))
+	copy(tmp, _ScopeNames)
+	return tmp
+}
+
+var _ScopeMap = map[Scope]string{
+	ScopeRead:   _ScopeName[0:4],
+	ScopeSubmit: _ScopeName[4:10],
+}
+
+// String implements the Stringer interface.
+func (x Scope) String() string {
+	if str, ok := _ScopeMap[x]; ok {
+		return str
+	}
+	return fmt.Sprintf("Scope(%d)", x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x Scope) IsValid() bool {
+	_, ok := _ScopeMap[x]
+	return ok
+}
+
+var _ScopeValue = map[string]Scope{
+	_ScopeName[0:4]:                   ScopeRead,
+	strings.ToLower(_ScopeName[0:4]):  ScopeRead,
+	_ScopeName[4:10]:                  ScopeSubmit,
+	strings.ToLower(_ScopeName[4:10]): ScopeSubmit,
+}
+
+// ParseScope attempts to convert a string to a Scope.
+func ParseScope(name string) (Scope, error) {
+	if x, ok := _ScopeValue[name]; ok {
+		return x, nil
+	}
+	// Case insensitive parse, do a separate lookup to prevent unnecessary cost of lowercasing a string if we don't need to.
+	if x, ok := _ScopeValue[strings.ToLower(name)]; ok {
+		return x, nil
+	}
+	return Scope(0), fmt.Errorf("%s is %w", name, ErrInvalidScope)
+}
@@ -0,0 +1,274 @@
+package apitoken
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+var psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+// tokenColumns are selected for every Token, in scanToken order
+var tokenColumns = []string{
+	"t.id",
+	"t.user_id",
+	"t.name",
+	"t.token_prefix",
+	"t.scopes",
+	"t.expires_at",
+	"t.last_used_at",
+	"COALESCE(t.last_used_ip, '')",
+	"t.created_at",
+}
+
+// Repository handles access tokens data access
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+// NewRepository creates new access token repository
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// Create inserts a new token and fills its ID
+func (r *Repository) Create(ctx context.Context, t *Token, tokenHash string) error {
+	query, args, err := psql.
+		Insert("access_tokens").
+		Columns("user_id", "name", "token_hash", "token_prefix", "scopes", "expires_at", "created_at").
+		Values(t.UserID, t.Name, tokenHash, t.Prefix, scopeNames(t.Scopes), t.ExpiresAt, t.CreatedAt).
+		Suffix("RETURNING id").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&t.ID); err != nil {
+		return fmt.Errorf("failed to create access token: %w", err)
+	}
+
+	return nil
+}
+
+// CountByUserID returns number of user's tokens, expired included
+func (r *Repository) CountByUserID(ctx context.Context, userID int64) (int, error) {
+	query, args, err := psql.
+		Select("COUNT(*)").
+		From("access_tokens").
+		Where(sq.Eq{"user_id": userID}).
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count access tokens: %w", err)
+	}
+
+	return count, nil
+}
+
+// ListByUserID returns all user's tokens, newest first
+// Expired tokens are included - the profile page shows them as expired
+func (r *Repository) ListByUserID(ctx context.Context, userID int64) ([]Token, error) {
+	query, args, err := psql.
+		Select(tokenColumns...).
+		From("access_tokens t").
+		Where(sq.Eq{"t.user_id": userID}).
+		OrderBy("t.created_at DESC").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list access tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []Token
+	for rows.Next() {
+		var t Token
+		if err := scanToken(rows, &t); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate access tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// GetUserByTokenHash returns an unexpired token and its owner
+// Returns ErrInvalidToken if token is unknown, revoked, expired or its owner is suspended
+func (r *Repository) GetUserByTokenHash(ctx context.Context, tokenHash string) (*user.User, *Token, error) {
+	columns := append([]string{
+		"u.id",
+		"u.name",
+		"u.email",
+		"u.password_hash",
+		"u.phone",
+		"u.registered_at",
+		"u.updated_at",
+		"u.sub_plan",
+		"u.score",
+		"u.is_verified",
+		"u.avatar_url",
+		"u.suspended_at",
+		user.RolesColumn("u.id"),
+	}, tokenColumns...)
+
+	query, args, err := psql.
+		Select(columns...).
+		From("access_tokens t").
+		Join("users u ON u.id = t.user_id").
+		Where(sq.Eq{"u.suspended_at": nil}).
+		Where(sq.Eq{"t.token_hash": tokenHash}).
+		Where(sq.Or{
+			sq.Eq{"t.expires_at": nil},
+			sq.Gt{"t.expires_at": time.Now().UTC()},
+		}).
+		ToSql()
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var u user.User
+	var t Token
+	var scopes, roles []string
+	err = r.db.QueryRow(ctx, query, args...).Scan(
+		&u.ID,
+		&u.Name,
+		&u.Email,
+		&u.PasswordHash,
+		&u.Phone,
+		&u.RegisteredAt,
+		&u.UpdatedAt,
+		&u.SubPlan,
+		&u.Score,
+		&u.IsVerified,
+		&u.AvatarURL,
+		&u.SuspendedAt,
+		&roles,
+		&t.ID,
+		&t.UserID,
+		&t.Name,
+		&t.Prefix,
+		&scopes,
+		&t.ExpiresAt,
+		&t.LastUsedAt,
+		&t.LastUsedIP,
+		&t.CreatedAt,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get access token: %w", err)
+	}
+
+	u.Roles = user.ParseRoles(roles)
+	t.Scopes = parseScopes(scopes)
+
+	return &u, &t, nil
+}
+
+// Touch records token usage
+func (r *Repository) Touch(ctx context.Context, tokenID int64, usedAt time.Time, ip string) error {
+	query, args, err := psql.
+		Update("access_tokens").
+		Set("last_used_at", usedAt).
+		Set("last_used_ip", ip).
+		Where(sq.Eq{"id": tokenID}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to touch access token: %w", err)
+	}
+
+	return nil
+}
+
+// Delete revokes user's token
+// Returns ErrTokenNotFound if token doesn't exist or belongs to another user
+func (r *Repository) Delete(ctx context.Context, userID, tokenID int64) error {
+	query, args, err := psql.
+		Delete("access_tokens").
+		Where(sq.Eq{"id": tokenID, "user_id": userID}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete access token: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrTokenNotFound
+	}
+
+	return nil
+}
+
+// scanToken scans tokenColumns into t
+func scanToken(row pgx.Row, t *Token) error {
+	var scopes []string
+	err := row.Scan(
+		&t.ID,
+		&t.UserID,
+		&t.Name,
+		&t.Prefix,
+		&scopes,
+		&t.ExpiresAt,
+		&t.LastUsedAt,
+		&t.LastUsedIP,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to scan access token: %w", err)
+	}
+
+	t.Scopes = parseScopes(scopes)
+	return nil
+}
+
+// scopeNames converts scopes to TEXT[] values
+func scopeNames(scopes []Scope) []string {
+	names := make([]string, 0, len(scopes))
+	for _, s := range scopes {
+		names = append(names, s.String())
+	}
+	return names
+}
+
+// parseScopes converts TEXT[] values to scopes
+// Unknown names (scope removed from code) are skipped, not granted
+func parseScopes(names []string) []Scope {
+	scopes := make([]Scope, 0, len(names))
+	for _, name := range names {
+		if s, err := ParseScope(name); err == nil {
+			scopes = append(scopes, s)
+		}
+	}
+	return scopes
+}
@@ -0,0 +1,130 @@
+package apitoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/udisondev/learn-go/internal/user"
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+const (
+	// tokenPrefix marks our tokens, so they are easy to spot in configs
+	// and secret scanners can match them
+	tokenPrefix = "lgo_"
+
+	// displayPrefixLen - characters of the token shown on the profile page
+	displayPrefixLen = len(tokenPrefix) + 6
+)
+
+// Service manages personal access tokens
+// WHY: Terminal client and editor plugins can't use the session cookie
+// HOW: Random token sent as "Authorization: Bearer lgo_...", only its hash is stored
+// (same scheme as email tokens in user.Repository), each token has a name,
+// scopes and an optional expiry
+type Service struct {
+	repo          *Repository
+	maxPerUser    int
+	touchInterval time.Duration
+}
+
+// NewService creates new access token service
+func NewService(db *pgxpool.Pool, cfg config.APITokenConfig) *Service {
+	return &Service{
+		repo:          NewRepository(db),
+		maxPerUser:    cfg.MaxPerUser,
+		touchInterval: cfg.TouchInterval,
+	}
+}
+
+// Create issues a new token
+// ttl == 0 means the token never expires
+//
+// Returns the token and its secret - the secret is shown to the user once
+// and can't be recovered later
+func (s *Service) Create(ctx context.Context, userID int64, name string, scopes []Scope, ttl time.Duration) (*Token, string, error) {
+	if s.maxPerUser > 0 {
+		count, err := s.repo.CountByUserID(ctx, userID)
+		if err != nil {
+			return nil, "", err
+		}
+		if count >= s.maxPerUser {
+			return nil, "", ErrTooManyTokens
+		}
+	}
+
+	secret := tokenPrefix + generateToken()
+	now := time.Now().UTC()
+
+	t := &Token{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    secret[:displayPrefixLen],
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	if ttl > 0 {
+		expiresAt := now.Add(ttl)
+		t.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.Create(ctx, t, hashToken(secret)); err != nil {
+		return nil, "", err
+	}
+
+	return t, secret, nil
+}
+
+// List returns user's tokens for the profile page
+func (s *Service) List(ctx context.Context, userID int64) ([]Token, error) {
+	return s.repo.ListByUserID(ctx, userID)
+}
+
+// Authenticate resolves bearer token to its owner
+// Records usage (time and IP) at most once per touchInterval, so
+// a busy CLI doesn't write to DB on every request
+//
+// Returns ErrInvalidToken if token is malformed, unknown, revoked or expired
+func (s *Service) Authenticate(ctx context.Context, secret, ip string) (*user.User, *Token, error) {
+	if !strings.HasPrefix(secret, tokenPrefix) {
+		return nil, nil, ErrInvalidToken
+	}
+
+	u, t, err := s.repo.GetUserByTokenHash(ctx, hashToken(secret))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= s.touchInterval || t.LastUsedIP != ip {
+		if err := s.repo.Touch(ctx, t.ID, now, ip); err != nil {
+			return nil, nil, err
+		}
+		t.LastUsedAt = &now
+		t.LastUsedIP = ip
+	}
+
+	return u, t, nil
+}
+
+// Revoke deletes user's token, it stops working immediately
+func (s *Service) Revoke(ctx context.Context, userID, tokenID int64) error {
+	return s.repo.Delete(ctx, userID, tokenID)
+}
+
+// generateToken creates token secret (same scheme as email tokens in user.Repository)
+func generateToken() string {
+	hash := sha256.Sum256([]byte(rand.Text()))
+	return hex.EncodeToString(hash[:])
+}
+
+// hashToken hashes token secret for storage
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -8,11 +8,21 @@ import (
 	"os"
 	"time"
 
+	"github.com/udisondev/learn-go/internal/apitoken"
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/clientip"
 	"github.com/udisondev/learn-go/internal/email"
 	"github.com/udisondev/learn-go/internal/handler"
+	"github.com/udisondev/learn-go/internal/impersonation"
+	"github.com/udisondev/learn-go/internal/loginguard"
+	"github.com/udisondev/learn-go/internal/middleware"
+	"github.com/udisondev/learn-go/internal/oauth"
+	"github.com/udisondev/learn-go/internal/password"
+	"github.com/udisondev/learn-go/internal/ratelimit"
 	"github.com/udisondev/learn-go/internal/router"
 	"github.com/udisondev/learn-go/internal/session"
 	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/twofactor"
 	"github.com/udisondev/learn-go/internal/user"
 	"github.com/udisondev/learn-go/pkg/config"
 	"github.com/udisondev/learn-go/pkg/postgres"
@@ -44,8 +54,60 @@ func Run(ctx context.Context, cfg *config.Config) error {
 	defer db.Close()
 
 	// 3. Initialize services
-	userService := user.NewService(db)
-	sessionService := session.NewService(db)
+	locator, err := session.NewGeoLocator(cfg.Session.GeoIPFile)
+	if err != nil {
+		return fmt.Errorf("failed to load geoip file: %w", err)
+	}
+
+	passwords, err := password.NewHasher(cfg.Password)
+	if err != nil {
+		return fmt.Errorf("invalid password hashing config: %w", err)
+	}
+
+	passwordPolicy, err := password.NewPolicy(cfg.Password)
+	if err != nil {
+		return fmt.Errorf("invalid password policy config: %w", err)
+	}
+
+	userService := user.NewService(db, passwords, passwordPolicy)
+
+	sessionStore, err := session.NewStore(cfg.Session, db, userService)
+	if err != nil {
+		return fmt.Errorf("failed to init session store: %w", err)
+	}
+	sessionService := session.NewService(sessionStore, cfg.Session, locator)
+
+	if cfg.TwoFactor.EncryptionKey == config.DefaultTOTPEncryptionKey &&
+		cfg.App.Env != middleware.EnvDevelopment && cfg.App.Env != middleware.EnvTest {
+		return fmt.Errorf("TOTP_ENCRYPTION_KEY must be set in %s", cfg.App.Env)
+	}
+
+	twoFactorService, err := twofactor.NewService(db, cfg.TwoFactor)
+	if err != nil {
+		return fmt.Errorf("failed to init two-factor service: %w", err)
+	}
+
+	oauthService := oauth.NewService(cfg.OAuth, cfg.App.BaseURL, cfg.Session.Secure)
+
+	loginGuard := loginguard.NewService(db, cfg.Login)
+
+	apiTokens := apitoken.NewService(db, cfg.APIToken)
+
+	auditService := audit.NewService(db, cfg.Audit)
+
+	impersonationService := impersonation.NewService(db, cfg.Impersonation, userService)
+
+	rateLimitStore, err := ratelimit.NewStore(cfg.RateLimit.Store, db)
+	if err != nil {
+		return fmt.Errorf("failed to init rate limit store: %w", err)
+	}
+	rateLimiter := ratelimit.New(rateLimitStore, cfg.RateLimit)
+
+	// Delete expired sessions, old login attempts, audit events and full rate limit buckets in background, stops with ctx
+	go sessionService.RunSweeper(ctx, cfg.Session.SweepInterval)
+	go loginGuard.RunSweeper(ctx, cfg.Login.SweepInterval)
+	go auditService.RunSweeper(ctx, cfg.Audit.SweepInterval)
+	go rateLimiter.RunSweeper(ctx, cfg.RateLimit.SweepInterval)
 
 	// 4. Initialize email queue
 	emailQueue := email.NewQueue(db)
@@ -57,10 +119,15 @@ func Run(ctx context.Context, cfg *config.Config) error {
 	}
 
 	// 6. Initialize handler
-	h := handler.New(tmpl, userService, sessionService, emailQueue, cfg)
+	h := handler.New(tmpl, userService, sessionService, twoFactorService, oauthService, loginGuard, apiTokens, auditService, impersonationService, emailQueue, rateLimiter, cfg)
 
 	// 7. Initialize router
-	r := router.New(h, sessionService)
+	ipResolver, err := clientip.New(cfg.Proxy)
+	if err != nil {
+		return fmt.Errorf("invalid proxy config: %w", err)
+	}
+
+	r := router.New(h, sessionService, apiTokens, impersonationService, auditService, rateLimiter, ipResolver, cfg)
 
 	// 8. Create HTTP server
 	srv := &http.Server{
@@ -0,0 +1,33 @@
+package audit
+
+//go:generate go-enum --names --nocase
+
+import "time"
+
+// EventType is a kind of security-relevant account event
+// ENUM(login_succeeded, login_failed, logout, email_verified, password_changed, session_revoked, sessions_revoked, two_factor_enabled, two_factor_disabled, access_token_created, access_token_revoked, plan_changed, account_suspended, account_unsuspended, impersonation_started, impersonation_stopped)
+type EventType int
+
+// Event is one record of the audit log
+type Event struct {
+	ID        int64
+	UserID    *int64 // nil - failed login with unknown email
+	Type      EventType
+	IP        string
+	UserAgent string
+	RequestID string
+	Details   map[string]string // event specific: login method, failure reason, token name...
+	CreatedAt time.Time
+}
+
+// Filter selects events for the admin query endpoint
+// Zero fields don't filter, results are ordered newest first
+type Filter struct {
+	UserID   *int64
+	Types    []EventType
+	IP       string
+	Since    time.Time
+	Until    time.Time
+	BeforeID int64 // keyset pagination: only events with smaller ID
+	Limit    int
+}
@@ -0,0 +1,157 @@
+// Code generated by go-enum DO NOT EDIT.
+// Version: v0.9.1
+
+// Built By: go install
+
+package audit
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// EventTypeLoginSucceeded is a EventType of type Login_succeeded.
+	EventTypeLoginSucceeded EventType = iota
+	// EventTypeLoginFailed is a EventType of type Login_failed.
+	EventTypeLoginFailed
+	// EventTypeLogout is a EventType of type Logout.
+	EventTypeLogout
+	// EventTypeEmailVerified is a EventType of type Email_verified.
+	EventTypeEmailVerified
+	// EventTypePasswordChanged is a EventType of type Password_changed.
+	EventTypePasswordChanged
+	// EventTypeSessionRevoked is a EventType of type Session_revoked.
+	EventTypeSessionRevoked
+	// EventTypeSessionsRevoked is a EventType of type Sessions_revoked.
+	EventTypeSessionsRevoked
+	// EventTypeTwoFactorEnabled is a EventType of type Two_factor_enabled.
+	EventTypeTwoFactorEnabled
+	// EventTypeTwoFactorDisabled is a EventType of type Two_factor_disabled.
+	EventTypeTwoFactorDisabled
+	// EventTypeAccessTokenCreated is a EventType of type Access_token_created.
+	EventTypeAccessTokenCreated
+	// EventTypeAccessTokenRevoked is a EventType of type Access_token_revoked.
+	EventTypeAccessTokenRevoked
+	// EventTypePlanChanged is a EventType of type Plan_changed.
+	EventTypePlanChanged
+	// EventTypeAccountSuspended is a EventType of type Account_suspended.
+	EventTypeAccountSuspended
+	// EventTypeAccountUnsuspended is a EventType of type Account_unsuspended.
+	EventTypeAccountUnsuspended
+	// EventTypeImpersonationStarted is a EventType of type Impersonation_started.
+	EventTypeImpersonationStarted
+	// EventTypeImpersonationStopped is a EventType of type Impersonation_stopped.
+	EventTypeImpersonationStopped
+)
+
+var ErrInvalidEventType = fmt.Errorf("not a valid EventType, try [%s]", strings.Join(_EventTypeNames, ", "))
+
+const _EventTypeName = "login_succeededlogin_failedlogoutemail_verifiedpassword_changedsession_revokedsessions_revokedtwo_factor_enabledtwo_factor_disabledaccess_token_createdaccess_token_revokedplan_changedaccount_suspendedaccount_unsuspendedimpersonation_startedimpersonation_stopped"
+
+var _EventTypeNames = []string{
+	_EventTypeName[0:15],
+	_EventTypeName[15:27],
+	_EventTypeName[27:33],
+	_EventTypeName[33:47],
+	_EventTypeName[47:63],
+	_EventTypeName[63:78],
+	_EventTypeName[78:94],
+	_EventTypeName[94:112],
+	_EventTypeName[112:131],
+	_EventTypeName[131:151],
+	_EventTypeName[151:171],
+	_EventTypeName[171:183],
+	_EventTypeName[183:200],
+	_EventTypeName[200:219],
+	_EventTypeName[219:240],
+	_EventTypeName[240:261],
+}
+
+// EventTypeNames returns a list of possible string values of EventType.
+func EventTypeNames() []string {
+	tmp := make([]string, len(_EventTypeNames))
+	copy(tmp, _EventTypeNames)
+	return tmp
+}
+
+var _EventTypeMap = map[EventType]string{
+	EventTypeLoginSucceeded:       _EventTypeName[0:15],
+	EventTypeLoginFailed:          _EventTypeName[15:27],
+	EventTypeLogout:               _EventTypeName[27:33],
+	EventTypeEmailVerified:        _EventTypeName[33:47],
+	EventTypePasswordChanged:      _EventTypeName[47:63],
+	EventTypeSessionRevoked:       _EventTypeName[63:78],
+	EventTypeSessionsRevoked:      _EventTypeName[78:94],
+	EventTypeTwoFactorEnabled:     _EventTypeName[94:112],
+	EventTypeTwoFactorDisabled:    _EventTypeName[112:131],
+	EventTypeAccessTokenCreated:   _EventTypeName[131:151],
+	EventTypeAccessTokenRevoked:   _EventTypeName[151:171],
+	EventTypePlanChanged:          _EventTypeName[171:183],
+	EventTypeAccountSuspended:     _EventTypeName[183:200],
+	EventTypeAccountUnsuspended:   _EventTypeName[200:219],
+	EventTypeImpersonationStarted: _EventTypeName[219:240],
+	EventTypeImpersonationStopped: _EventTypeName[240:261],
+}
+
+// String implements the Stringer interface.
+func (x EventType) String() string {
+	if str, ok := _EventTypeMap[x]; ok {
+		return str
+	}
+	return fmt.Sprintf("EventType(%d)", x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x EventType) IsValid() bool {
+	_, ok := _EventTypeMap[x]
+	return ok
+}
+
+var _EventTypeValue = map[string]EventType{
+	_EventTypeName[0:15]:                     EventTypeLoginSucceeded,
+	strings.ToLower(_EventTypeName[0:15]):    EventTypeLoginSucceeded,
+	_EventTypeName[15:27]:                    EventTypeLoginFailed,
+	strings.ToLower(_EventTypeName[15:27]):   EventTypeLoginFailed,
+	_EventTypeName[27:33]:                    EventTypeLogout,
+	strings.ToLower(_EventTypeName[27:33]):   EventTypeLogout,
+	_EventTypeName[33:47]:                    EventTypeEmailVerified,
+	strings.ToLower(_EventTypeName[33:47]):   EventTypeEmailVerified,
+	_EventTypeName[47:63]:                    EventTypePasswordChanged,
+	strings.ToLower(_EventTypeName[47:63]):   EventTypePasswordChanged,
+	_EventTypeName[63:78]:                    EventTypeSessionRevoked,
+	strings.ToLower(_EventTypeName[63:78]):   EventTypeSessionRevoked,
+	_EventTypeName[78:94]:                    EventTypeSessionsRevoked,
+	strings.ToLower(_EventTypeName[78:94]):   EventTypeSessionsRevoked,
+	_EventTypeName[94:112]:                   EventTypeTwoFactorEnabled,
+	strings.ToLower(_EventTypeName[94:112]):  EventTypeTwoFactorEnabled,
+	_EventTypeName[112:131]:                  EventTypeTwoFactorDisabled,
+	strings.ToLower(_EventTypeName[112:131]): EventTypeTwoFactorDisabled,
+	_EventTypeName[131:151]:                  EventTypeAccessTokenCreated,
+	strings.ToLower(_EventTypeName[131:151]): EventTypeAccessTokenCreated,
+	_EventTypeName[151:171]:                  EventTypeAccessTokenRevoked,
+	strings.ToLower(_EventTypeName[151:171]): EventTypeAccessTokenRevoked,
+	_EventTypeName[171:183]:                  EventTypePlanChanged,
+	strings.ToLower(_EventTypeName[171:183]): EventTypePlanChanged,
+	_EventTypeName[183:200]:                  EventTypeAccountSuspended,
+	strings.ToLower(_EventTypeName[183:200]): EventTypeAccountSuspended,
+	_EventTypeName[200:219]:                  EventTypeAccountUnsuspended,
+	strings.ToLower(_EventTypeName[200:219]): EventTypeAccountUnsuspended,
+	_EventTypeName[219:240]:                  EventTypeImpersonationStarted,
+	strings.ToLower(_EventTypeName[219:240]): EventTypeImpersonationStarted,
+	_EventTypeName[240:261]:                  EventTypeImpersonationStopped,
+	strings.ToLower(_EventTypeName[240:261]): EventTypeImpersonationStopped,
+}
+
+// ParseEventType attempts to convert a string to a EventType.
+func ParseEventType(name string) (EventType, error) {
+	if x, ok := _EventTypeValue[name]; ok {
+		return x, nil
+	}
+	// Case insensitive parse, do a separate lookup to prevent unnecessary cost of lowercasing a string if we don't need to.
+	if x, ok := _EventTypeValue[strings.ToLower(name)]; ok {
+		return x, nil
+	}
+	return EventType(0), fmt.Errorf("%s is %w", name, ErrInvalidEventType)
+}
@@ -0,0 +1,148 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+// Repository handles audit events data access
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+// NewRepository creates new audit repository
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// Insert saves an event and fills its ID
+func (r *Repository) Insert(ctx context.Context, e *Event) error {
+	details := e.Details
+	if details == nil {
+		details = map[string]string{}
+	}
+
+	query, args, err := psql.
+		Insert("audit_events").
+		Columns("user_id", "event_type", "ip_address", "user_agent", "request_id", "details", "created_at").
+		Values(e.UserID, e.Type.String(), e.IP, e.UserAgent, e.RequestID, details, e.CreatedAt).
+		Suffix("RETURNING id").
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&e.ID); err != nil {
+		return fmt.Errorf("failed to insert audit event: %w", err)
+	}
+
+	return nil
+}
+
+// List returns events matching the filter, newest first
+func (r *Repository) List(ctx context.Context, f Filter) ([]Event, error) {
+	q := psql.
+		Select(
+			"id",
+			"user_id",
+			"event_type",
+			"COALESCE(ip_address, '')",
+			"COALESCE(user_agent, '')",
+			"COALESCE(request_id, '')",
+			"details",
+			"created_at",
+		).
+		From("audit_events").
+		OrderBy("id DESC").
+		Limit(uint64(f.Limit))
+
+	if f.UserID != nil {
+		q = q.Where(sq.Eq{"user_id": *f.UserID})
+	}
+	if len(f.Types) > 0 {
+		names := make([]string, 0, len(f.Types))
+		for _, t := range f.Types {
+			names = append(names, t.String())
+		}
+		q = q.Where(sq.Eq{"event_type": names})
+	}
+	if f.IP != "" {
+		q = q.Where(sq.Eq{"ip_address": f.IP})
+	}
+	if !f.Since.IsZero() {
+		q = q.Where(sq.GtOrEq{"created_at": f.Since})
+	}
+	if !f.Until.IsZero() {
+		q = q.Where(sq.Lt{"created_at": f.Until})
+	}
+	if f.BeforeID > 0 {
+		q = q.Where(sq.Lt{"id": f.BeforeID})
+	}
+
+	query, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var e Event
+		var eventType string
+		if err := rows.Scan(
+			&e.ID,
+			&e.UserID,
+			&eventType,
+			&e.IP,
+			&e.UserAgent,
+			&e.RequestID,
+			&e.Details,
+			&e.CreatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("failed to scan audit event: %w", err)
+		}
+
+		// Type removed from code - skip rather than show it as another type
+		e.Type, err = ParseEventType(eventType)
+		if err != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate audit events: %w", err)
+	}
+
+	return events, nil
+}
+
+// DeleteOlderThan deletes events created before the given time
+func (r *Repository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	query, args, err := psql.
+		Delete("audit_events").
+		Where(sq.Lt{"created_at": before}).
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old audit events: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
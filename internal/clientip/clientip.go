@@ -0,0 +1,200 @@
+package clientip
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// Headers the resolver can read the proxy chain from (CLIENT_IP_HEADER)
+const (
+	HeaderXForwardedFor = "x-forwarded-for"
+	HeaderXRealIP       = "x-real-ip"
+	HeaderForwarded     = "forwarded"
+)
+
+// Resolver determines the real client IP behind reverse proxies
+// WHY: Forwarding headers are set by the client as much as by proxies -
+// trusting them verbatim lets anyone pick an IP for rate limits,
+// login throttling and session audit fields
+// HOW: The chain "header addresses + TCP peer" is walked from the right
+// (closest hop first). An address is skipped as a proxy only if it is trusted:
+// - in TrustedProxies CIDRs (when configured)
+// - within the first ProxyHops hops (when configured; limits CIDR mode too)
+//
+// The first untrusted address is the client. With nothing configured
+// headers are ignored and the TCP peer is the client
+type Resolver struct {
+	trusted []netip.Prefix
+	hops    int
+	header  string
+}
+
+// New creates resolver from configuration
+func New(cfg config.ProxyConfig) (*Resolver, error) {
+	r := &Resolver{
+		hops:   cfg.Hops,
+		header: strings.ToLower(cfg.Header),
+	}
+
+	switch r.header {
+	case HeaderXForwardedFor, HeaderXRealIP, HeaderForwarded:
+	default:
+		return nil, fmt.Errorf("unsupported client ip header %q", cfg.Header)
+	}
+
+	for _, raw := range cfg.TrustedProxies {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		// Single addresses are accepted as /32 or /128
+		if !strings.Contains(raw, "/") {
+			addr, err := netip.ParseAddr(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+			}
+			r.trusted = append(r.trusted, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+
+		prefix, err := netip.ParsePrefix(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", raw, err)
+		}
+		r.trusted = append(r.trusted, prefix.Masked())
+	}
+
+	return r, nil
+}
+
+// Resolve returns client IP of the request
+// Falls back to RemoteAddr as is if it can't be parsed (unix socket, tests)
+func (r *Resolver) Resolve(req *http.Request) string {
+	peer, ok := parseHostPort(req.RemoteAddr)
+	if !ok {
+		return req.RemoteAddr
+	}
+
+	// Don't even read headers from an untrusted peer
+	if !r.isProxy(peer, 0) {
+		return peer.String()
+	}
+
+	chain := r.chain(req)
+
+	// Walk from the closest hop, peer is proxy number 1
+	client := peer
+	proxies := 1
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr, ok := chain[i].Get()
+		if !ok {
+			// Garbage in the chain - everything to the left of it
+			// is unverifiable, the last good hop is the best we know
+			break
+		}
+
+		client = addr
+		if !r.isProxy(addr, proxies) {
+			break
+		}
+		proxies++
+	}
+
+	return client.String()
+}
+
+// isProxy reports whether addr at the given position (number of proxies
+// already passed) may be skipped as a trusted proxy
+func (r *Resolver) isProxy(addr netip.Addr, passed int) bool {
+	if r.hops > 0 && passed >= r.hops {
+		return false
+	}
+
+	if len(r.trusted) == 0 {
+		// Count-only mode: the first Hops hops are proxies by definition
+		return r.hops > 0
+	}
+
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// chain returns addresses from the configured header, leftmost first
+func (r *Resolver) chain(req *http.Request) []hop {
+	switch r.header {
+	case HeaderXRealIP:
+		if v := strings.TrimSpace(req.Header.Get("X-Real-IP")); v != "" {
+			return []hop{parseHop(v)}
+		}
+		return nil
+	case HeaderForwarded:
+		return parseForwarded(req.Header.Values("Forwarded"))
+	default:
+		var hops []hop
+		// Several X-Forwarded-For headers are one list, in order
+		for _, line := range req.Header.Values("X-Forwarded-For") {
+			for _, part := range strings.Split(line, ",") {
+				hops = append(hops, parseHop(part))
+			}
+		}
+		return hops
+	}
+}
+
+// hop is one address of the proxy chain, invalid if it can't be parsed
+type hop struct {
+	addr  netip.Addr
+	valid bool
+}
+
+// Get returns the address and whether it is valid
+func (h hop) Get() (netip.Addr, bool) {
+	return h.addr, h.valid
+}
+
+// parseHop parses "ip", "ip:port" or "[ipv6]:port"
+func parseHop(raw string) hop {
+	raw = strings.TrimSpace(raw)
+
+	if addr, err := netip.ParseAddr(raw); err == nil {
+		return hop{addr: addr.Unmap(), valid: true}
+	}
+
+	if addr, ok := parseHostPort(raw); ok {
+		return hop{addr: addr, valid: true}
+	}
+
+	// "[2001:db8::1]" without port
+	if strings.HasPrefix(raw, "[") && strings.HasSuffix(raw, "]") {
+		if addr, err := netip.ParseAddr(raw[1 : len(raw)-1]); err == nil {
+			return hop{addr: addr.Unmap(), valid: true}
+		}
+	}
+
+	return hop{}
+}
+
+// parseHostPort parses "host:port" with IP host
+func parseHostPort(raw string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(raw)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
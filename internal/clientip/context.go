@@ -0,0 +1,56 @@
+package clientip
+
+import (
+	"context"
+	"net"
+	"net/http"
+)
+
+// ctxKey is a type-safe context key for the resolved client IP
+type ctxKey struct{}
+
+// WithCtx adds client IP to context
+func WithCtx(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, ip)
+}
+
+// FromCtx retrieves client IP from context
+// Returns (ip, true) if Middleware resolved it, ("", false) otherwise
+func FromCtx(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(ctxKey{}).(string)
+	return ip, ok
+}
+
+// FromRequest returns client IP of the request
+// WHY: One source of truth for rate limits, login throttling and sessions
+// HOW: Takes IP resolved by Middleware; without it (middleware not mounted)
+// falls back to TCP peer - never to forwarding headers
+func FromRequest(r *http.Request) string {
+	if ip, ok := FromCtx(r.Context()); ok {
+		return ip
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// Middleware resolves client IP once per request
+// Replaces chi's RealIP, which trusts X-Forwarded-For from anyone
+//
+// RemoteAddr is rewritten to the client IP (without port, as chi's RealIP does)
+// so request logger and other RemoteAddr readers see the client, not the proxy
+func Middleware(resolver *Resolver) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolver.Resolve(r)
+
+			r = r.WithContext(WithCtx(r.Context(), ip))
+			r.RemoteAddr = ip
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package clientip
+
+import "strings"
+
+// parseForwarded extracts "for=" addresses from RFC 7239 Forwarded headers
+// Elements are comma-separated, pairs inside an element are ';'-separated:
+//
+//	Forwarded: for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8::1]:4711"
+//
+// Elements without "for" and obfuscated identifiers ("unknown", "_hidden")
+// become invalid hops - the proxy chain can't be verified past them
+func parseForwarded(lines []string) []hop {
+	var hops []hop
+
+	for _, line := range lines {
+		for _, element := range splitQuoted(line, ',') {
+			var node string
+			found := false
+
+			for _, pair := range splitQuoted(element, ';') {
+				key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(strings.TrimSpace(key), "for") {
+					continue
+				}
+				node = unquote(strings.TrimSpace(value))
+				found = true
+				break
+			}
+
+			if !found {
+				hops = append(hops, hop{})
+				continue
+			}
+			hops = append(hops, parseHop(node))
+		}
+	}
+
+	return hops
+}
+
+// splitQuoted splits s by sep, ignoring separators inside quoted strings
+func splitQuoted(s string, sep byte) []string {
+	var parts []string
+	quoted := false
+	start := 0
+
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(parts, s[start:])
+}
+
+// unquote removes quotes and backslash escapes of a quoted-string
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+
+	s = s[1 : len(s)-1]
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
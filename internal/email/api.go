@@ -0,0 +1,148 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// apiSendPath - endpoint of the sending API relative to EMAIL_API_URL
+const apiSendPath = "/api/send"
+
+// maxAPIErrorBody - how much of an error response goes into the task error
+const maxAPIErrorBody = 512
+
+// APIMailer sends email through a provider's HTTP API
+// WHY: Providers' HTTP APIs work where outbound SMTP ports are blocked
+// and report errors as JSON instead of SMTP reply codes
+// HOW: POST $EMAIL_API_URL/api/send with a bearer token, Mailtrap sending API format;
+// the base URL is configurable to point at a sandbox or a local fake
+type APIMailer struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+var _ Mailer = (*APIMailer)(nil)
+
+// NewAPIMailer creates mailer for the HTTP sending API
+func NewAPIMailer(cfg *config.EmailConfig) (*APIMailer, error) {
+	if cfg.APIToken == "" {
+		return nil, errors.New("EMAIL_API_TOKEN is required for api email driver")
+	}
+
+	return &APIMailer{
+		url:    strings.TrimSuffix(cfg.APIURL, "/") + apiSendPath,
+		token:  cfg.APIToken,
+		client: &http.Client{Timeout: cfg.APITimeout},
+	}, nil
+}
+
+// apiAddress - sender or recipient in the API request
+type apiAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+// apiAttachment - file in the API request, content in base64
+type apiAttachment struct {
+	Content     string `json:"content"`
+	Filename    string `json:"filename"`
+	Type        string `json:"type"`
+	Disposition string `json:"disposition"`
+	ContentID   string `json:"content_id,omitempty"`
+}
+
+// apiRequest - body of the send request
+type apiRequest struct {
+	From        apiAddress      `json:"from"`
+	To          []apiAddress    `json:"to"`
+	Subject     string          `json:"subject"`
+	HTML        string          `json:"html"`
+	Text        string          `json:"text"`
+	Attachments []apiAttachment `json:"attachments,omitempty"`
+}
+
+// Send posts the message to the API
+// Headers are validated like for SMTP, MIME is built by the provider
+// Any non-2xx response is an error with the start of the response body
+func (m *APIMailer) Send(ctx context.Context, msg *Message) error {
+	from, err := parseAddress("From", msg.From)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPermanent, err)
+	}
+	to, err := parseAddress("To", msg.To)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPermanent, err)
+	}
+	if err := validateHeaderValue("Subject", msg.Subject); err != nil {
+		return fmt.Errorf("%w: %w", ErrPermanent, err)
+	}
+
+	text := msg.Text
+	if text == "" {
+		text = htmlToText(msg.HTML)
+	}
+
+	apiReq := apiRequest{
+		From:    apiAddress{Email: from.Address, Name: from.Name},
+		To:      []apiAddress{{Email: to.Address, Name: to.Name}},
+		Subject: msg.Subject,
+		HTML:    msg.HTML,
+		Text:    text,
+	}
+	for _, a := range msg.Inline {
+		apiReq.Attachments = append(apiReq.Attachments, apiAttachmentOf(a, "inline"))
+	}
+	for _, a := range msg.Attachments {
+		apiReq.Attachments = append(apiReq.Attachments, apiAttachmentOf(a, "attachment"))
+	}
+
+	body, err := json.Marshal(apiReq)
+	if err != nil {
+		return fmt.Errorf("marshal api request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build api request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+m.token)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("send api request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, maxAPIErrorBody))
+		return fmt.Errorf("email api returned %s: %s", resp.Status, bytes.TrimSpace(detail))
+	}
+
+	// Drain so the connection can be reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	return nil
+}
+
+// apiAttachmentOf converts attachment to the API format
+func apiAttachmentOf(a Attachment, disposition string) apiAttachment {
+	return apiAttachment{
+		Content:     base64.StdEncoding.EncodeToString(a.Data),
+		Filename:    a.Filename,
+		Type:        a.ContentType,
+		Disposition: disposition,
+		ContentID:   a.ContentID,
+	}
+}
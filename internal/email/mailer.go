@@ -0,0 +1,56 @@
+package email
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// Message is a rendered email ready for delivery
+type Message struct {
+	From        string // "Name <address>" or bare address
+	To          string
+	Subject     string
+	HTML        string
+	Text        string       // plain text alternative, generated from HTML if empty
+	Inline      []Attachment // images referenced from HTML as cid:ContentID
+	Attachments []Attachment // files (certificates, receipts)
+}
+
+// Mailer delivers rendered messages
+// WHY: Production sends through SMTP or a provider's HTTP API, local dev wants
+// to read emails without any server, tests want to assert on what was sent
+// HOW: Sender renders templates and hands the Message to a Mailer; the driver
+// is chosen by EMAIL_DRIVER in NewMailer:
+// - smtp: SMTPMailer, any SMTP server (Mailhog locally)
+// - api: APIMailer, provider's HTTP sending API
+// - outbox: OutboxMailer, writes messages to a maildir, nothing is sent
+// - memory: MemoryMailer, keeps messages in memory
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Mailer drivers (EMAIL_DRIVER)
+const (
+	DriverSMTP   = "smtp"
+	DriverAPI    = "api"
+	DriverOutbox = "outbox"
+	DriverMemory = "memory"
+)
+
+// NewMailer builds mailer from configuration
+func NewMailer(cfg *config.EmailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg)
+	case DriverAPI:
+		return NewAPIMailer(cfg)
+	case DriverOutbox:
+		return NewOutboxMailer(cfg.OutboxDir)
+	case DriverMemory:
+		return NewMemoryMailer(), nil
+	default:
+		return nil, fmt.Errorf("unknown email driver %q", cfg.Driver)
+	}
+}
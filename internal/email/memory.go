@@ -0,0 +1,47 @@
+package email
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryMailer keeps sent messages in memory
+// WHY: Tests assert on what was sent (recipient, link in the body)
+// without a mail server
+// HOW: Slice guarded by Mutex; Messages returns a copy
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+var _ Mailer = (*MemoryMailer)(nil)
+
+// NewMemoryMailer creates empty in-memory mailer
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+// Send records the message
+func (m *MemoryMailer) Send(_ context.Context, msg *Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = append(m.messages, *msg)
+	return nil
+}
+
+// Messages returns recorded messages in send order
+func (m *MemoryMailer) Messages() []Message {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Message(nil), m.messages...)
+}
+
+// Reset forgets recorded messages
+func (m *MemoryMailer) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.messages = nil
+}
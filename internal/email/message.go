@@ -0,0 +1,280 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// base64LineLength - RFC 2045 limit for encoded lines
+const base64LineLength = 76
+
+// contentIDRegex - Content-ID is referenced as cid:<id> from HTML, keep it to safe characters
+var contentIDRegex = regexp.MustCompile(`^[A-Za-z0-9._@-]+$`)
+
+// Attachment is a file sent with the message
+// Inline attachments (Message.Inline) are shown inside HTML as <img src="cid:ContentID">
+type Attachment struct {
+	Filename    string
+	ContentType string // e.g. image/gif, application/pdf
+	Data        []byte
+	ContentID   string // inline only
+}
+
+// buildMessage renders message as RFC 5322 / MIME for SMTP and maildir
+// WHY: A single text/html part is shown as raw HTML by some clients and scored
+// as spam by filters; unencoded Cyrillic headers break in others
+// HOW: Headers are validated (no CR/LF - no header injection) and RFC 2047 encoded,
+// the body is nested multiparts, each level only when needed:
+//
+//	multipart/mixed            - if there are attachments
+//	  multipart/related        - if there are inline images
+//	    multipart/alternative  - always: text/plain (generated from HTML if empty), text/html
+//	    image/...              - inline, Content-ID
+//	  application/...          - attachments
+//
+// Text parts are quoted-printable, binary parts base64; lines end with CRLF
+func buildMessage(msg *Message, now time.Time) ([]byte, error) {
+	from, err := parseAddress("From", msg.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseAddress("To", msg.To)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateHeaderValue("Subject", msg.Subject); err != nil {
+		return nil, err
+	}
+
+	messageID, err := newMessageID(from.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType, body, err := buildBody(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	writeHeader(&buf, "From", from.String())
+	writeHeader(&buf, "To", to.String())
+	writeHeader(&buf, "Subject", encodeHeader(msg.Subject))
+	writeHeader(&buf, "Date", now.Format(time.RFC1123Z))
+	writeHeader(&buf, "Message-ID", messageID)
+	writeHeader(&buf, "MIME-Version", "1.0")
+	writeHeader(&buf, "Content-Type", contentType)
+	buf.WriteString("\r\n")
+	buf.Write(body)
+
+	return buf.Bytes(), nil
+}
+
+// buildBody returns Content-Type and body of the outermost part
+func buildBody(msg *Message) (string, []byte, error) {
+	text := msg.Text
+	if text == "" {
+		text = htmlToText(msg.HTML)
+	}
+
+	contentType, body, err := writeMultipart("alternative", func(w *multipart.Writer) error {
+		if err := writeTextPart(w, "text/plain", text); err != nil {
+			return err
+		}
+		return writeTextPart(w, "text/html", msg.HTML)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(msg.Inline) > 0 {
+		contentType, body, err = writeMultipart("related", func(w *multipart.Writer) error {
+			if err := writeRawPart(w, contentType, body); err != nil {
+				return err
+			}
+			for _, a := range msg.Inline {
+				if err := writeAttachmentPart(w, a, true); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	if len(msg.Attachments) > 0 {
+		contentType, body, err = writeMultipart("mixed", func(w *multipart.Writer) error {
+			if err := writeRawPart(w, contentType, body); err != nil {
+				return err
+			}
+			for _, a := range msg.Attachments {
+				if err := writeAttachmentPart(w, a, false); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	return contentType, body, nil
+}
+
+// writeMultipart renders multipart/<subtype> with parts written by fill
+func writeMultipart(subtype string, fill func(w *multipart.Writer) error) (string, []byte, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	if err := fill(w); err != nil {
+		return "", nil, err
+	}
+	if err := w.Close(); err != nil {
+		return "", nil, fmt.Errorf("close multipart/%s: %w", subtype, err)
+	}
+
+	contentType := mime.FormatMediaType("multipart/"+subtype, map[string]string{"boundary": w.Boundary()})
+	return contentType, buf.Bytes(), nil
+}
+
+// writeTextPart writes UTF-8 text part in quoted-printable
+func writeTextPart(w *multipart.Writer, mediaType, text string) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mediaType + "; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return fmt.Errorf("create %s part: %w", mediaType, err)
+	}
+
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(text)); err != nil {
+		return fmt.Errorf("write %s part: %w", mediaType, err)
+	}
+	return qp.Close()
+}
+
+// writeRawPart writes an already rendered nested multipart
+func writeRawPart(w *multipart.Writer, contentType string, body []byte) error {
+	part, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {contentType}})
+	if err != nil {
+		return fmt.Errorf("create nested part: %w", err)
+	}
+	_, err = part.Write(body)
+	return err
+}
+
+// writeAttachmentPart writes a file in base64, inline (with Content-ID) or as attachment
+func writeAttachmentPart(w *multipart.Writer, a Attachment, inline bool) error {
+	if err := validateHeaderValue("attachment filename", a.Filename); err != nil {
+		return err
+	}
+	if a.Filename == "" {
+		return errors.New("attachment filename is empty")
+	}
+
+	mediaType, _, err := mime.ParseMediaType(a.ContentType)
+	if err != nil {
+		return fmt.Errorf("invalid content type %q of %s: %w", a.ContentType, a.Filename, err)
+	}
+
+	disposition := "attachment"
+	header := textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType(mediaType, map[string]string{"name": a.Filename})},
+		"Content-Transfer-Encoding": {"base64"},
+	}
+	if inline {
+		if !contentIDRegex.MatchString(a.ContentID) {
+			return fmt.Errorf("invalid content id %q of %s", a.ContentID, a.Filename)
+		}
+		disposition = "inline"
+		header["Content-ID"] = []string{"<" + a.ContentID + ">"}
+	}
+	header["Content-Disposition"] = []string{mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename})}
+
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("create part %s: %w", a.Filename, err)
+	}
+
+	encoded := base64.StdEncoding.EncodeToString(a.Data)
+	for len(encoded) > base64LineLength {
+		if _, err := part.Write([]byte(encoded[:base64LineLength] + "\r\n")); err != nil {
+			return err
+		}
+		encoded = encoded[base64LineLength:]
+	}
+	_, err = part.Write([]byte(encoded + "\r\n"))
+	return err
+}
+
+// parseAddress validates address header value, "Name <addr>" or bare address
+// The name is RFC 2047 encoded by mail.Address.String
+func parseAddress(header, value string) (*mail.Address, error) {
+	if err := validateHeaderValue(header, value); err != nil {
+		return nil, err
+	}
+
+	addr, err := mail.ParseAddress(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s address %q: %w", header, value, err)
+	}
+	return addr, nil
+}
+
+// validateHeaderValue rejects control characters
+// WHY: CR/LF in a subject or recipient would start a new header (Bcc: ...) - header injection
+func validateHeaderValue(header, value string) error {
+	for _, r := range value {
+		if (r < ' ' && r != '\t') || r == 0x7f {
+			return fmt.Errorf("invalid %s: control character %U", header, r)
+		}
+	}
+	return nil
+}
+
+// encodeHeader RFC 2047 encodes non-ASCII text (base64 - shorter than Q for Cyrillic)
+// and folds encoded words onto continuation lines to stay under the line length limit
+func encodeHeader(value string) string {
+	encoded := mime.BEncoding.Encode("UTF-8", value)
+	return strings.ReplaceAll(encoded, "?= =?", "?=\r\n =?")
+}
+
+// writeHeader writes one header line
+func writeHeader(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.WriteString(value)
+	buf.WriteString("\r\n")
+}
+
+// newMessageID returns unique Message-ID in the sender's domain
+// Without it some servers add their own, others lower the spam score
+func newMessageID(from string) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate message id: %w", err)
+	}
+
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 && i < len(from)-1 {
+		domain = from[i+1:]
+	}
+
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">", nil
+}
@@ -0,0 +1,420 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/textproto"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// mimePart - parsed message or part, text parts already decoded from quoted-printable
+type mimePart struct {
+	mediaType string
+	header    textproto.MIMEHeader
+	body      []byte
+	parts     []*mimePart
+}
+
+// parseMessage parses builder output the way a mail client would
+func parseMessage(t *testing.T, raw []byte) (*mail.Message, *mimePart) {
+	t.Helper()
+
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("read message: %v", err)
+	}
+	return msg, parsePart(t, textproto.MIMEHeader(msg.Header), msg.Body)
+}
+
+func parsePart(t *testing.T, header textproto.MIMEHeader, body io.Reader) *mimePart {
+	t.Helper()
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		t.Fatalf("content type %q: %v", header.Get("Content-Type"), err)
+	}
+	p := &mimePart{mediaType: mediaType, header: header}
+
+	if !strings.HasPrefix(mediaType, "multipart/") {
+		if p.body, err = io.ReadAll(body); err != nil {
+			t.Fatalf("read %s: %v", mediaType, err)
+		}
+		return p
+	}
+
+	r := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := r.NextPart()
+		if err == io.EOF {
+			return p
+		}
+		if err != nil {
+			t.Fatalf("next part of %s: %v", mediaType, err)
+		}
+		p.parts = append(p.parts, parsePart(t, part.Header, part))
+	}
+}
+
+// structure describes part nesting: multipart/mixed(text/plain, ...)
+func (p *mimePart) structure() string {
+	if len(p.parts) == 0 {
+		return p.mediaType
+	}
+	children := make([]string, len(p.parts))
+	for i, c := range p.parts {
+		children[i] = c.structure()
+	}
+	return p.mediaType + "(" + strings.Join(children, ", ") + ")"
+}
+
+// find returns the first part of mediaType, depth-first
+func (p *mimePart) find(mediaType string) *mimePart {
+	if p.mediaType == mediaType {
+		return p
+	}
+	for _, c := range p.parts {
+		if found := c.find(mediaType); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// decodedBody returns body of a base64 part
+func (p *mimePart) decodedBody(t *testing.T) []byte {
+	t.Helper()
+
+	if p.header.Get("Content-Transfer-Encoding") != "base64" {
+		t.Fatalf("%s is %q, want base64", p.mediaType, p.header.Get("Content-Transfer-Encoding"))
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.NewReplacer("\r", "", "\n", "").Replace(string(p.body)))
+	if err != nil {
+		t.Fatalf("decode %s: %v", p.mediaType, err)
+	}
+	return data
+}
+
+var (
+	testGIF = Attachment{Filename: "dance.gif", ContentType: "image/gif", Data: []byte("GIF89a-test"), ContentID: "gopher"}
+	testPDF = Attachment{Filename: "certificate.pdf", ContentType: "application/pdf", Data: bytes.Repeat([]byte("%PDF"), 100)}
+)
+
+func testHTMLMessage() *Message {
+	return &Message{
+		From:    "Learn Go <noreply@learn-go.dev>",
+		To:      "user@example.com",
+		Subject: "Подтвердите ваш email",
+		HTML:    `<p>Привет!</p><p><a href="https://learn-go.dev/verify?token=abc">Подтвердить</a></p><img src="cid:gopher">`,
+	}
+}
+
+func TestBuildMessageRejectsHeaderInjection(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*Message)
+	}{
+		{"CRLF in To", func(m *Message) { m.To = "user@example.com\r\nBcc: victim@example.com" }},
+		{"LF in To", func(m *Message) { m.To = "user@example.com\nBcc: victim@example.com" }},
+		{"CR in To", func(m *Message) { m.To = "user@example.com\rBcc: victim@example.com" }},
+		{"CRLF in Subject", func(m *Message) { m.Subject = "Hello\r\nBcc: victim@example.com" }},
+		{"LF in Subject", func(m *Message) { m.Subject = "Hello\nBcc: victim@example.com" }},
+		{"NUL in Subject", func(m *Message) { m.Subject = "Hello\x00" }},
+		{"CRLF in From name", func(m *Message) { m.From = "Learn Go\r\nBcc: victim@example.com <noreply@learn-go.dev>" }},
+		{"CRLF in attachment filename", func(m *Message) {
+			m.Attachments = []Attachment{{Filename: "a.pdf\r\nBcc: victim@example.com", ContentType: "application/pdf"}}
+		}},
+		{"header in Content-ID", func(m *Message) {
+			m.Inline = []Attachment{{Filename: "a.gif", ContentType: "image/gif", ContentID: "a>\r\nBcc: victim@example.com"}}
+		}},
+		{"invalid To address", func(m *Message) { m.To = "not an address" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := testHTMLMessage()
+			tt.modify(msg)
+
+			raw, err := buildMessage(msg, time.Now())
+			if err == nil {
+				t.Fatalf("message built:\n%s", raw)
+			}
+		})
+	}
+}
+
+func TestBuildMessageHeaders(t *testing.T) {
+	now := time.Date(2026, 3, 14, 15, 9, 26, 0, time.FixedZone("MSK", 3*60*60))
+	msg := testHTMLMessage()
+	msg.From = "Школа Go <noreply@learn-go.dev>"
+
+	raw, err := buildMessage(msg, now)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	parsed, _ := parseMessage(t, raw)
+
+	// Headers are ASCII only, non-ASCII goes in RFC 2047 encoded words
+	head, _, _ := bytes.Cut(raw, []byte("\r\n\r\n"))
+	for _, b := range head {
+		if b >= 0x80 {
+			t.Fatalf("raw 8-bit byte in headers:\n%s", head)
+		}
+	}
+
+	subject := parsed.Header.Get("Subject")
+	if !regexp.MustCompile(`^=\?UTF-8\?[bB]\?`).MatchString(subject) {
+		t.Errorf("Subject = %q, want RFC 2047 B-encoded", subject)
+	}
+	dec := new(mime.WordDecoder)
+	if got, err := dec.DecodeHeader(subject); err != nil || got != msg.Subject {
+		t.Errorf("decoded Subject = %q (err %v), want %q", got, err, msg.Subject)
+	}
+
+	from, err := parsed.Header.AddressList("From")
+	if err != nil || len(from) != 1 || from[0].Name != "Школа Go" || from[0].Address != "noreply@learn-go.dev" {
+		t.Errorf("From = %v (err %v), want Школа Go <noreply@learn-go.dev>", from, err)
+	}
+
+	date, err := parsed.Header.Date()
+	if err != nil || !date.Equal(now) {
+		t.Errorf("Date = %v (err %v), want %v", date, err, now)
+	}
+
+	messageID := parsed.Header.Get("Message-ID")
+	if !regexp.MustCompile(`^<[0-9a-f]{32}@learn-go\.dev>$`).MatchString(messageID) {
+		t.Errorf("Message-ID = %q, want <random@sender domain>", messageID)
+	}
+	again, err := buildMessage(msg, now)
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+	other, _ := parseMessage(t, again)
+	if other.Header.Get("Message-ID") == messageID {
+		t.Error("two messages share a Message-ID")
+	}
+
+	if got := parsed.Header.Get("MIME-Version"); got != "1.0" {
+		t.Errorf("MIME-Version = %q, want 1.0", got)
+	}
+}
+
+func TestBuildMessageFoldsLongSubject(t *testing.T) {
+	msg := testHTMLMessage()
+	msg.Subject = strings.Repeat("Очень длинная тема письма ", 10)
+
+	raw, err := buildMessage(msg, time.Now())
+	if err != nil {
+		t.Fatalf("build: %v", err)
+	}
+
+	// Encoded words are at most 75 characters (RFC 2047), one per folded line
+	_, subject, _ := bytes.Cut(raw, []byte("Subject: "))
+	subject, _, _ = bytes.Cut(subject, []byte("\r\nDate:"))
+	lines := strings.Split(string(subject), "\r\n ")
+	if len(lines) < 2 {
+		t.Errorf("long Subject is not folded: %q", subject)
+	}
+	for _, word := range lines {
+		if len(word) > 75 || strings.ContainsAny(word, "\r\n") {
+			t.Errorf("encoded word of %d characters: %q", len(word), word)
+		}
+	}
+
+	parsed, _ := parseMessage(t, raw)
+	dec := new(mime.WordDecoder)
+	if got, err := dec.DecodeHeader(parsed.Header.Get("Subject")); err != nil || got != msg.Subject {
+		t.Errorf("decoded Subject = %q (err %v), want %q", got, err, msg.Subject)
+	}
+}
+
+func TestBuildMessageStructure(t *testing.T) {
+	tests := []struct {
+		name        string
+		inline      []Attachment
+		attachments []Attachment
+		want        string
+	}{
+		{
+			name: "html only",
+			want: "multipart/alternative(text/plain, text/html)",
+		},
+		{
+			name:   "inline image",
+			inline: []Attachment{testGIF},
+			want:   "multipart/related(multipart/alternative(text/plain, text/html), image/gif)",
+		},
+		{
+			name:        "attachment",
+			attachments: []Attachment{testPDF},
+			want:        "multipart/mixed(multipart/alternative(text/plain, text/html), application/pdf)",
+		},
+		{
+			name:        "inline image and attachment",
+			inline:      []Attachment{testGIF},
+			attachments: []Attachment{testPDF},
+			want:        "multipart/mixed(multipart/related(multipart/alternative(text/plain, text/html), image/gif), application/pdf)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := testHTMLMessage()
+			msg.Inline = tt.inline
+			msg.Attachments = tt.attachments
+
+			raw, err := buildMessage(msg, time.Now())
+			if err != nil {
+				t.Fatalf("build: %v", err)
+			}
+			_, root := parseMessage(t, raw)
+
+			if got := root.structure(); got != tt.want {
+				t.Errorf("structure:\n got %s\nwant %s", got, tt.want)
+			}
+
+			if html := root.find("text/html"); html == nil || string(html.body) != msg.HTML {
+				t.Errorf("html part does not round-trip")
+			}
+
+			if gif := root.find("image/gif"); gif != nil {
+				if got := gif.header.Get("Content-ID"); got != "<gopher>" {
+					t.Errorf("inline Content-ID = %q, want <gopher>", got)
+				}
+				if got := gif.header.Get("Content-Disposition"); !strings.HasPrefix(got, "inline") {
+					t.Errorf("inline Content-Disposition = %q", got)
+				}
+				if !bytes.Equal(gif.decodedBody(t), testGIF.Data) {
+					t.Error("inline image data does not round-trip")
+				}
+			}
+
+			if pdf := root.find("application/pdf"); pdf != nil {
+				if got := pdf.header.Get("Content-ID"); got != "" {
+					t.Errorf("attachment has Content-ID %q", got)
+				}
+				if got := pdf.header.Get("Content-Disposition"); got != `attachment; filename=certificate.pdf` {
+					t.Errorf("attachment Content-Disposition = %q", got)
+				}
+				for line := range strings.SplitSeq(string(pdf.body), "\r\n") {
+					if len(line) > base64LineLength {
+						t.Fatalf("base64 line of %d characters", len(line))
+					}
+				}
+				if !bytes.Equal(pdf.decodedBody(t), testPDF.Data) {
+					t.Error("attachment data does not round-trip")
+				}
+			}
+		})
+	}
+}
+
+func TestBuildMessagePlainTextPart(t *testing.T) {
+	t.Run("generated from html", func(t *testing.T) {
+		msg := testHTMLMessage()
+
+		raw, err := buildMessage(msg, time.Now())
+		if err != nil {
+			t.Fatalf("build: %v", err)
+		}
+		_, root := parseMessage(t, raw)
+
+		text := root.find("text/plain")
+		if text == nil {
+			t.Fatal("no text/plain part")
+		}
+		if got := text.header.Get("Content-Type"); got != "text/plain; charset=UTF-8" {
+			t.Errorf("Content-Type = %q", got)
+		}
+		want := "Привет!\r\n\r\nПодтвердить (https://learn-go.dev/verify?token=abc)\r\n"
+		if got := string(text.body); got != want {
+			t.Errorf("text part = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("explicit text kept", func(t *testing.T) {
+		msg := testHTMLMessage()
+		msg.Text = "Ссылка: https://learn-go.dev/verify?token=abc"
+
+		raw, err := buildMessage(msg, time.Now())
+		if err != nil {
+			t.Fatalf("build: %v", err)
+		}
+		_, root := parseMessage(t, raw)
+
+		if text := root.find("text/plain"); text == nil || string(text.body) != msg.Text {
+			t.Errorf("text part does not match Message.Text")
+		}
+	})
+}
+
+func TestValidateHeaderValue(t *testing.T) {
+	tests := []struct {
+		value   string
+		wantErr bool
+	}{
+		{"Подтвердите ваш email", false},
+		{"tab\tis allowed", false},
+		{"", false},
+		{"a\r\nBcc: victim@example.com", true},
+		{"a\nb", true},
+		{"a\rb", true},
+		{"a\x00b", true},
+		{"a\x1bb", true},
+		{"a\x7fb", true},
+	}
+
+	for _, tt := range tests {
+		err := validateHeaderValue("Subject", tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateHeaderValue(%q) = %v, want error %v", tt.value, err, tt.wantErr)
+		}
+	}
+}
+
+func TestEncodeHeader(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Welcome", "Welcome"},
+		{"Привет", "=?UTF-8?b?0J/RgNC40LLQtdGC?="},
+	}
+
+	for _, tt := range tests {
+		if got := encodeHeader(tt.value); got != tt.want {
+			t.Errorf("encodeHeader(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestWriteAttachmentPartRejectsInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		a      Attachment
+		inline bool
+	}{
+		{"empty filename", Attachment{ContentType: "application/pdf"}, false},
+		{"CRLF in filename", Attachment{Filename: "a.pdf\r\nX-Evil: 1", ContentType: "application/pdf"}, false},
+		{"invalid content type", Attachment{Filename: "a.pdf", ContentType: "application/pdf; ="}, false},
+		{"empty content type", Attachment{Filename: "a.pdf"}, false},
+		{"inline without content id", Attachment{Filename: "a.gif", ContentType: "image/gif"}, true},
+		{"content id with brackets", Attachment{Filename: "a.gif", ContentType: "image/gif", ContentID: "<gopher>"}, true},
+		{"content id with space", Attachment{Filename: "a.gif", ContentType: "image/gif", ContentID: "go pher"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := multipart.NewWriter(io.Discard)
+			if err := writeAttachmentPart(w, tt.a, tt.inline); err == nil {
+				t.Error("attachment written")
+			}
+		})
+	}
+}
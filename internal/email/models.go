@@ -8,7 +8,7 @@ import (
 
 // EmailType represents the type of email to send
 // This enum is used to determine which template and configuration to use
-// ENUM(verification, password_reset, notification)
+// ENUM(verification, password_reset, notification, account_unlock, magic_link)
 type EmailType int
 
 // Task represents an email task in the queue
@@ -26,6 +26,8 @@ type Task struct {
 	CreatedAt      time.Time
 	ProcessedAt    *time.Time
 	NextRetryAt    time.Time
+	WorkerID       string    // worker holding the lease while processing
+	LockedUntil    time.Time // lease expiry, extended by Queue.Extend
 }
 
 // EmailConfig holds the configuration for a specific email type
@@ -52,6 +54,14 @@ var emailConfigs = map[EmailType]EmailConfig{
 		Subject:  "Уведомление",
 		Template: "notification",
 	},
+	EmailTypeAccountUnlock: {
+		Subject:  "Вход в аккаунт временно заблокирован",
+		Template: "account_unlock",
+	},
+	EmailTypeMagicLink: {
+		Subject:  "Ссылка для входа в Learn Go",
+		Template: "magic_link",
+	},
 }
 
 // GetConfig returns the configuration for a given email type
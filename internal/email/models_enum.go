@@ -17,16 +17,22 @@ const (
 	EmailTypePasswordReset
 	// EmailTypeNotification is a EmailType of type Notification.
 	EmailTypeNotification
+	// EmailTypeAccountUnlock is a EmailType of type Account_unlock.
+	EmailTypeAccountUnlock
+	// EmailTypeMagicLink is a EmailType of type Magic_link.
+	EmailTypeMagicLink
 )
 
 var ErrInvalidEmailType = fmt.Errorf("not a valid EmailType, try [%s]", strings.Join(_EmailTypeNames, ", "))
 
-const _EmailTypeName = "verificationpassword_resetnotification"
+const _EmailTypeName = "verificationpassword_resetnotificationaccount_unlockmagic_link"
 
 var _EmailTypeNames = []string{
 	_EmailTypeName[0:12],
 	_EmailTypeName[12:26],
 	_EmailTypeName[26:38],
+	_EmailTypeName[38:52],
+	_EmailTypeName[52:62],
 }
 
 // EmailTypeNames returns a list of possible string values of EmailType.
@@ -42,6 +48,8 @@ func EmailTypeValues() []EmailType {
 		EmailTypeVerification,
 		EmailTypePasswordReset,
 		EmailTypeNotification,
+		EmailTypeAccountUnlock,
+		EmailTypeMagicLink,
 	}
 }
 
@@ -49,6 +57,8 @@ var _EmailTypeMap = map[EmailType]string{
 	EmailTypeVerification:  _EmailTypeName[0:12],
 	EmailTypePasswordReset: _EmailTypeName[12:26],
 	EmailTypeNotification:  _EmailTypeName[26:38],
+	EmailTypeAccountUnlock: _EmailTypeName[38:52],
+	EmailTypeMagicLink:     _EmailTypeName[52:62],
 }
 
 // String implements the Stringer interface.
@@ -73,6 +83,10 @@ var _EmailTypeValue = map[string]EmailType{
 	strings.ToLower(_EmailTypeName[12:26]): EmailTypePasswordReset,
 	_EmailTypeName[26:38]:                  EmailTypeNotification,
 	strings.ToLower(_EmailTypeName[26:38]): EmailTypeNotification,
+	_EmailTypeName[38:52]:                  EmailTypeAccountUnlock,
+	strings.ToLower(_EmailTypeName[38:52]): EmailTypeAccountUnlock,
+	_EmailTypeName[52:62]:                  EmailTypeMagicLink,
+	strings.ToLower(_EmailTypeName[52:62]): EmailTypeMagicLink,
 }
 
 // ParseEmailType attempts to convert a string to a EmailType.
@@ -0,0 +1,66 @@
+package email
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// OutboxMailer writes messages to a maildir instead of sending them
+// WHY: Local dev without Mailhog or a provider account - links from
+// verification and login emails are read straight from the files
+// HOW: Maildir delivery: the message is written to tmp/ and renamed into new/,
+// so a reader never sees a half-written file; any maildir client (mutt, Thunderbird
+// via a local account) can open the directory
+type OutboxMailer struct {
+	dir     string
+	host    string
+	counter atomic.Uint64
+}
+
+var _ Mailer = (*OutboxMailer)(nil)
+
+// NewOutboxMailer creates maildir subdirectories (tmp, new, cur) if needed
+func NewOutboxMailer(dir string) (*OutboxMailer, error) {
+	for _, sub := range []string{"tmp", "new", "cur"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return nil, fmt.Errorf("create outbox dir: %w", err)
+		}
+	}
+
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+
+	return &OutboxMailer{dir: dir, host: host}, nil
+}
+
+// Send writes the message as a new maildir file
+func (m *OutboxMailer) Send(_ context.Context, msg *Message) error {
+	data, err := buildMessage(msg, time.Now())
+	if err != nil {
+		return fmt.Errorf("%w: build message: %w", ErrPermanent, err)
+	}
+
+	// Unique name per maildir convention: time.pid_counter.host
+	name := strconv.FormatInt(time.Now().UnixNano(), 10) + "." +
+		strconv.Itoa(os.Getpid()) + "_" +
+		strconv.FormatUint(m.counter.Add(1), 10) + "." + m.host
+
+	tmpPath := filepath.Join(m.dir, "tmp", name)
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("write outbox message: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, filepath.Join(m.dir, "new", name)); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("deliver outbox message: %w", err)
+	}
+
+	return nil
+}
@@ -3,6 +3,7 @@ package email
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"time"
@@ -12,6 +13,13 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// notifyChannel - Postgres channel Enqueue notifies and workers listen on
+const notifyChannel = "email_queue"
+
+// ErrLeaseLost - the task is no longer leased to this worker: the lease expired
+// and another worker reclaimed it (or it was already finished)
+var ErrLeaseLost = errors.New("email task lease lost")
+
 // Queue provides methods for working with the email_queue table
 // This implements a simple task queue using PostgreSQL
 type Queue struct {
@@ -27,7 +35,9 @@ func NewQueue(db *pgxpool.Pool) *Queue {
 
 // Enqueue adds a new email task to the queue
 // WHY: Web application calls this after user registration to schedule email sending
-// HOW: Inserts a new row into email_queue with status='pending'
+// HOW: Inserts a new row into email_queue with status='pending' and notifies
+// listening workers; both in one transaction - NOTIFY is delivered on commit,
+// when the row is already visible
 func (q *Queue) Enqueue(ctx context.Context, emailType EmailType, recipientEmail string, userID *int64, payload any) error {
 	// Marshal payload to JSON
 	payloadBytes, err := json.Marshal(payload)
@@ -55,34 +65,99 @@ func (q *Queue) Enqueue(ctx context.Context, emailType EmailType, recipientEmail
 		return fmt.Errorf("build query: %w", err)
 	}
 
-	_, err = q.db.Exec(ctx, query, args...)
+	tx, err := q.db.Begin(ctx)
 	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
 		return fmt.Errorf("exec query: %w", err)
 	}
 
+	if _, err := tx.Exec(ctx, "SELECT pg_notify($1, '')", notifyChannel); err != nil {
+		return fmt.Errorf("notify workers: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
 	return nil
 }
 
-// Dequeue retrieves the next pending task from the queue
-// WHY: Worker process calls this to get the next email to send
+// Listen calls wake once LISTEN is set up and then on every Enqueue notification
+// WHY: Workers start sending right after registration instead of on the next poll
+// HOW: Holds a dedicated connection (taken out of the pool - it stays in LISTEN state)
+// until ctx is done or the connection fails; the caller reconnects and keeps polling meanwhile
+func (q *Queue) Listen(ctx context.Context, wake func()) error {
+	pooled, err := q.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	// Tasks enqueued before LISTEN (or while reconnecting) sent no notification we could see
+	wake()
+
+	for {
+		if _, err := conn.WaitForNotification(ctx); err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+		wake()
+	}
+}
+
+// Dequeue leases up to one task per worker in workerIDs
+// WHY: Worker pool calls this to get emails for its free workers
 // HOW: Uses FOR UPDATE SKIP LOCKED to safely handle concurrent workers
 //
 // This query finds tasks that are:
-// - status = 'pending'
-// - next_retry_at <= NOW() (ready to be processed)
+// - status = 'pending' and next_retry_at <= NOW() (ready to be processed), or
+// - status = 'processing' with an expired lease (the worker crashed mid-send) and attempts left
 // - Orders by created_at (FIFO)
-// - Locks the row (FOR UPDATE) so other workers can't take it
+// - Locks the rows (FOR UPDATE) so other workers can't take them
 // - SKIP LOCKED means if another worker already locked a row, skip it
 //
-// After retrieving, the status is immediately set to 'processing'
-func (q *Queue) Dequeue(ctx context.Context) (*Task, error) {
+// The tasks are set to 'processing', the i-th leased to workerIDs[i] until NOW() + lease.
+// Times are the database clock - workers on different hosts agree on expiry.
+// A reclaimed task comes back with Status 'processing'; the crashed attempt counts.
+// An expired task with no attempts left is marked failed instead of reclaimed.
+// Returns an empty slice if no tasks are available
+func (q *Queue) Dequeue(ctx context.Context, workerIDs []string, lease time.Duration) ([]*Task, error) {
 	tx, err := q.db.Begin(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("begin transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	// Find and lock the next task using squirrel
+	// A task whose every attempt ended with an expired lease likely kills the worker
+	// that sends it (poison message) - reclaiming it forever would take down worker after worker
+	failQuery, failArgs, err := squirrel.Update("email_queue").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("status", "failed").
+		Set("error", "lease expired on the last attempt").
+		Set("processed_at", time.Now()).
+		Set("locked_until", nil).
+		Where(squirrel.Eq{"status": "processing"}).
+		Where("locked_until < NOW()").
+		Where("attempts >= max_attempts").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("build fail query: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, failQuery, failArgs...); err != nil {
+		return nil, fmt.Errorf("fail exhausted tasks: %w", err)
+	}
+
+	// Find and lock the next tasks using squirrel
 	// Note: Squirrel doesn't support FOR UPDATE SKIP LOCKED, so we use Suffix
 	query, args, err := squirrel.Select(
 		"id",
@@ -92,13 +167,23 @@ func (q *Queue) Dequeue(ctx context.Context) (*Task, error) {
 		"payload",
 		"attempts",
 		"max_attempts",
+		"status",
 	).
 		PlaceholderFormat(squirrel.Dollar).
 		From("email_queue").
-		Where(squirrel.Eq{"status": "pending"}).
-		Where(squirrel.LtOrEq{"next_retry_at": time.Now().UTC()}).
+		Where(squirrel.Or{
+			squirrel.And{
+				squirrel.Eq{"status": "pending"},
+				squirrel.Expr("next_retry_at <= NOW()"),
+			},
+			squirrel.And{
+				squirrel.Eq{"status": "processing"},
+				squirrel.Expr("locked_until < NOW()"),
+				squirrel.Expr("attempts < max_attempts"),
+			},
+		}).
 		OrderBy("created_at ASC").
-		Limit(1).
+		Limit(uint64(len(workerIDs))).
 		Suffix("FOR UPDATE SKIP LOCKED").
 		ToSql()
 
@@ -106,78 +191,128 @@ func (q *Queue) Dequeue(ctx context.Context) (*Task, error) {
 		return nil, fmt.Errorf("build select query: %w", err)
 	}
 
-	var task Task
-	var emailTypeStr string
-	err = tx.QueryRow(ctx, query, args...).Scan(
-		&task.ID,
-		&emailTypeStr,
-		&task.RecipientEmail,
-		&task.UserID,
-		&task.Payload,
-		&task.Attempts,
-		&task.MaxAttempts,
-	)
-
+	rows, err := tx.Query(ctx, query, args...)
 	if err != nil {
-		if err == pgx.ErrNoRows {
-			return nil, nil // No tasks available
-		}
-		return nil, fmt.Errorf("query row: %w", err)
+		return nil, fmt.Errorf("query tasks: %w", err)
 	}
 
-	// Parse email type
-	emailType, err := ParseEmailType(emailTypeStr)
+	tasks, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*Task, error) {
+		var task Task
+		var emailTypeStr string
+		err := row.Scan(
+			&task.ID,
+			&emailTypeStr,
+			&task.RecipientEmail,
+			&task.UserID,
+			&task.Payload,
+			&task.Attempts,
+			&task.MaxAttempts,
+			&task.Status,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		// Parse email type
+		emailType, err := ParseEmailType(emailTypeStr)
+		if err != nil {
+			return nil, fmt.Errorf("parse email type: %w", err)
+		}
+		task.EmailType = emailType
+
+		return &task, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("parse email type: %w", err)
+		return nil, fmt.Errorf("scan tasks: %w", err)
+	}
+
+	if len(tasks) == 0 {
+		return tasks, nil // No tasks available
 	}
-	task.EmailType = emailType
 
-	// Mark as processing
-	updateQuery, updateArgs, err := squirrel.Update("email_queue").
+	// Lease expiry by the database clock, one for the whole batch
+	expiryQuery, expiryArgs, err := squirrel.Select().
 		PlaceholderFormat(squirrel.Dollar).
-		Set("status", "processing").
-		Set("attempts", task.Attempts+1).
-		Where(squirrel.Eq{"id": task.ID}).
+		Column(leaseExpiry(lease)).
 		ToSql()
 
 	if err != nil {
-		return nil, fmt.Errorf("build update query: %w", err)
+		return nil, fmt.Errorf("build lease query: %w", err)
 	}
 
-	_, err = tx.Exec(ctx, updateQuery, updateArgs...)
-	if err != nil {
-		return nil, fmt.Errorf("update status: %w", err)
+	var lockedUntil time.Time
+	if err := tx.QueryRow(ctx, expiryQuery, expiryArgs...).Scan(&lockedUntil); err != nil {
+		return nil, fmt.Errorf("query lease expiry: %w", err)
+	}
+
+	// Mark as processing and take the lease, each task for its own worker
+	for i, task := range tasks {
+		updateQuery, updateArgs, err := squirrel.Update("email_queue").
+			PlaceholderFormat(squirrel.Dollar).
+			Set("status", "processing").
+			Set("attempts", squirrel.Expr("attempts + 1")).
+			Set("worker_id", workerIDs[i]).
+			Set("locked_until", lockedUntil).
+			Where(squirrel.Eq{"id": task.ID}).
+			ToSql()
+
+		if err != nil {
+			return nil, fmt.Errorf("build update query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, updateQuery, updateArgs...); err != nil {
+			return nil, fmt.Errorf("update status: %w", err)
+		}
 	}
 
 	if err := tx.Commit(ctx); err != nil {
 		return nil, fmt.Errorf("commit transaction: %w", err)
 	}
 
-	task.Attempts++ // Increment for the current attempt
-	return &task, nil
+	for i, task := range tasks {
+		task.Attempts++ // Increment for the current attempt
+		task.WorkerID = workerIDs[i]
+		task.LockedUntil = lockedUntil
+	}
+	return tasks, nil
+}
+
+// Extend moves the lease of a task being sent to NOW() + lease
+// WHY: A send may outlive the lease (slow SMTP server, retries inside the mailer);
+// without the heartbeat another worker would reclaim the task and send it twice
+// Returns ErrLeaseLost if the task is no longer leased to workerID
+func (q *Queue) Extend(ctx context.Context, taskID int64, workerID string, lease time.Duration) error {
+	query, args, err := squirrel.Update("email_queue").
+		PlaceholderFormat(squirrel.Dollar).
+		Set("locked_until", leaseExpiry(lease)).
+		Where(leasedTo(taskID, workerID)).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("build query: %w", err)
+	}
+
+	return q.execLeased(ctx, query, args)
 }
 
 // MarkCompleted marks a task as successfully completed
 // WHY: Worker calls this after successfully sending an email
-// HOW: Sets status='completed' and processed_at=NOW()
-func (q *Queue) MarkCompleted(ctx context.Context, taskID int64) error {
+// HOW: Sets status='completed' and processed_at=NOW(), releases the lease
+// Returns ErrLeaseLost if the task is no longer leased to workerID
+func (q *Queue) MarkCompleted(ctx context.Context, taskID int64, workerID string) error {
 	query, args, err := squirrel.Update("email_queue").
 		PlaceholderFormat(squirrel.Dollar).
 		Set("status", "completed").
 		Set("processed_at", time.Now()).
-		Where(squirrel.Eq{"id": taskID}).
+		Set("locked_until", nil).
+		Where(leasedTo(taskID, workerID)).
 		ToSql()
 
 	if err != nil {
 		return fmt.Errorf("build query: %w", err)
 	}
 
-	_, err = q.db.Exec(ctx, query, args...)
-	if err != nil {
-		return fmt.Errorf("exec query: %w", err)
-	}
-
-	return nil
+	return q.execLeased(ctx, query, args)
 }
 
 // MarkFailed marks a task as failed and schedules retry if attempts remain
@@ -190,7 +325,9 @@ func (q *Queue) MarkCompleted(ctx context.Context, taskID int64) error {
 // - 2nd retry: 2 minutes
 // - 3rd retry: 4 minutes
 // - etc.
-func (q *Queue) MarkFailed(ctx context.Context, taskID int64, attempts, maxAttempts int, errorMsg string) error {
+//
+// Releases the lease; returns ErrLeaseLost if the task is no longer leased to workerID
+func (q *Queue) MarkFailed(ctx context.Context, taskID int64, workerID string, attempts, maxAttempts int, errorMsg string) error {
 	if attempts >= maxAttempts {
 		// Exceeded max attempts, mark as permanently failed
 		query, args, err := squirrel.Update("email_queue").
@@ -198,19 +335,15 @@ func (q *Queue) MarkFailed(ctx context.Context, taskID int64, attempts, maxAttem
 			Set("status", "failed").
 			Set("error", errorMsg).
 			Set("processed_at", time.Now()).
-			Where(squirrel.Eq{"id": taskID}).
+			Set("locked_until", nil).
+			Where(leasedTo(taskID, workerID)).
 			ToSql()
 
 		if err != nil {
 			return fmt.Errorf("build query: %w", err)
 		}
 
-		_, err = q.db.Exec(ctx, query, args...)
-		if err != nil {
-			return fmt.Errorf("exec query: %w", err)
-		}
-
-		return nil
+		return q.execLeased(ctx, query, args)
 	}
 
 	// Calculate next retry time with exponential backoff
@@ -223,17 +356,40 @@ func (q *Queue) MarkFailed(ctx context.Context, taskID int64, attempts, maxAttem
 		Set("status", "pending").
 		Set("error", errorMsg).
 		Set("next_retry_at", nextRetry).
-		Where(squirrel.Eq{"id": taskID}).
+		Set("locked_until", nil).
+		Where(leasedTo(taskID, workerID)).
 		ToSql()
 
 	if err != nil {
 		return fmt.Errorf("build query: %w", err)
 	}
 
-	_, err = q.db.Exec(ctx, query, args...)
+	return q.execLeased(ctx, query, args)
+}
+
+// leaseExpiry - lease end by the database clock
+func leaseExpiry(lease time.Duration) squirrel.Sqlizer {
+	return squirrel.Expr("NOW() + make_interval(secs => ?)", lease.Seconds())
+}
+
+// leasedTo matches the task only while it is processed under workerID's lease
+// WHY: After a reclaim the old worker must not overwrite the new one's result
+func leasedTo(taskID int64, workerID string) squirrel.Sqlizer {
+	return squirrel.Eq{
+		"id":        taskID,
+		"status":    "processing",
+		"worker_id": workerID,
+	}
+}
+
+// execLeased runs an update guarded by leasedTo, ErrLeaseLost if nothing matched
+func (q *Queue) execLeased(ctx context.Context, query string, args []any) error {
+	tag, err := q.db.Exec(ctx, query, args...)
 	if err != nil {
 		return fmt.Errorf("exec query: %w", err)
 	}
-
+	if tag.RowsAffected() == 0 {
+		return ErrLeaseLost
+	}
 	return nil
 }
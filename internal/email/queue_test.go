@@ -0,0 +1,288 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// testQueue connects to TEST_DATABASE_URL - a migrated database (make db-migrate-up);
+// email_queue is emptied before and after the test
+func testQueue(t *testing.T) *Queue {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := pgxpool.New(t.Context(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+
+	clean := func() {
+		if _, err := db.Exec(context.Background(), "DELETE FROM email_queue"); err != nil {
+			t.Fatalf("clean email_queue: %v", err)
+		}
+	}
+	clean()
+	t.Cleanup(func() {
+		clean()
+		db.Close()
+	})
+
+	return NewQueue(db)
+}
+
+// testSender renders the real templates and delivers through mailer
+func testSender(t *testing.T, mailer Mailer) *Sender {
+	t.Helper()
+
+	sender, err := NewSender(mailer, "noreply@example.com", "../../web/templates/email", "../../web/static/images/gophers")
+	if err != nil {
+		t.Fatalf("new sender: %v", err)
+	}
+	return sender
+}
+
+// enqueueTasks adds n verification emails to user0@example.com ... user<n-1>@example.com
+func enqueueTasks(t *testing.T, q *Queue, n int) {
+	t.Helper()
+
+	for i := range n {
+		to := fmt.Sprintf("user%d@example.com", i)
+		if err := q.Enqueue(t.Context(), EmailTypeVerification, to, nil, map[string]string{"URL": "https://example.com/verify"}); err != nil {
+			t.Fatalf("enqueue: %v", err)
+		}
+	}
+}
+
+// countStatus returns the number of tasks with the given status
+func countStatus(t *testing.T, q *Queue, status string) int {
+	t.Helper()
+
+	var n int
+	if err := q.db.QueryRow(t.Context(), "SELECT COUNT(*) FROM email_queue WHERE status = $1", status).Scan(&n); err != nil {
+		t.Fatalf("count %s tasks: %v", status, err)
+	}
+	return n
+}
+
+// waitForStatus polls until n tasks have the given status
+func waitForStatus(t *testing.T, q *Queue, status string, n int, timeout time.Duration) {
+	t.Helper()
+
+	deadline := time.Now().Add(timeout)
+	for countStatus(t, q, status) < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d of %d tasks %s after %v", countStatus(t, q, status), n, status, timeout)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// assertDeliveredOnce checks that each of the n recipients got exactly one email
+func assertDeliveredOnce(t *testing.T, mailer *MemoryMailer, n int) {
+	t.Helper()
+
+	sent := make(map[string]int)
+	for _, msg := range mailer.Messages() {
+		sent[msg.To]++
+	}
+	for i := range n {
+		to := fmt.Sprintf("user%d@example.com", i)
+		if sent[to] != 1 {
+			t.Errorf("%s got %d emails, want 1", to, sent[to])
+		}
+	}
+	if len(sent) != n {
+		t.Errorf("emails sent to %d recipients, want %d", len(sent), n)
+	}
+}
+
+// hangingMailer freezes mid-send until release is closed, ignoring ctx -
+// a worker process that hung or was killed: it never reports back
+type hangingMailer struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (m *hangingMailer) Send(_ context.Context, _ *Message) error {
+	select {
+	case m.started <- struct{}{}:
+	default:
+	}
+	<-m.release
+	return errors.New("worker killed")
+}
+
+func testQueueConfig() config.EmailQueueConfig {
+	return config.EmailQueueConfig{
+		Workers:         4,
+		BatchSize:       10,
+		PollInterval:    50 * time.Millisecond,
+		Lease:           config.MinEmailQueueLease,
+		ShutdownTimeout: time.Second,
+	}
+}
+
+func TestDequeueReclaimsExpiredLease(t *testing.T) {
+	q := testQueue(t)
+	enqueueTasks(t, q, 1)
+
+	crashed, err := q.Dequeue(t.Context(), []string{"crashed"}, 100*time.Millisecond)
+	if err != nil || len(crashed) != 1 {
+		t.Fatalf("dequeue: %d tasks, err %v", len(crashed), err)
+	}
+
+	// Lease still valid - nobody else gets the task
+	if tasks, err := q.Dequeue(t.Context(), []string{"other"}, time.Minute); err != nil || len(tasks) != 0 {
+		t.Fatalf("dequeue under a valid lease: %d tasks, err %v", len(tasks), err)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+
+	tasks, err := q.Dequeue(t.Context(), []string{"other"}, time.Minute)
+	if err != nil || len(tasks) != 1 {
+		t.Fatalf("dequeue after lease expiry: %d tasks, err %v", len(tasks), err)
+	}
+	if tasks[0].Status != "processing" || tasks[0].Attempts != 2 {
+		t.Errorf("reclaimed task: status %q, attempts %d; want processing, 2", tasks[0].Status, tasks[0].Attempts)
+	}
+
+	// The old worker wakes up - its result must not overwrite the new owner's
+	if err := q.MarkCompleted(t.Context(), crashed[0].ID, "crashed"); !errors.Is(err, ErrLeaseLost) {
+		t.Errorf("MarkCompleted by the old worker: err %v, want ErrLeaseLost", err)
+	}
+	if err := q.MarkCompleted(t.Context(), tasks[0].ID, "other"); err != nil {
+		t.Errorf("MarkCompleted by the new worker: %v", err)
+	}
+}
+
+func TestDequeueFailsPoisonTask(t *testing.T) {
+	q := testQueue(t)
+	enqueueTasks(t, q, 1)
+
+	// Every attempt crashes the worker: lease taken and never extended or released
+	for attempt := 1; ; attempt++ {
+		tasks, err := q.Dequeue(t.Context(), []string{fmt.Sprintf("crashed-%d", attempt)}, 50*time.Millisecond)
+		if err != nil {
+			t.Fatalf("dequeue: %v", err)
+		}
+		if len(tasks) == 0 {
+			if attempt != 4 {
+				t.Fatalf("task dropped after %d attempts, want max_attempts = 3", attempt-1)
+			}
+			break
+		}
+		if attempt > 3 {
+			t.Fatalf("attempt %d leased a task with max_attempts = %d", attempt, tasks[0].MaxAttempts)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	if n := countStatus(t, q, "failed"); n != 1 {
+		t.Errorf("%d failed tasks, want 1", n)
+	}
+}
+
+func TestPoolWorkerKilledMidSend(t *testing.T) {
+	q := testQueue(t)
+	enqueueTasks(t, q, 1)
+
+	// Worker A takes the task and hangs; its heartbeat stops once it is shut down
+	hanging := &hangingMailer{started: make(chan struct{}, 1), release: make(chan struct{})}
+	cfgA := testQueueConfig()
+	cfgA.ShutdownTimeout = 10 * time.Millisecond
+	poolA, err := NewPool(q, testSender(t, hanging), cfgA)
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	ctxA, stopA := context.WithCancel(t.Context())
+	doneA := make(chan struct{})
+	go func() {
+		poolA.Run(ctxA)
+		close(doneA)
+	}()
+
+	select {
+	case <-hanging.started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker A did not start sending")
+	}
+	stopA()
+
+	// Worker B reclaims the task after the lease expires
+	mailer := NewMemoryMailer()
+	poolB, err := NewPool(q, testSender(t, mailer), testQueueConfig())
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+	ctxB, stopB := context.WithCancel(t.Context())
+	doneB := make(chan struct{})
+	go func() {
+		poolB.Run(ctxB)
+		close(doneB)
+	}()
+
+	waitForStatus(t, q, "completed", 1, config.MinEmailQueueLease+10*time.Second)
+	stopB()
+	<-doneB
+
+	// A comes back to life: its failure report must not touch B's result
+	close(hanging.release)
+	<-doneA
+
+	assertDeliveredOnce(t, mailer, 1)
+	if n := countStatus(t, q, "completed"); n != 1 {
+		t.Errorf("%d completed tasks after the old worker reported, want 1", n)
+	}
+
+	var workerID string
+	if err := q.db.QueryRow(t.Context(), "SELECT worker_id FROM email_queue").Scan(&workerID); err != nil {
+		t.Fatalf("select worker_id: %v", err)
+	}
+	if !strings.HasPrefix(workerID, poolB.ID()+"/") {
+		t.Errorf("task completed by %q, want a worker of pool %q", workerID, poolB.ID())
+	}
+}
+
+func TestPoolsDeliverExactlyOnce(t *testing.T) {
+	q := testQueue(t)
+	const tasks = 30
+	enqueueTasks(t, q, tasks)
+
+	mailer := NewMemoryMailer()
+	ctx, stop := context.WithCancel(t.Context())
+	done := make(chan struct{}, 3)
+	for range 3 {
+		cfg := testQueueConfig()
+		cfg.Workers = 2
+		cfg.BatchSize = 3
+		pool, err := NewPool(q, testSender(t, mailer), cfg)
+		if err != nil {
+			t.Fatalf("new pool: %v", err)
+		}
+		go func() {
+			pool.Run(ctx)
+			done <- struct{}{}
+		}()
+	}
+
+	waitForStatus(t, q, "completed", tasks, 10*time.Second)
+	stop()
+	for range 3 {
+		<-done
+	}
+
+	assertDeliveredOnce(t, mailer, tasks)
+}
@@ -6,24 +6,37 @@ import (
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"mime"
+	"os"
 	"path/filepath"
+	"strings"
 )
 
+// inlineImages - images available to templates as <img src="cid:NAME">, file in the images directory
+var inlineImages = map[string]string{
+	"gopher": "dance.gif",
+}
+
 // Sender handles email sending with template rendering
 // WHY: Provides high-level API for sending emails with type-specific templates
-// HOW: Uses SMTPClient for actual sending, renders HTML templates with data
+// HOW: Renders HTML templates with data, delivers through Mailer
 type Sender struct {
-	smtp      *SMTPClient
+	mailer    Mailer
+	from      string
 	templates map[string]*template.Template
+	images    map[string]Attachment // by Content-ID
 }
 
 // NewSender creates a new Sender instance
-// WHY: Initializes sender with SMTP client and loads all email templates
-// HOW: Parses templates from web/templates/email/ directory
-func NewSender(smtp *SMTPClient, templatesDir string) (*Sender, error) {
+// WHY: Initializes sender with mailer and loads all email templates
+// HOW: Parses templates from web/templates/email/ directory,
+// reads inline images from imagesDir
+func NewSender(mailer Mailer, from, templatesDir, imagesDir string) (*Sender, error) {
 	sender := &Sender{
-		smtp:      smtp,
+		mailer:    mailer,
+		from:      from,
 		templates: make(map[string]*template.Template),
+		images:    make(map[string]Attachment),
 	}
 
 	// Load all email templates
@@ -33,6 +46,8 @@ func NewSender(smtp *SMTPClient, templatesDir string) (*Sender, error) {
 		"verification",
 		"password_reset",
 		"notification",
+		"account_unlock",
+		"magic_link",
 	}
 
 	for _, name := range templateFiles {
@@ -44,18 +59,34 @@ func NewSender(smtp *SMTPClient, templatesDir string) (*Sender, error) {
 		sender.templates[name] = tmpl
 	}
 
+	// Load inline images
+	// WHY: External image URLs are blocked by most clients until the user allows them,
+	// embedded ones are shown right away
+	for cid, file := range inlineImages {
+		data, err := os.ReadFile(filepath.Join(imagesDir, file))
+		if err != nil {
+			return nil, fmt.Errorf("read inline image %s: %w", cid, err)
+		}
+		sender.images[cid] = Attachment{
+			Filename:    file,
+			ContentType: mime.TypeByExtension(filepath.Ext(file)),
+			Data:        data,
+			ContentID:   cid,
+		}
+	}
+
 	return sender, nil
 }
 
 // Send sends an email based on task configuration
 // WHY: Single method to send any type of email
-// HOW: Looks up config by EmailType, renders template, sends via SMTP
+// HOW: Looks up config by EmailType, renders template, sends via Mailer
 //
 // This is the main method called by the worker:
 // 1. Get email config (subject + template name) by type
 // 2. Parse JSON payload into map
 // 3. Render HTML template with payload data
-// 4. Send via SMTP
+// 4. Send via Mailer
 func (s *Sender) Send(ctx context.Context, task *Task) error {
 	// Get configuration for this email type
 	config, ok := GetConfig(task.EmailType)
@@ -75,9 +106,16 @@ func (s *Sender) Send(ctx context.Context, task *Task) error {
 		return fmt.Errorf("render template: %w", err)
 	}
 
-	// Send email via SMTP
-	if err := s.smtp.Send(task.RecipientEmail, config.Subject, body); err != nil {
-		return fmt.Errorf("smtp send: %w", err)
+	msg := &Message{
+		From:    s.from,
+		To:      task.RecipientEmail,
+		Subject: config.Subject,
+		HTML:    body,
+		Inline:  s.inlineImagesFor(body),
+	}
+
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		return fmt.Errorf("mailer send: %w", err)
 	}
 
 	return nil
@@ -99,3 +137,15 @@ func (s *Sender) renderTemplate(templateName string, data any) (string, error) {
 
 	return buf.String(), nil
 }
+
+// inlineImagesFor returns images referenced from the rendered HTML
+// Unused ones are not attached - they would only make the message bigger
+func (s *Sender) inlineImagesFor(html string) []Attachment {
+	var images []Attachment
+	for cid, image := range s.images {
+		if strings.Contains(html, `"cid:`+cid+`"`) {
+			images = append(images, image)
+		}
+	}
+	return images
+}
@@ -1,93 +1,330 @@
 package email
 
 import (
-	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"net/smtp"
-	"text/template"
+	"net/textproto"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/udisondev/learn-go/pkg/config"
 )
 
-// SMTPClient handles SMTP email sending
-// WHY: Abstracts SMTP connection and authentication logic
-// HOW: Uses standard library net/smtp package
-type SMTPClient struct {
-	host     string
-	port     int
-	username string
-	password string
-	from     string
-	msgTmpl  *template.Template
+// SMTP connection security modes (SMTP_TLS)
+const (
+	SMTPTLSNone     = "none"     // plaintext, Mailhog and local relays only
+	SMTPTLSStartTLS = "starttls" // plaintext connect, upgrade required (port 587)
+	SMTPTLSImplicit = "implicit" // TLS from the first byte (port 465)
+)
+
+// ErrPermanent marks delivery errors that will fail the same way on retry
+// (5xx reply to MAIL/RCPT/DATA, invalid message); check with errors.Is
+var ErrPermanent = errors.New("permanent delivery error")
+
+// SMTPMailer sends email through an SMTP server
+// WHY: Works with any provider and with Mailhog in development
+// HOW: Speaks SMTP through net/smtp.Client over connections it dials itself:
+// - TLS mode from SMTP_TLS; starttls refuses servers without STARTTLS
+// instead of silently sending in plaintext
+// - every command has a deadline: SMTP_TIMEOUT, or earlier if ctx ends sooner;
+// ctx cancellation interrupts a blocked command
+// - connections are kept idle after a message and reused with RSET
+// (one handshake with TLS and AUTH instead of one per email),
+// closed after SMTP_IDLE_TIMEOUT
+type SMTPMailer struct {
+	host        string
+	port        int
+	username    string
+	password    string
+	tlsMode     string
+	tlsConfig   *tls.Config
+	timeout     time.Duration
+	idleTimeout time.Duration
+	maxIdle     int
+
+	mu     sync.Mutex
+	idle   []*smtpConn
+	closed bool
 }
 
-// NewSMTPClient creates a new SMTP client from configuration
-func NewSMTPClient(cfg *config.EmailConfig) (*SMTPClient, error) {
-	// Parse email message template with headers
-	// WHY: Using template allows for clean separation and easy modification
-	// HOW: Template includes MIME headers and body placeholder
-	msgTmpl, err := template.New("email").Parse(`From: {{.From}}
-To: {{.To}}
-Subject: {{.Subject}}
-MIME-Version: 1.0
-Content-Type: text/html; charset=UTF-8
-
-{{.Body}}
-`)
-	if err != nil {
-		return nil, fmt.Errorf("parse message template: %w", err)
+// smtpConn - pooled connection
+// conn is the raw TCP connection (deadlines are set on it, TLS on top shares them)
+type smtpConn struct {
+	conn      net.Conn
+	client    *smtp.Client
+	idleSince time.Time
+}
+
+var _ Mailer = (*SMTPMailer)(nil)
+
+// NewSMTPMailer creates a new SMTP mailer from configuration
+func NewSMTPMailer(cfg *config.EmailConfig) (*SMTPMailer, error) {
+	switch cfg.SMTP.TLS {
+	case SMTPTLSNone, SMTPTLSStartTLS, SMTPTLSImplicit:
+	default:
+		return nil, fmt.Errorf("unknown smtp tls mode %q", cfg.SMTP.TLS)
+	}
+	if cfg.SMTP.Timeout <= 0 {
+		return nil, errors.New("smtp timeout must be positive")
 	}
 
-	return &SMTPClient{
-		host:     cfg.Host,
-		port:     cfg.Port,
-		username: cfg.Username,
-		password: cfg.Password,
-		from:     cfg.From,
-		msgTmpl:  msgTmpl,
+	return &SMTPMailer{
+		host:        cfg.SMTP.Host,
+		port:        cfg.SMTP.Port,
+		username:    cfg.SMTP.Username,
+		password:    cfg.SMTP.Password,
+		tlsMode:     cfg.SMTP.TLS,
+		tlsConfig:   &tls.Config{ServerName: cfg.SMTP.Host, MinVersion: tls.VersionTLS12},
+		timeout:     cfg.SMTP.Timeout,
+		idleTimeout: cfg.SMTP.IdleTimeout,
+		maxIdle:     cfg.SMTP.MaxIdleConns,
 	}, nil
 }
 
 // Send sends an email via SMTP
 // WHY: Core method to actually send emails
-// HOW: Connects to SMTP server, authenticates (if credentials provided), sends email
-//
-// Parameters:
-// - to: recipient email address
-// - subject: email subject line
-// - body: HTML email body
+// HOW: Takes an idle connection (or dials one), runs MAIL, RCPT, DATA
+// and returns the connection to the pool
 //
-// For Mailhog (development): no authentication required
-// For production SMTP (Gmail, SendGrid, etc): requires username/password
-func (c *SMTPClient) Send(to, subject, body string) error {
-	// Build email message using template
-	var buf bytes.Buffer
-	err := c.msgTmpl.Execute(&buf, map[string]string{
-		"From":    c.from,
-		"To":      to,
-		"Subject": subject,
-		"Body":    body,
-	})
+// Errors wrapping ErrPermanent must not be retried; everything else
+// (network, timeouts, 4xx, handshake and AUTH failures) is transient -
+// a misconfigured password must not fail the whole queue for good
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	data, err := buildMessage(msg, time.Now())
 	if err != nil {
-		return fmt.Errorf("execute message template: %w", err)
+		return fmt.Errorf("%w: build message: %w", ErrPermanent, err)
 	}
 
-	// SMTP server address
-	addr := fmt.Sprintf("%s:%d", c.host, c.port)
-
-	// Setup authentication if credentials are provided
-	// Mailhog doesn't require auth, but production SMTP does
-	var auth smtp.Auth
-	if c.username != "" && c.password != "" {
-		auth = smtp.PlainAuth("", c.username, c.password, c.host)
+	// Envelope sender and recipient - bare addresses, headers may carry names
+	from, err := parseAddress("From", msg.From)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPermanent, err)
+	}
+	to, err := parseAddress("To", msg.To)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPermanent, err)
 	}
 
-	// Send email
-	// If auth is nil (Mailhog case), smtp.SendMail will skip authentication
-	err = smtp.SendMail(addr, auth, c.from, []string{to}, buf.Bytes())
+	c, err := m.acquire(ctx)
 	if err != nil {
+		return fmt.Errorf("smtp connect: %w", err)
+	}
+
+	stop := context.AfterFunc(ctx, c.interrupt)
+	err = c.deliver(ctx, m.timeout, from.Address, to.Address, data)
+	if !stop() {
+		// Interrupted - the deadline may be moved at any moment, don't reuse the connection
+		c.conn.Close()
+		if err == nil {
+			// ctx ended after the server accepted the message - it is sent,
+			// reporting an error would make the queue send it again
+			return nil
+		}
+		return fmt.Errorf("send email: %w", ctx.Err())
+	}
+
+	var reply *textproto.Error
+	switch {
+	case err == nil:
+		m.release(c)
+		return nil
+	case errors.As(err, &reply):
+		// The server answered - the connection is fine, RSET on the next use clears the transaction
+		m.release(c)
+		if reply.Code >= 500 {
+			return fmt.Errorf("%w: send email: %w", ErrPermanent, err)
+		}
+		return fmt.Errorf("send email: %w", err)
+	default:
+		c.conn.Close()
 		return fmt.Errorf("send email: %w", err)
 	}
+}
+
+// Close sends QUIT on idle connections and stops pooling
+func (m *SMTPMailer) Close() error {
+	m.mu.Lock()
+	idle := m.idle
+	m.idle = nil
+	m.closed = true
+	m.mu.Unlock()
 
+	for _, c := range idle {
+		c.quit(m.timeout)
+	}
 	return nil
 }
+
+// acquire returns a ready connection: idle one that answers RSET, or a new one
+func (m *SMTPMailer) acquire(ctx context.Context) (*smtpConn, error) {
+	for {
+		c := m.popIdle()
+		if c == nil {
+			break
+		}
+		if m.idleTimeout > 0 && time.Since(c.idleSince) > m.idleTimeout {
+			c.quit(m.timeout)
+			continue
+		}
+
+		// The server may have dropped it in the meantime
+		c.extend(ctx, m.timeout)
+		if err := c.client.Reset(); err != nil {
+			c.conn.Close()
+			continue
+		}
+		return c, nil
+	}
+
+	return m.dial(ctx)
+}
+
+// popIdle takes the most recently used idle connection
+func (m *SMTPMailer) popIdle() *smtpConn {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.idle) == 0 {
+		return nil
+	}
+	c := m.idle[len(m.idle)-1]
+	m.idle = m.idle[:len(m.idle)-1]
+	return c
+}
+
+// release returns connection to the pool, or closes it if the pool is full or closed
+func (m *SMTPMailer) release(c *smtpConn) {
+	c.idleSince = time.Now()
+
+	m.mu.Lock()
+	if !m.closed && len(m.idle) < m.maxIdle {
+		m.idle = append(m.idle, c)
+		c = nil
+	}
+	m.mu.Unlock()
+
+	if c != nil {
+		c.quit(m.timeout)
+	}
+}
+
+// dial connects, reads the greeting, upgrades to TLS and authenticates
+func (m *SMTPMailer) dial(ctx context.Context) (*smtpConn, error) {
+	addr := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	dialer := &net.Dialer{Timeout: m.timeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &smtpConn{conn: conn}
+	stop := context.AfterFunc(ctx, c.interrupt)
+	err = m.handshake(ctx, c)
+	if !stop() {
+		conn.Close()
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// handshake runs everything before the first MAIL on a fresh connection
+func (m *SMTPMailer) handshake(ctx context.Context, c *smtpConn) error {
+	// Implicit TLS wraps the raw connection, deadlines set on c.conn still apply
+	var conn net.Conn = c.conn
+	if m.tlsMode == SMTPTLSImplicit {
+		tlsConn := tls.Client(c.conn, m.tlsConfig)
+		c.extend(ctx, m.timeout)
+		if err := tlsConn.HandshakeContext(ctx); err != nil {
+			return fmt.Errorf("tls handshake: %w", err)
+		}
+		conn = tlsConn
+	}
+
+	c.extend(ctx, m.timeout)
+	client, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		return fmt.Errorf("greeting: %w", err)
+	}
+	c.client = client
+
+	if m.tlsMode == SMTPTLSStartTLS {
+		c.extend(ctx, m.timeout)
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS")
+		}
+		c.extend(ctx, m.timeout)
+		if err := client.StartTLS(m.tlsConfig); err != nil {
+			return fmt.Errorf("starttls: %w", err)
+		}
+	}
+
+	// Mailhog doesn't require auth, but production SMTP does
+	// PlainAuth itself refuses to send credentials without TLS (except to localhost)
+	if m.username != "" && m.password != "" {
+		c.extend(ctx, m.timeout)
+		if err := client.Auth(smtp.PlainAuth("", m.username, m.password, m.host)); err != nil {
+			return fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// deliver runs one mail transaction
+func (c *smtpConn) deliver(ctx context.Context, timeout time.Duration, from, to string, data []byte) error {
+	c.extend(ctx, timeout)
+	if err := c.client.Mail(from); err != nil {
+		return err
+	}
+
+	c.extend(ctx, timeout)
+	if err := c.client.Rcpt(to); err != nil {
+		return err
+	}
+
+	c.extend(ctx, timeout)
+	w, err := c.client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+
+	// Close sends the final dot and waits for the server to accept the message
+	c.extend(ctx, timeout)
+	return w.Close()
+}
+
+// extend sets the deadline for the next command: timeout from now, or ctx deadline if earlier
+func (c *smtpConn) extend(ctx context.Context, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetDeadline(deadline)
+}
+
+// interrupt unblocks a command in progress, called on ctx cancellation
+func (c *smtpConn) interrupt() {
+	c.conn.SetDeadline(time.Now())
+}
+
+// quit politely ends the session and closes the connection
+func (c *smtpConn) quit(timeout time.Duration) {
+	c.conn.SetDeadline(time.Now().Add(timeout))
+	if err := c.client.Quit(); err != nil {
+		c.conn.Close()
+	}
+}
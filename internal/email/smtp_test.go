@@ -0,0 +1,401 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// Recipients that make fakeSMTP misbehave
+const (
+	rcptBusy     = "busy@example.com"     // RCPT gets 451
+	rcptRejected = "rejected@example.com" // RCPT gets 550
+	rcptSlow     = "slow@example.com"     // RCPT gets no reply until the server is closed
+)
+
+// fakeMessage - mail transaction accepted by fakeSMTP
+type fakeMessage struct {
+	from, to string
+	data     string
+	tls      bool
+}
+
+// fakeSMTP - minimal SMTP server: EHLO, STARTTLS, MAIL, RCPT, DATA, RSET, QUIT
+type fakeSMTP struct {
+	ln       net.Listener
+	tls      *tls.Config // server certificate for STARTTLS
+	starttls bool        // advertise STARTTLS
+
+	conns atomic.Int32
+	rsets atomic.Int32
+
+	mu       sync.Mutex
+	messages []fakeMessage
+
+	done chan struct{} // closed on shutdown, unblocks rcptSlow
+	wg   sync.WaitGroup
+}
+
+// testCertificate returns a server certificate for 127.0.0.1 and a pool trusting it
+func testCertificate(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(nil)
+	srv.StartTLS()
+	defer srv.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	return srv.TLS.Certificates[0], roots
+}
+
+// startFakeSMTP serves SMTP on a random port, wrapping every connection in TLS for implicit mode
+func startFakeSMTP(t *testing.T, mode string) (*fakeSMTP, *SMTPMailer) {
+	t.Helper()
+
+	cert, roots := testCertificate(t)
+	serverTLS := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	if mode == SMTPTLSImplicit {
+		ln = tls.NewListener(ln, serverTLS)
+	}
+
+	s := &fakeSMTP{
+		ln:       ln,
+		tls:      serverTLS,
+		starttls: mode == SMTPTLSStartTLS,
+		done:     make(chan struct{}),
+	}
+	s.wg.Go(s.serve)
+	t.Cleanup(func() {
+		close(s.done)
+		ln.Close()
+		s.wg.Wait()
+	})
+
+	m, err := NewSMTPMailer(&config.EmailConfig{SMTP: config.SMTPConfig{
+		Host:         "127.0.0.1",
+		Port:         ln.Addr().(*net.TCPAddr).Port,
+		TLS:          mode,
+		Timeout:      5 * time.Second,
+		IdleTimeout:  time.Minute,
+		MaxIdleConns: 2,
+	}})
+	if err != nil {
+		t.Fatalf("new smtp mailer: %v", err)
+	}
+	m.tlsConfig.RootCAs = roots
+	t.Cleanup(func() { m.Close() })
+
+	return s, m
+}
+
+func (s *fakeSMTP) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.conns.Add(1)
+		s.wg.Go(func() {
+			defer conn.Close()
+			s.session(conn)
+		})
+	}
+}
+
+// session runs one client connection until QUIT or a read error
+func (s *fakeSMTP) session(conn net.Conn) {
+	_, secure := conn.(*tls.Conn)
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 fake ESMTP")
+
+	var msg fakeMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0])
+
+		switch verb {
+		case "EHLO", "HELO":
+			if s.starttls && !secure {
+				reply("250-fake")
+				reply("250 STARTTLS")
+			} else {
+				reply("250 fake")
+			}
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn, secure = tlsConn, true
+			r = bufio.NewReader(conn)
+		case "MAIL":
+			msg = fakeMessage{from: envelopeAddress(cmd), tls: secure}
+			reply("250 ok")
+		case "RCPT":
+			msg.to = envelopeAddress(cmd)
+			switch msg.to {
+			case rcptBusy:
+				reply("451 try again later")
+			case rcptRejected:
+				reply("550 no such user")
+			case rcptSlow:
+				<-s.done
+				return
+			default:
+				reply("250 ok")
+			}
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			msg.data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 queued")
+		case "RSET":
+			s.rsets.Add(1)
+			msg = fakeMessage{}
+			reply("250 ok")
+		case "NOOP":
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+// received returns accepted messages
+func (s *fakeSMTP) received() []fakeMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]fakeMessage(nil), s.messages...)
+}
+
+// envelopeAddress extracts the address from "MAIL FROM:<a>" / "RCPT TO:<a>"
+func envelopeAddress(cmd string) string {
+	start := strings.IndexByte(cmd, '<')
+	end := strings.IndexByte(cmd, '>')
+	if start < 0 || end < start {
+		return ""
+	}
+	return cmd[start+1 : end]
+}
+
+func testMessage(to string) *Message {
+	return &Message{
+		From:    "Learn Go <noreply@example.com>",
+		To:      to,
+		Subject: "Test",
+		Text:    "Hello",
+	}
+}
+
+func TestSMTPMailerModes(t *testing.T) {
+	tests := []struct {
+		mode       string
+		wantSecure bool
+	}{
+		{SMTPTLSNone, false},
+		{SMTPTLSStartTLS, true},
+		{SMTPTLSImplicit, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			srv, m := startFakeSMTP(t, tt.mode)
+
+			if err := m.Send(t.Context(), testMessage("user@example.com")); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+
+			got := srv.received()
+			if len(got) != 1 {
+				t.Fatalf("received %d messages, want 1", len(got))
+			}
+			if got[0].from != "noreply@example.com" || got[0].to != "user@example.com" {
+				t.Errorf("envelope = %q -> %q, want bare addresses", got[0].from, got[0].to)
+			}
+			if got[0].tls != tt.wantSecure {
+				t.Errorf("sent over tls = %v, want %v", got[0].tls, tt.wantSecure)
+			}
+			if !strings.Contains(got[0].data, "Subject: Test") {
+				t.Errorf("message has no subject header: %q", got[0].data)
+			}
+		})
+	}
+}
+
+func TestSMTPMailerStartTLSRequired(t *testing.T) {
+	// Server without STARTTLS: the mailer must not fall back to plaintext
+	srv, m := startFakeSMTP(t, SMTPTLSNone)
+	m.tlsMode = SMTPTLSStartTLS
+
+	err := m.Send(t.Context(), testMessage("user@example.com"))
+	if err == nil {
+		t.Fatal("send succeeded without STARTTLS")
+	}
+	if errors.Is(err, ErrPermanent) {
+		t.Errorf("missing STARTTLS is a configuration problem, got permanent error: %v", err)
+	}
+	if n := len(srv.received()); n != 0 {
+		t.Errorf("received %d messages in plaintext", n)
+	}
+}
+
+func TestSMTPMailerReplyCodes(t *testing.T) {
+	tests := []struct {
+		name          string
+		to            string
+		wantPermanent bool
+	}{
+		{"4xx is transient", rcptBusy, false},
+		{"5xx is permanent", rcptRejected, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, m := startFakeSMTP(t, SMTPTLSNone)
+
+			err := m.Send(t.Context(), testMessage(tt.to))
+			if err == nil {
+				t.Fatal("send succeeded")
+			}
+			if got := errors.Is(err, ErrPermanent); got != tt.wantPermanent {
+				t.Errorf("errors.Is(err, ErrPermanent) = %v, want %v (err: %v)", got, tt.wantPermanent, err)
+			}
+
+			// The server answered, the connection stays usable
+			if err := m.Send(t.Context(), testMessage("user@example.com")); err != nil {
+				t.Fatalf("send after rejection: %v", err)
+			}
+			if n := srv.conns.Load(); n != 1 {
+				t.Errorf("dialed %d connections, want 1", n)
+			}
+		})
+	}
+}
+
+func TestSMTPMailerDeadline(t *testing.T) {
+	t.Run("ctx deadline", func(t *testing.T) {
+		_, m := startFakeSMTP(t, SMTPTLSNone)
+
+		ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		err := m.Send(ctx, testMessage(rcptSlow))
+		if err == nil {
+			t.Fatal("send succeeded without a reply")
+		}
+		if errors.Is(err, ErrPermanent) {
+			t.Errorf("timeout must be transient, got %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("send returned after %v, ctx deadline ignored", elapsed)
+		}
+	})
+
+	t.Run("smtp timeout", func(t *testing.T) {
+		_, m := startFakeSMTP(t, SMTPTLSNone)
+		m.timeout = 200 * time.Millisecond
+
+		start := time.Now()
+		err := m.Send(t.Context(), testMessage(rcptSlow))
+		if err == nil {
+			t.Fatal("send succeeded without a reply")
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("send returned after %v, SMTP_TIMEOUT ignored", elapsed)
+		}
+	})
+
+	t.Run("timed out connection is not reused", func(t *testing.T) {
+		srv, m := startFakeSMTP(t, SMTPTLSNone)
+		m.timeout = 200 * time.Millisecond
+
+		if err := m.Send(t.Context(), testMessage(rcptSlow)); err == nil {
+			t.Fatal("send succeeded without a reply")
+		}
+		if err := m.Send(t.Context(), testMessage("user@example.com")); err != nil {
+			t.Fatalf("send after timeout: %v", err)
+		}
+		if n := srv.conns.Load(); n != 2 {
+			t.Errorf("dialed %d connections, want 2", n)
+		}
+	})
+}
+
+func TestSMTPMailerReusesIdleConnection(t *testing.T) {
+	for _, mode := range []string{SMTPTLSNone, SMTPTLSStartTLS, SMTPTLSImplicit} {
+		t.Run(mode, func(t *testing.T) {
+			srv, m := startFakeSMTP(t, mode)
+
+			for range 3 {
+				if err := m.Send(t.Context(), testMessage("user@example.com")); err != nil {
+					t.Fatalf("send: %v", err)
+				}
+			}
+
+			if n := len(srv.received()); n != 3 {
+				t.Errorf("received %d messages, want 3", n)
+			}
+			if n := srv.conns.Load(); n != 1 {
+				t.Errorf("dialed %d connections, want 1", n)
+			}
+			if n := srv.rsets.Load(); n != 2 {
+				t.Errorf("RSET sent %d times, want 2 (once per reuse)", n)
+			}
+		})
+	}
+
+	t.Run("expired idle connection is replaced", func(t *testing.T) {
+		srv, m := startFakeSMTP(t, SMTPTLSNone)
+		m.idleTimeout = time.Nanosecond
+
+		for range 2 {
+			if err := m.Send(t.Context(), testMessage("user@example.com")); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+		}
+		if n := srv.conns.Load(); n != 2 {
+			t.Errorf("dialed %d connections, want 2", n)
+		}
+	})
+}
@@ -0,0 +1,57 @@
+package email
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// Elements whose content is never shown
+	hiddenElementsRegex = regexp.MustCompile(`(?is)<(head|style|script)\b.*?</(head|style|script)>`)
+	// <a href="url">label</a>
+	linkRegex = regexp.MustCompile(`(?is)<a\b[^>]*?\bhref\s*=\s*["']([^"']*)["'][^>]*>(.*?)</a>`)
+	// Tags that end a line or a paragraph
+	lineBreakRegex      = regexp.MustCompile(`(?i)<br\s*/?>|</(li|tr)>`)
+	paragraphBreakRegex = regexp.MustCompile(`(?i)</(p|div|h[1-6]|table|ul|ol)>`)
+	listItemRegex       = regexp.MustCompile(`(?i)<li\b[^>]*>`)
+	tagRegex            = regexp.MustCompile(`(?s)<[^>]*>`)
+	spacesRegex         = regexp.MustCompile(`[ \t\r\f\v]+`)
+	blankLinesRegex     = regexp.MustCompile(`\n{3,}`)
+)
+
+// htmlToText builds the plain text alternative of an HTML email
+// WHY: Clients that can't (or are told not to) render HTML show the text part;
+// without it they show raw markup
+// HOW: Good enough for our templates, not a general HTML renderer:
+// head/style/script dropped, links become "label (url)", block ends become
+// line breaks, tags stripped, entities decoded, whitespace collapsed
+func htmlToText(s string) string {
+	s = hiddenElementsRegex.ReplaceAllString(s, "")
+
+	s = linkRegex.ReplaceAllStringFunc(s, func(m string) string {
+		parts := linkRegex.FindStringSubmatch(m)
+		url := parts[1]
+		label := strings.TrimSpace(spacesRegex.ReplaceAllString(tagRegex.ReplaceAllString(parts[2], ""), " "))
+		label = strings.Join(strings.Fields(label), " ")
+		if label == "" || label == url {
+			return url
+		}
+		return label + " (" + url + ")"
+	})
+
+	s = lineBreakRegex.ReplaceAllString(s, "\n")
+	s = paragraphBreakRegex.ReplaceAllString(s, "\n\n")
+	s = listItemRegex.ReplaceAllString(s, "- ")
+	s = tagRegex.ReplaceAllString(s, "")
+	s = html.UnescapeString(s)
+
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(spacesRegex.ReplaceAllString(line, " "))
+	}
+	s = strings.Join(lines, "\n")
+	s = blankLinesRegex.ReplaceAllString(s, "\n\n")
+
+	return strings.TrimSpace(s) + "\n"
+}
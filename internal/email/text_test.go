@@ -0,0 +1,55 @@
+package email
+
+import "testing"
+
+func TestHTMLToText(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "paragraphs",
+			html: "<p>Первый</p><p>Второй</p>",
+			want: "Первый\n\nВторой\n",
+		},
+		{
+			name: "link with label",
+			html: `<a href="https://learn-go.dev/verify">Подтвердить <b>email</b></a>`,
+			want: "Подтвердить email (https://learn-go.dev/verify)\n",
+		},
+		{
+			name: "link labelled with its url",
+			html: `<a href='https://learn-go.dev'>https://learn-go.dev</a>`,
+			want: "https://learn-go.dev\n",
+		},
+		{
+			name: "hidden elements dropped",
+			html: "<head><title>T</title></head><style>p{color:red}</style><script>alert(1)</script><p>Текст</p>",
+			want: "Текст\n",
+		},
+		{
+			name: "line breaks and lists",
+			html: "Строка<br>Ещё<br/>\n<ul><li>один</li><li>два</li></ul>",
+			want: "Строка\nЕщё\n\n- один\n- два\n",
+		},
+		{
+			name: "entities decoded",
+			html: "<p>5 &lt; 7 &amp;&amp; &quot;Go&quot;&nbsp;!</p>",
+			want: "5 < 7 && \"Go\" !\n",
+		},
+		{
+			name: "whitespace collapsed",
+			html: "<div>\n   много    пробелов\t\t</div>\n\n\n\n<div>конец</div>",
+			want: "много пробелов\n\nконец\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := htmlToText(tt.html); got != tt.want {
+				t.Errorf("htmlToText:\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package email
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// listenRetryDelay - pause before reconnecting a failed LISTEN connection
+const listenRetryDelay = 5 * time.Second
+
+// resultTimeout bounds recording a send result; it is recorded even after
+// the drain canceled the send - a sent but unrecorded email is sent again
+const resultTimeout = 10 * time.Second
+
+// abandonTimeout - how long the drain waits for canceled sends before giving up on them
+// (a Mailer ignoring ctx must not block shutdown), covers recording their results
+const abandonTimeout = resultTimeout + 5*time.Second
+
+// Pool sends queued emails with a fixed number of concurrent workers
+// WHY: One task per poll tick drains a burst of registrations at one email per tick
+// HOW: A dispatcher leases up to (free workers, batch size) tasks at once and starts
+// a goroutine per task; it wakes on:
+// - NOTIFY from Queue.Enqueue (immediately after registration)
+// - a finished task, if the last batch was full (more tasks are likely waiting)
+// - PollInterval ticks - fallback for a lost LISTEN and for retries becoming due
+//
+// Each task is leased to the ID of the worker sending it (pool ID + "/" + slot)
+// and the lease is extended while sending (see Queue.Extend); a task of a crashed
+// process is reclaimed after the lease expires
+type Pool struct {
+	id              string
+	queue           taskQueue
+	sender          *Sender
+	batchSize       int
+	pollInterval    time.Duration
+	lease           time.Duration
+	shutdownTimeout time.Duration
+	abandonTimeout  time.Duration
+
+	slots chan int      // free worker slots, capacity = workers
+	freed chan struct{} // signalled when a task finishes
+	wg    sync.WaitGroup
+}
+
+// taskQueue - Queue operations the pool relies on
+type taskQueue interface {
+	Listen(ctx context.Context, wake func()) error
+	Dequeue(ctx context.Context, workerIDs []string, lease time.Duration) ([]*Task, error)
+	Extend(ctx context.Context, taskID int64, workerID string, lease time.Duration) error
+	MarkCompleted(ctx context.Context, taskID int64, workerID string) error
+	MarkFailed(ctx context.Context, taskID int64, workerID string, attempts, maxAttempts int, errorMsg string) error
+}
+
+// NewPool creates worker pool from configuration
+func NewPool(queue *Queue, sender *Sender, cfg config.EmailQueueConfig) (*Pool, error) {
+	return newPool(queue, sender, cfg)
+}
+
+func newPool(queue taskQueue, sender *Sender, cfg config.EmailQueueConfig) (*Pool, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	slots := make(chan int, cfg.Workers)
+	for slot := range cfg.Workers {
+		slots <- slot
+	}
+
+	return &Pool{
+		id:              newWorkerID(),
+		queue:           queue,
+		sender:          sender,
+		batchSize:       cfg.BatchSize,
+		pollInterval:    cfg.PollInterval,
+		lease:           cfg.Lease,
+		shutdownTimeout: cfg.ShutdownTimeout,
+		abandonTimeout:  abandonTimeout,
+		slots:           slots,
+		freed:           make(chan struct{}, 1),
+	}, nil
+}
+
+// ID returns pool identity, the prefix of its workers' IDs in email_queue.worker_id
+func (p *Pool) ID() string {
+	return p.id
+}
+
+// workerID returns identity of the worker in slot, stored in email_queue.worker_id
+func (p *Pool) workerID(slot int) string {
+	return fmt.Sprintf("%s/%d", p.id, slot)
+}
+
+// Run processes tasks until ctx is done, then drains in-flight sends
+// WHY: A send cut off mid-way is retried after the lease expires - with a
+// possible duplicate email; letting it finish is better
+// HOW: After ctx is done no new tasks are taken; running sends get
+// ShutdownTimeout to finish, then their context is canceled
+func (p *Pool) Run(ctx context.Context) {
+	// Sends outlive ctx until the drain deadline
+	workCtx, cancelWork := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelWork()
+
+	wake := make(chan struct{}, 1)
+	go p.listen(ctx, wake)
+
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		backlog := p.dispatch(ctx, workCtx)
+
+		// Finished tasks matter only when there is more work than workers
+		var freed <-chan struct{}
+		if backlog {
+			freed = p.freed
+		}
+
+		select {
+		case <-ctx.Done():
+			p.drain(cancelWork)
+			return
+		case <-wake:
+		case <-ticker.C:
+		case <-freed:
+		}
+	}
+}
+
+// dispatch leases tasks for the free workers and starts them
+// Reports whether more tasks are likely waiting (all workers busy or the batch was full)
+func (p *Pool) dispatch(ctx, workCtx context.Context) bool {
+	// Only dispatch takes slots, so these can be taken without blocking
+	limit := min(len(p.slots), p.batchSize)
+	if limit == 0 {
+		return true
+	}
+
+	slots := make([]int, limit)
+	workerIDs := make([]string, limit)
+	for i := range slots {
+		slots[i] = <-p.slots
+		workerIDs[i] = p.workerID(slots[i])
+	}
+
+	tasks, err := p.queue.Dequeue(ctx, workerIDs, p.lease)
+	if err != nil {
+		if ctx.Err() == nil {
+			slog.Error("Failed to dequeue email tasks", "error", err)
+		}
+		tasks = nil
+	}
+
+	// Task i is leased to the worker in slots[i]
+	for i, task := range tasks {
+		slot := slots[i]
+		p.wg.Go(func() {
+			defer p.release(slot)
+			p.process(workCtx, task)
+		})
+	}
+	for _, slot := range slots[len(tasks):] {
+		p.slots <- slot
+	}
+
+	return err == nil && len(tasks) == limit
+}
+
+// release frees the task's slot and wakes the dispatcher
+func (p *Pool) release(slot int) {
+	p.slots <- slot
+	select {
+	case p.freed <- struct{}{}:
+	default:
+	}
+}
+
+// drain waits for running sends, canceling them after ShutdownTimeout
+// and abandoning the ones that don't stop within abandonTimeout
+func (p *Pool) drain(cancelWork context.CancelFunc) {
+	running := cap(p.slots) - len(p.slots)
+	if running > 0 {
+		slog.Info("Draining in-flight emails", "running", running, "timeout", p.shutdownTimeout)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(p.shutdownTimeout):
+		slog.Warn("Drain timeout, canceling in-flight emails; they will be retried")
+		cancelWork()
+	}
+
+	select {
+	case <-done:
+	case <-time.After(p.abandonTimeout):
+		slog.Error("Canceled emails did not stop, abandoning them; they are retried after the lease expires",
+			"running", cap(p.slots)-len(p.slots),
+		)
+	}
+}
+
+// listen keeps a LISTEN connection, reconnecting after failures
+// Polling covers the time without it
+func (p *Pool) listen(ctx context.Context, wake chan<- struct{}) {
+	notify := func() {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+
+	for {
+		err := p.queue.Listen(ctx, notify)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Warn("Email queue listener failed, polling until reconnect", "error", err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+// process sends a single leased task and records the result
+// HOW: Send with heartbeat → Mark completed/failed
+func (p *Pool) process(ctx context.Context, task *Task) {
+	if task.Status == "processing" {
+		slog.Warn("Reclaimed email task with expired lease", "task_id", task.ID)
+	}
+
+	slog.Info("Processing email task",
+		"task_id", task.ID,
+		"worker_id", task.WorkerID,
+		"email_type", task.EmailType.String(),
+		"recipient", task.RecipientEmail,
+		"attempt", task.Attempts,
+	)
+
+	// Send email, extending the lease while it takes
+	sendCtx, cancelSend := context.WithCancelCause(ctx)
+	var wg sync.WaitGroup
+	wg.Go(func() { p.heartbeat(sendCtx, task, cancelSend) })
+	err := p.sender.Send(sendCtx, task)
+	cancelSend(nil)
+	wg.Wait()
+
+	// The drain may have canceled ctx after the server accepted the message
+	resultCtx, cancelResult := context.WithTimeout(context.WithoutCancel(ctx), resultTimeout)
+	defer cancelResult()
+
+	if errors.Is(context.Cause(sendCtx), ErrLeaseLost) {
+		// Another worker owns the task now, its result wins
+		slog.Warn("Lease lost while sending, leaving task to its new worker", "task_id", task.ID, "error", err)
+		return
+	}
+
+	if err != nil {
+		p.fail(resultCtx, task, err)
+		return
+	}
+
+	// Email sent successfully - mark as completed
+	if err := p.queue.MarkCompleted(resultCtx, task.ID, task.WorkerID); err != nil {
+		// ErrLeaseLost here means the lease expired before the heartbeat could extend it
+		// (database unreachable) - the new worker may send the email a second time
+		slog.Error("Failed to mark task as completed", "task_id", task.ID, "error", err)
+		return
+	}
+
+	slog.Info("Email sent successfully",
+		"task_id", task.ID,
+		"email_type", task.EmailType.String(),
+		"recipient", task.RecipientEmail,
+	)
+}
+
+// fail records a failed send, scheduling a retry unless the error is permanent
+func (p *Pool) fail(ctx context.Context, task *Task, err error) {
+	slog.Error("Failed to send email",
+		"task_id", task.ID,
+		"error", err,
+		"attempts", task.Attempts,
+		"max_attempts", task.MaxAttempts,
+	)
+
+	// Permanent errors (rejected recipient, invalid message) fail the same way on retry
+	attempts := task.Attempts
+	if errors.Is(err, ErrPermanent) {
+		attempts = task.MaxAttempts
+	}
+
+	if markErr := p.queue.MarkFailed(ctx, task.ID, task.WorkerID, attempts, task.MaxAttempts, err.Error()); markErr != nil {
+		slog.Error("Failed to mark task as failed", "task_id", task.ID, "error", markErr)
+		return
+	}
+
+	if errors.Is(err, ErrPermanent) {
+		slog.Warn("Task permanently failed, not retrying",
+			"task_id", task.ID,
+			"attempts", task.Attempts,
+		)
+	} else if task.Attempts >= task.MaxAttempts {
+		slog.Warn("Task permanently failed after max attempts",
+			"task_id", task.ID,
+			"attempts", task.Attempts,
+		)
+	} else {
+		slog.Info("Task will be retried",
+			"task_id", task.ID,
+			"next_attempt", task.Attempts+1,
+		)
+	}
+}
+
+// heartbeat extends the task lease every lease/3 until ctx is done
+// A failed extension is retried on the next tick (the lease still has time left);
+// a lost lease cancels the send
+func (p *Pool) heartbeat(ctx context.Context, task *Task, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(p.lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := p.queue.Extend(ctx, task.ID, task.WorkerID, p.lease)
+			if errors.Is(err, ErrLeaseLost) {
+				cancel(err)
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				slog.Warn("Failed to extend email task lease", "task_id", task.ID, "error", err)
+			}
+		}
+	}
+}
+
+// newWorkerID returns pool identity
+// host and pid point at the process in logs, the random suffix survives pid reuse
+func newWorkerID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), rand.Text()[:8])
+}
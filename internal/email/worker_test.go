@@ -0,0 +1,462 @@
+package email
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// fakeQueue - in-memory taskQueue with the same lease rules as Queue:
+// only the worker holding the lease may extend or finish a task;
+// like the database, it refuses writes with a canceled ctx
+type fakeQueue struct {
+	mu        sync.Mutex
+	nextID    int64
+	pending   []*Task
+	leased    map[int64]string // task ID -> worker ID
+	completed []int64
+	failed    []int64
+	dequeues  int
+
+	wake      func()
+	listening chan struct{} // closed once Listen is set up
+}
+
+var _ taskQueue = (*fakeQueue)(nil)
+
+func newFakeQueue() *fakeQueue {
+	return &fakeQueue{
+		leased:    make(map[int64]string),
+		listening: make(chan struct{}),
+	}
+}
+
+// add enqueues a verification email to each recipient, without notifying
+func (q *fakeQueue) add(recipients ...string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, to := range recipients {
+		q.nextID++
+		q.pending = append(q.pending, &Task{
+			ID:             q.nextID,
+			EmailType:      EmailTypeVerification,
+			RecipientEmail: to,
+			Payload:        []byte(`{"URL": "https://example.com/verify"}`),
+			MaxAttempts:    3,
+			Status:         "pending",
+		})
+	}
+}
+
+// notify delivers a NOTIFY to the listening pool
+func (q *fakeQueue) notify() {
+	q.mu.Lock()
+	wake := q.wake
+	q.mu.Unlock()
+	wake()
+}
+
+func (q *fakeQueue) Listen(ctx context.Context, wake func()) error {
+	q.mu.Lock()
+	q.wake = wake
+	q.mu.Unlock()
+	close(q.listening)
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (q *fakeQueue) Dequeue(_ context.Context, workerIDs []string, _ time.Duration) ([]*Task, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.dequeues++
+	n := min(len(workerIDs), len(q.pending))
+	tasks := q.pending[:n]
+	q.pending = q.pending[n:]
+
+	for i, task := range tasks {
+		task.Status = "processing"
+		task.Attempts++
+		task.WorkerID = workerIDs[i]
+		q.leased[task.ID] = workerIDs[i]
+	}
+	return tasks, nil
+}
+
+func (q *fakeQueue) Extend(ctx context.Context, taskID int64, workerID string, _ time.Duration) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if q.leased[taskID] != workerID {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+func (q *fakeQueue) MarkCompleted(ctx context.Context, taskID int64, workerID string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if q.leased[taskID] != workerID {
+		return ErrLeaseLost
+	}
+	delete(q.leased, taskID)
+	q.completed = append(q.completed, taskID)
+	return nil
+}
+
+func (q *fakeQueue) MarkFailed(ctx context.Context, taskID int64, workerID string, _, _ int, _ string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if q.leased[taskID] != workerID {
+		return ErrLeaseLost
+	}
+	delete(q.leased, taskID)
+	q.failed = append(q.failed, taskID)
+	return nil
+}
+
+// stats returns the number of completed and failed tasks and Dequeue calls
+func (q *fakeQueue) stats() (completed, failed, dequeues int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.completed), len(q.failed), q.dequeues
+}
+
+// blockingMailer holds every send until release is closed or ctx ends
+type blockingMailer struct {
+	started chan *Message
+	release chan struct{}
+}
+
+func newBlockingMailer() *blockingMailer {
+	return &blockingMailer{
+		started: make(chan *Message, 100),
+		release: make(chan struct{}),
+	}
+}
+
+func (m *blockingMailer) Send(ctx context.Context, msg *Message) error {
+	m.started <- msg
+	select {
+	case <-m.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// waitStarted waits for n sends to begin
+func (m *blockingMailer) waitStarted(t *testing.T, n int) {
+	t.Helper()
+
+	for range n {
+		select {
+		case <-m.started:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("send did not start")
+		}
+	}
+}
+
+// acceptedLateMailer completes every send only once ctx is canceled -
+// the server accepted the message just as the drain gave up on it
+type acceptedLateMailer struct {
+	started chan struct{}
+}
+
+func (m *acceptedLateMailer) Send(ctx context.Context, _ *Message) error {
+	m.started <- struct{}{}
+	<-ctx.Done()
+	return nil
+}
+
+// runPool starts the pool and returns a channel closed when Run returns
+func runPool(t *testing.T, q *fakeQueue, mailer Mailer, cfg config.EmailQueueConfig) (*Pool, context.CancelFunc, <-chan struct{}) {
+	t.Helper()
+
+	pool, err := newPool(q, testSender(t, mailer), cfg)
+	if err != nil {
+		t.Fatalf("new pool: %v", err)
+	}
+
+	cancel, done := startPool(t, pool)
+	return pool, cancel, done
+}
+
+// startPool runs the pool until the test ends; done is closed when Run returns
+func startPool(t *testing.T, pool *Pool) (context.CancelFunc, <-chan struct{}) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(t.Context())
+	done := make(chan struct{})
+	go func() {
+		pool.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return cancel, done
+}
+
+// fakeQueueConfig polls rarely - anything sent sooner was woken by NOTIFY or a freed worker
+func fakeQueueConfig() config.EmailQueueConfig {
+	return config.EmailQueueConfig{
+		Workers:         2,
+		BatchSize:       10,
+		PollInterval:    time.Hour,
+		Lease:           config.MinEmailQueueLease,
+		ShutdownTimeout: 5 * time.Second,
+	}
+}
+
+func TestPoolWakesOnNotify(t *testing.T) {
+	q := newFakeQueue()
+	mailer := NewMemoryMailer()
+	runPool(t, q, mailer, fakeQueueConfig())
+
+	select {
+	case <-q.listening:
+	case <-time.After(5 * time.Second):
+		t.Fatal("pool did not start listening")
+	}
+
+	// A task added before the initial dispatch would be sent without a notification
+	deadline := time.Now().Add(5 * time.Second)
+	for _, _, dequeues := q.stats(); dequeues == 0; _, _, dequeues = q.stats() {
+		if time.Now().After(deadline) {
+			t.Fatal("pool did not dispatch on start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	q.add("user@example.com")
+	time.Sleep(100 * time.Millisecond)
+	if n := len(mailer.Messages()); n != 0 {
+		t.Fatalf("sent %d emails without a notification", n)
+	}
+
+	q.notify()
+
+	deadline = time.Now().Add(5 * time.Second)
+	for len(mailer.Messages()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("email not sent after NOTIFY")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPoolTakesBacklogWhenWorkerFrees(t *testing.T) {
+	q := newFakeQueue()
+	// More tasks than workers, enqueued before start: no further notifications
+	q.add("user0@example.com", "user1@example.com", "user2@example.com", "user3@example.com", "user4@example.com")
+
+	mailer := NewMemoryMailer()
+	runPool(t, q, mailer, fakeQueueConfig())
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if completed, _, _ := q.stats(); completed == 5 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("backlog not sent without polling")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPoolWorkerIDs(t *testing.T) {
+	q := newFakeQueue()
+	q.add("user0@example.com", "user1@example.com", "user2@example.com")
+
+	mailer := newBlockingMailer()
+	cfg := fakeQueueConfig()
+	cfg.Workers = 3
+	pool, _, _ := runPool(t, q, mailer, cfg)
+	mailer.waitStarted(t, 3)
+
+	q.mu.Lock()
+	ids := make(map[string]bool)
+	for _, workerID := range q.leased {
+		ids[workerID] = true
+		if !strings.HasPrefix(workerID, pool.ID()+"/") {
+			t.Errorf("worker ID %q is not under pool %q", workerID, pool.ID())
+		}
+	}
+	q.mu.Unlock()
+	if len(ids) != 3 {
+		t.Errorf("3 tasks leased to %d distinct workers, want 3", len(ids))
+	}
+
+	close(mailer.release)
+}
+
+func TestPoolDrain(t *testing.T) {
+	t.Run("in-flight sends finish", func(t *testing.T) {
+		q := newFakeQueue()
+		q.add("user0@example.com", "user1@example.com")
+
+		mailer := newBlockingMailer()
+		_, stop, done := runPool(t, q, mailer, fakeQueueConfig())
+		mailer.waitStarted(t, 2)
+
+		stop()
+		q.add("late@example.com")
+
+		select {
+		case <-done:
+			t.Fatal("Run returned before in-flight sends finished")
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		close(mailer.release)
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not return after sends finished")
+		}
+
+		completed, failed, _ := q.stats()
+		if completed != 2 || failed != 0 {
+			t.Errorf("completed %d, failed %d; want 2, 0", completed, failed)
+		}
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if len(q.pending) != 1 {
+			t.Errorf("task enqueued during shutdown was taken")
+		}
+	})
+
+	t.Run("timeout cancels sends", func(t *testing.T) {
+		q := newFakeQueue()
+		q.add("user@example.com")
+
+		mailer := newBlockingMailer()
+		cfg := fakeQueueConfig()
+		cfg.ShutdownTimeout = 50 * time.Millisecond
+		_, stop, done := runPool(t, q, mailer, cfg)
+		mailer.waitStarted(t, 1)
+
+		start := time.Now()
+		stop()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not return after the shutdown timeout")
+		}
+		if elapsed := time.Since(start); elapsed < cfg.ShutdownTimeout {
+			t.Errorf("Run returned after %v, before the shutdown timeout", elapsed)
+		}
+
+		// Recorded as failed for a retry, not left to the lease
+		if completed, failed, _ := q.stats(); completed != 0 || failed != 1 {
+			t.Errorf("completed %d, failed %d; want 0, 1", completed, failed)
+		}
+	})
+
+	t.Run("send accepted after cancel is completed", func(t *testing.T) {
+		q := newFakeQueue()
+		q.add("user@example.com")
+
+		mailer := &acceptedLateMailer{started: make(chan struct{}, 1)}
+		cfg := fakeQueueConfig()
+		cfg.ShutdownTimeout = 50 * time.Millisecond
+		_, stop, done := runPool(t, q, mailer, cfg)
+		<-mailer.started
+
+		stop()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Run did not return after the shutdown timeout")
+		}
+
+		// Left in processing, it would be sent again once the lease expires
+		if completed, failed, _ := q.stats(); completed != 1 || failed != 0 {
+			t.Errorf("completed %d, failed %d; want 1, 0", completed, failed)
+		}
+	})
+
+	t.Run("mailer ignoring cancel is abandoned", func(t *testing.T) {
+		q := newFakeQueue()
+		q.add("user@example.com")
+
+		mailer := &hangingMailer{started: make(chan struct{}, 1), release: make(chan struct{})}
+		defer close(mailer.release)
+
+		cfg := fakeQueueConfig()
+		cfg.ShutdownTimeout = 50 * time.Millisecond
+		pool, err := newPool(q, testSender(t, mailer), cfg)
+		if err != nil {
+			t.Fatalf("new pool: %v", err)
+		}
+		pool.abandonTimeout = 50 * time.Millisecond
+		stop, done := startPool(t, pool)
+		<-mailer.started
+
+		stop()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("shutdown blocked by a send ignoring ctx")
+		}
+	})
+
+	t.Run("idle pool stops at once", func(t *testing.T) {
+		q := newFakeQueue()
+		_, stop, done := runPool(t, q, NewMemoryMailer(), fakeQueueConfig())
+
+		stop()
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("idle pool did not stop")
+		}
+	})
+}
+
+func TestNewPoolRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*config.EmailQueueConfig)
+	}{
+		{"no workers", func(c *config.EmailQueueConfig) { c.Workers = 0 }},
+		{"no batch", func(c *config.EmailQueueConfig) { c.BatchSize = 0 }},
+		{"zero poll interval", func(c *config.EmailQueueConfig) { c.PollInterval = 0 }},
+		{"zero lease", func(c *config.EmailQueueConfig) { c.Lease = 0 }},
+		{"nanosecond lease", func(c *config.EmailQueueConfig) { c.Lease = 2 * time.Nanosecond }},
+		{"lease below minimum", func(c *config.EmailQueueConfig) { c.Lease = time.Second }},
+		{"negative shutdown timeout", func(c *config.EmailQueueConfig) { c.ShutdownTimeout = -time.Second }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := fakeQueueConfig()
+			tt.modify(&cfg)
+			if _, err := newPool(newFakeQueue(), nil, cfg); err == nil {
+				t.Errorf("pool created with %+v", cfg)
+			}
+		})
+	}
+}
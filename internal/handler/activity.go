@@ -0,0 +1,232 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/session"
+	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// auditEventLabels - названия событий журнала для страницы "Недавняя активность"
+var auditEventLabels = map[audit.EventType]string{
+	audit.EventTypeLoginSucceeded:       "Вход в аккаунт",
+	audit.EventTypeLoginFailed:          "Неудачная попытка входа",
+	audit.EventTypeLogout:               "Выход из аккаунта",
+	audit.EventTypeEmailVerified:        "Email подтвержден",
+	audit.EventTypePasswordChanged:      "Пароль изменен",
+	audit.EventTypeSessionRevoked:       "Сессия завершена",
+	audit.EventTypeSessionsRevoked:      "Завершены все другие сессии",
+	audit.EventTypeTwoFactorEnabled:     "Двухфакторная аутентификация включена",
+	audit.EventTypeTwoFactorDisabled:    "Двухфакторная аутентификация отключена",
+	audit.EventTypeAccessTokenCreated:   "Создан токен доступа",
+	audit.EventTypeAccessTokenRevoked:   "Токен доступа отозван",
+	audit.EventTypePlanChanged:          "Тариф изменен",
+	audit.EventTypeAccountSuspended:     "Аккаунт заблокирован",
+	audit.EventTypeAccountUnsuspended:   "Блокировка аккаунта снята",
+	audit.EventTypeImpersonationStarted: "Поддержка вошла в аккаунт",
+	audit.EventTypeImpersonationStopped: "Поддержка вышла из аккаунта",
+}
+
+// loginMethodLabels - способы входа из details["method"]
+var loginMethodLabels = map[string]string{
+	"password":           "пароль",
+	"two_factor":         "пароль и код 2FA",
+	"magic_link":         "ссылка из письма",
+	"email_verification": "подтверждение email",
+}
+
+// loginFailureLabels - причины неудачного входа из details["reason"]
+var loginFailureLabels = map[string]string{
+	"invalid_password":        "неверный пароль",
+	"invalid_two_factor_code": "неверный код 2FA",
+	"throttled":               "слишком много попыток",
+}
+
+// GetActivity отображает страницу "Недавняя активность" в профиле
+// Пользователь видит входы, неудачные попытки и изменения настроек безопасности -
+// по ним можно заметить, что аккаунтом пользуется кто-то еще
+func (h *Handler) GetActivity(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+
+	events, err := h.audit.Recent(r.Context(), u.ID)
+	if err != nil {
+		slog.Error("Failed to load audit events", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := templates.ActivityData{
+		User:   u,
+		Events: make([]templates.ActivityEventView, 0, len(events)),
+	}
+	for _, e := range events {
+		data.Events = append(data.Events, activityView(e))
+	}
+
+	if err := h.templates.Render(w, r, "activity.html", data); err != nil {
+		slog.Error("Failed to render activity page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// activityView готовит событие журнала к показу
+func activityView(e audit.Event) templates.ActivityEventView {
+	browser, os := session.ParseUserAgent(e.UserAgent)
+
+	view := templates.ActivityEventView{
+		Label:     auditEventLabels[e.Type],
+		Warning:   e.Type == audit.EventTypeLoginFailed,
+		IP:        e.IP,
+		Device:    browser + " · " + os,
+		CreatedAt: e.CreatedAt,
+	}
+	if view.Label == "" {
+		view.Label = e.Type.String()
+	}
+
+	switch e.Type {
+	case audit.EventTypeLoginSucceeded:
+		method := e.Details["method"]
+		if provider, ok := strings.CutPrefix(method, "oauth:"); ok {
+			view.Detail = "Способ: " + provider
+		} else if label, ok := loginMethodLabels[method]; ok {
+			view.Detail = "Способ: " + label
+		}
+	case audit.EventTypeLoginFailed:
+		if label, ok := loginFailureLabels[e.Details["reason"]]; ok {
+			view.Detail = "Причина: " + label
+		}
+	case audit.EventTypeAccessTokenCreated:
+		view.Detail = e.Details["name"]
+	case audit.EventTypePlanChanged:
+		view.Detail = e.Details["from"] + " → " + e.Details["to"]
+	}
+
+	return view
+}
+
+// apiAuditEvent - событие журнала в ответе админского API
+type apiAuditEvent struct {
+	ID        int64             `json:"id"`
+	UserID    *int64            `json:"user_id"`
+	Type      string            `json:"type"`
+	IP        string            `json:"ip"`
+	UserAgent string            `json:"user_agent"`
+	RequestID string            `json:"request_id"`
+	Details   map[string]string `json:"details"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// GetAdminAuditEvents отдает журнал аудита с фильтрами (только для админов)
+//
+// Параметры запроса (все необязательные):
+// - user_id, ip - точное совпадение
+// - type - тип события, можно несколько через запятую
+// - since, until - RFC 3339
+// - before_id - следующая страница: next_before_id из предыдущего ответа
+// - limit - не больше AUDIT_MAX_QUERY_LIMIT
+func (h *Handler) GetAdminAuditEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAuditFilter(r)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	events, err := h.audit.Query(r.Context(), filter)
+	if err != nil {
+		slog.Error("Failed to query audit events", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal error"})
+		return
+	}
+
+	resp := struct {
+		Events       []apiAuditEvent `json:"events"`
+		NextBeforeID int64           `json:"next_before_id,omitempty"`
+	}{
+		Events: make([]apiAuditEvent, 0, len(events)),
+	}
+	for _, e := range events {
+		resp.Events = append(resp.Events, apiAuditEvent{
+			ID:        e.ID,
+			UserID:    e.UserID,
+			Type:      e.Type.String(),
+			IP:        e.IP,
+			UserAgent: e.UserAgent,
+			RequestID: e.RequestID,
+			Details:   e.Details,
+			CreatedAt: e.CreatedAt,
+		})
+	}
+	if len(events) > 0 {
+		resp.NextBeforeID = events[len(events)-1].ID
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseAuditFilter читает фильтр журнала из query-параметров
+func parseAuditFilter(r *http.Request) (audit.Filter, error) {
+	q := r.URL.Query()
+	var f audit.Filter
+
+	if v := q.Get("user_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return f, errors.New("invalid user_id")
+		}
+		f.UserID = &id
+	}
+
+	if v := q.Get("type"); v != "" {
+		for _, name := range strings.Split(v, ",") {
+			t, err := audit.ParseEventType(strings.TrimSpace(name))
+			if err != nil {
+				return f, errors.New("invalid type")
+			}
+			f.Types = append(f.Types, t)
+		}
+	}
+
+	f.IP = q.Get("ip")
+
+	for _, p := range []struct {
+		name string
+		dst  *time.Time
+	}{
+		{"since", &f.Since},
+		{"until", &f.Until},
+	} {
+		if v := q.Get(p.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return f, errors.New("invalid " + p.name)
+			}
+			*p.dst = t
+		}
+	}
+
+	if v := q.Get("before_id"); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id < 0 {
+			return f, errors.New("invalid before_id")
+		}
+		f.BeforeID = id
+	}
+
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return f, errors.New("invalid limit")
+		}
+		f.Limit = n
+	}
+
+	return f, nil
+}
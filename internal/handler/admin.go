@@ -0,0 +1,285 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/email"
+	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// adminSearchLimit - сколько пользователей показывать в результатах поиска
+const adminSearchLimit = 50
+
+// GetAdminUsers отображает поиск пользователей в админке
+// Без запроса показывает последних зарегистрированных
+func (h *Handler) GetAdminUsers(w http.ResponseWriter, r *http.Request) {
+	admin, _ := user.FromCtx(r.Context())
+	query := r.URL.Query().Get("q")
+
+	users, err := h.userService.SearchUsers(r.Context(), query, adminSearchLimit)
+	if err != nil {
+		slog.Error("Failed to search users", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data := templates.AdminUsersData{
+		User:  admin,
+		Query: query,
+		Users: users,
+	}
+
+	if err := h.templates.Render(w, r, "admin-users.html", data); err != nil {
+		slog.Error("Failed to render admin users page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// GetAdminUser отображает карточку пользователя: тариф, прогресс, сессии и журнал
+func (h *Handler) GetAdminUser(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.adminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	data, err := h.adminUserData(r, target)
+	if err != nil {
+		slog.Error("Failed to load admin user card", "error", err, "user_id", target.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.templates.Render(w, r, "admin-user.html", data); err != nil {
+		slog.Error("Failed to render admin user page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// PostAdminChangePlan меняет тариф пользователя
+// Изменение попадает в журнал аудита пользователя (plan_changed с from/to)
+func (h *Handler) PostAdminChangePlan(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.adminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	plan, err := user.ParseSubPlan(r.FormValue("plan"))
+	if err != nil {
+		h.renderAdminUserCard(w, r, target, "Неизвестный тариф")
+		return
+	}
+
+	previous, err := h.userService.ChangeSubPlan(r.Context(), target.ID, plan)
+	if err != nil {
+		slog.Error("Failed to change sub plan", "error", err, "user_id", target.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Пользователь кешируется вместе с сессией - новый тариф должен действовать сразу
+	h.sessionService.InvalidateUser(target.ID)
+
+	if previous != plan {
+		h.audit.Record(r, audit.EventTypePlanChanged, &target.ID, h.adminDetails(r, map[string]string{
+			"from": previous.String(),
+			"to":   plan.String(),
+		}))
+		slog.Info("Sub plan changed by admin", "user_id", target.ID, "from", previous, "to", plan)
+	}
+
+	target.SubPlan = plan
+	h.renderAdminUserCard(w, r, target, "Тариф изменен")
+}
+
+// PostAdminResendVerification отправляет пользователю новое письмо для подтверждения email
+func (h *Handler) PostAdminResendVerification(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.adminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if target.IsVerified {
+		h.renderAdminUserCard(w, r, target, "Email уже подтвержден")
+		return
+	}
+
+	req, err := h.userService.ResendVerification(r.Context(), target.Email)
+	if err != nil {
+		slog.Error("Failed to resend verification", "error", err, "user_id", target.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// req == nil: email успели подтвердить между загрузкой карточки и запросом
+	if req != nil {
+		payload := map[string]string{
+			"token":     req.Token,
+			"user_name": req.UserName,
+		}
+
+		if err := h.emailQueue.Enqueue(r.Context(), email.EmailTypeVerification, req.Email, &req.UserID, payload); err != nil {
+			slog.Error("Failed to enqueue verification email", "error", err, "user_id", req.UserID)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		slog.Info("Verification email resent by admin", "user_id", req.UserID)
+	}
+
+	h.renderAdminUserCard(w, r, target, "Письмо отправлено")
+}
+
+// PostAdminSuspend блокирует аккаунт
+// Все сессии пользователя завершаются, токены доступа перестают приниматься
+func (h *Handler) PostAdminSuspend(w http.ResponseWriter, r *http.Request) {
+	admin, _ := user.FromCtx(r.Context())
+	target, ok := h.adminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	// Заблокировать себя - потерять доступ к админке без возможности вернуть его
+	if target.ID == admin.ID {
+		h.renderAdminUserCard(w, r, target, "Нельзя заблокировать свой аккаунт")
+		return
+	}
+
+	if err := h.userService.Suspend(r.Context(), target.ID); err != nil {
+		slog.Error("Failed to suspend user", "error", err, "user_id", target.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.sessionService.DeleteUserSessions(r.Context(), target.ID); err != nil {
+		slog.Error("Failed to revoke sessions of suspended user", "error", err, "user_id", target.ID)
+	}
+	h.sessionService.InvalidateUser(target.ID)
+
+	h.audit.Record(r, audit.EventTypeAccountSuspended, &target.ID, h.adminDetails(r, nil))
+	slog.Info("User suspended", "user_id", target.ID, "admin_id", admin.ID)
+
+	h.reloadAdminUserCard(w, r, target.ID, "Аккаунт заблокирован")
+}
+
+// PostAdminUnsuspend снимает блокировку аккаунта
+func (h *Handler) PostAdminUnsuspend(w http.ResponseWriter, r *http.Request) {
+	target, ok := h.adminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.userService.Unsuspend(r.Context(), target.ID); err != nil {
+		slog.Error("Failed to unsuspend user", "error", err, "user_id", target.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit.Record(r, audit.EventTypeAccountUnsuspended, &target.ID, h.adminDetails(r, nil))
+	slog.Info("User unsuspended", "user_id", target.ID)
+
+	h.reloadAdminUserCard(w, r, target.ID, "Блокировка снята")
+}
+
+// adminTarget загружает пользователя из URL ({id})
+// Если пользователя нет - отвечает 404 и возвращает false
+func (h *Handler) adminTarget(w http.ResponseWriter, r *http.Request) (*user.User, bool) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, false
+	}
+
+	target, err := h.userService.GetUserByID(r.Context(), id)
+	if errors.Is(err, user.ErrUserNotFound) {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	if err != nil {
+		slog.Error("Failed to get user", "error", err, "user_id", id)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return nil, false
+	}
+
+	return target, true
+}
+
+// adminDetails добавляет к details события ID администратора, который его совершил
+func (h *Handler) adminDetails(r *http.Request, details map[string]string) map[string]string {
+	admin, _ := user.FromCtx(r.Context())
+	if details == nil {
+		details = make(map[string]string, 1)
+	}
+	details["admin_id"] = strconv.FormatInt(admin.ID, 10)
+	return details
+}
+
+// adminUserData собирает данные карточки пользователя
+func (h *Handler) adminUserData(r *http.Request, target *user.User) (*templates.AdminUserData, error) {
+	admin, _ := user.FromCtx(r.Context())
+
+	progress, err := h.userService.GetProgress(r.Context(), target.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := h.sessionService.ListDevices(r.Context(), target.ID, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := h.audit.Recent(r.Context(), target.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	data := &templates.AdminUserData{
+		User:     admin,
+		Target:   target,
+		Plans:    allSubPlans,
+		Progress: progress,
+		Devices:  devices,
+		Events:   make([]templates.ActivityEventView, 0, len(events)),
+	}
+	for _, e := range events {
+		data.Events = append(data.Events, activityView(e))
+	}
+
+	return data, nil
+}
+
+// renderAdminUserCard отдает обновленную карточку пользователя для HTMX
+func (h *Handler) renderAdminUserCard(w http.ResponseWriter, r *http.Request, target *user.User, notice string) {
+	admin, _ := user.FromCtx(r.Context())
+
+	data := &templates.AdminUserData{
+		User:   admin,
+		Target: target,
+		Plans:  allSubPlans,
+		Notice: notice,
+	}
+
+	if err := h.templates.RenderComponent(w, "admin-user-card.html", data); err != nil {
+		slog.Error("Failed to render admin user card", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// reloadAdminUserCard перечитывает пользователя и отдает его карточку
+// Нужно после блокировки: suspended_at выставляет БД
+func (h *Handler) reloadAdminUserCard(w http.ResponseWriter, r *http.Request, userID int64, notice string) {
+	target, err := h.userService.GetUserByID(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to get user", "error", err, "user_id", userID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	h.renderAdminUserCard(w, r, target, notice)
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// apiUser - профиль пользователя в ответах API
+type apiUser struct {
+	ID         int64  `json:"id"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+	SubPlan    string `json:"sub_plan"`
+	Score      int    `json:"score"`
+	IsVerified bool   `json:"is_verified"`
+}
+
+// GetAPIMe возвращает профиль владельца токена
+// Консольный клиент вызывает его при входе, чтобы проверить токен
+func (h *Handler) GetAPIMe(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+
+	writeJSON(w, http.StatusOK, apiUser{
+		ID:         u.ID,
+		Name:       u.Name,
+		Email:      u.Email,
+		SubPlan:    u.SubPlan.String(),
+		Score:      u.Score,
+		IsVerified: u.IsVerified,
+	})
+}
+
+// writeJSON отдает ответ API в JSON
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to write json response", "error", err)
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+
+	"github.com/udisondev/learn-go/internal/clientip"
+)
+
+// maxCSPReportSize - отчет о нарушении занимает пару килобайт, больше не читаем
+const maxCSPReportSize = 64 << 10
+
+// cspViolation - поля отчета, которые полезны в логах
+// Браузеры присылают два формата с разными именами полей:
+// - report-uri (application/csp-report): {"csp-report": {"document-uri": ...}}
+// - report-to (application/reports+json): [{"type": "csp-violation", "body": {"documentURL": ...}}]
+type cspViolation struct {
+	DocumentURI        string `json:"document-uri"`
+	DocumentURL        string `json:"documentURL"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effectiveDirective"`
+	BlockedURI         string `json:"blocked-uri"`
+	BlockedURL         string `json:"blockedURL"`
+	SourceFile         string `json:"source-file"`
+	SourceFileNew      string `json:"sourceFile"`
+	LineNumber         int    `json:"line-number"`
+	LineNumberNew      int    `json:"lineNumber"`
+	Disposition        string `json:"disposition"`
+}
+
+// PostCSPReport принимает отчеты браузера о нарушениях Content-Security-Policy
+// WHY: Без отчетов не узнать, что политика ломает страницы или что кто-то
+// пытается внедрить скрипт
+// HOW: Разбираем оба формата отчетов и пишем нарушения в лог
+//
+// Всегда отвечаем 204 - браузеру ответ не важен, ошибки разбора только логируем
+func (h *Handler) PostCSPReport(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCSPReportSize))
+	if err != nil {
+		slog.Warn("Failed to read CSP report", "error", err)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	violations, err := parseCSPReport(body)
+	if err != nil {
+		slog.Warn("Failed to parse CSP report", "error", err, "content_type", r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	for _, v := range violations {
+		slog.Warn("CSP violation",
+			"document", firstNonEmpty(v.DocumentURI, v.DocumentURL),
+			"directive", firstNonEmpty(v.EffectiveDirective, v.ViolatedDirective),
+			"blocked", firstNonEmpty(v.BlockedURI, v.BlockedURL),
+			"source", firstNonEmpty(v.SourceFile, v.SourceFileNew),
+			"line", max(v.LineNumber, v.LineNumberNew),
+			"disposition", v.Disposition,
+			"ip", clientip.FromRequest(r),
+			"user_agent", r.UserAgent(),
+		)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// parseCSPReport разбирает отчет в любом из двух форматов
+func parseCSPReport(body []byte) ([]cspViolation, error) {
+	// report-to: массив отчетов, среди них могут быть не только CSP
+	var batch []struct {
+		Type string       `json:"type"`
+		Body cspViolation `json:"body"`
+	}
+	if err := json.Unmarshal(body, &batch); err == nil {
+		var violations []cspViolation
+		for _, report := range batch {
+			if report.Type == "csp-violation" {
+				violations = append(violations, report.Body)
+			}
+		}
+		return violations, nil
+	}
+
+	// report-uri: один отчет в объекте
+	var single struct {
+		Report cspViolation `json:"csp-report"`
+	}
+	if err := json.Unmarshal(body, &single); err != nil {
+		return nil, err
+	}
+	return []cspViolation{single.Report}, nil
+}
+
+// firstNonEmpty возвращает первую непустую строку
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/udisondev/learn-go/internal/clientip"
+	"github.com/udisondev/learn-go/internal/middleware"
+)
+
+// CSRFFailure отвечает на запрос, не прошедший проверку CSRF
+// WHY: Чаще всего это не атака, а устаревшая вкладка (истек cookie с токеном) -
+// пользователю нужно понятное сообщение, а не пустой экран
+// HOW: HTMX получает плашку в #flash (HX-Retarget, 403 показывает скрипт в layout),
+// обычный запрос - 403 текстом
+func (h *Handler) CSRFFailure(w http.ResponseWriter, r *http.Request) {
+	slog.Warn("CSRF check failed",
+		"reason", middleware.CSRFFailureReason(r),
+		"method", r.Method,
+		"path", r.URL.Path,
+		"ip", clientip.FromRequest(r),
+	)
+
+	if !middleware.IsHTMX(r) {
+		http.Error(w, "Forbidden - invalid CSRF token, reload the page and try again", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("HX-Retarget", "#flash")
+	w.Header().Set("HX-Reswap", "innerHTML")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+
+	if err := h.templates.RenderComponent(w, "csrf-error.html", nil); err != nil {
+		slog.Error("Failed to render CSRF error", "error", err)
+	}
+}
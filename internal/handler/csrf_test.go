@@ -0,0 +1,59 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/udisondev/learn-go/internal/middleware"
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+func TestCSRFFailure(t *testing.T) {
+	h := &Handler{templates: loadTemplates(t)}
+
+	cfg := &config.CSRFConfig{Secret: "0123456789abcdef0123456789abcdef"}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request without token reached the handler")
+	})
+	protected := middleware.CSRF(cfg, http.HandlerFunc(h.CSRFFailure))(next)
+
+	t.Run("htmx gets flash fragment", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		req.Header.Set("HX-Request", "true")
+		rec := httptest.NewRecorder()
+
+		protected.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+		if got := rec.Header().Get("HX-Retarget"); got != "#flash" {
+			t.Errorf("HX-Retarget = %q, want #flash", got)
+		}
+		if got := rec.Header().Get("HX-Reswap"); got != "innerHTML" {
+			t.Errorf("HX-Reswap = %q, want innerHTML", got)
+		}
+		if body := rec.Body.String(); !strings.Contains(body, "Страница устарела") {
+			t.Errorf("body is not the csrf-error fragment: %q", body)
+		}
+	})
+
+	t.Run("plain request gets text", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/login", nil)
+		rec := httptest.NewRecorder()
+
+		protected.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+		if rec.Header().Get("HX-Retarget") != "" {
+			t.Error("HX-Retarget set for a non-HTMX request")
+		}
+		if body := rec.Body.String(); strings.Contains(body, "<div") {
+			t.Errorf("non-HTMX request got HTML: %q", body)
+		}
+	})
+}
@@ -1,9 +1,16 @@
 package handler
 
 import (
+	"github.com/udisondev/learn-go/internal/apitoken"
+	"github.com/udisondev/learn-go/internal/audit"
 	"github.com/udisondev/learn-go/internal/email"
+	"github.com/udisondev/learn-go/internal/impersonation"
+	"github.com/udisondev/learn-go/internal/loginguard"
+	"github.com/udisondev/learn-go/internal/oauth"
+	"github.com/udisondev/learn-go/internal/ratelimit"
 	"github.com/udisondev/learn-go/internal/session"
 	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/twofactor"
 	"github.com/udisondev/learn-go/internal/user"
 	"github.com/udisondev/learn-go/pkg/config"
 )
@@ -13,19 +20,33 @@ type Handler struct {
 	templates      *templates.Templates
 	userService    *user.Service
 	sessionService *session.Service
+	twoFactor      *twofactor.Service
+	oauth          *oauth.Service
+	loginGuard     *loginguard.Service
+	apiTokens      *apitoken.Service
+	audit          *audit.Service
+	impersonation  *impersonation.Service
 	emailQueue     *email.Queue
+	rateLimiter    *ratelimit.Limiter
 	cfg            *config.Config
 	// TODO: add more services when ready
 	// courseService *course.Service
 }
 
 // New creates a new Handler instance
-func New(tmpl *templates.Templates, userService *user.Service, sessionService *session.Service, emailQueue *email.Queue, cfg *config.Config) *Handler {
+func New(tmpl *templates.Templates, userService *user.Service, sessionService *session.Service, twoFactor *twofactor.Service, oauthService *oauth.Service, loginGuard *loginguard.Service, apiTokens *apitoken.Service, auditService *audit.Service, impersonationService *impersonation.Service, emailQueue *email.Queue, rateLimiter *ratelimit.Limiter, cfg *config.Config) *Handler {
 	return &Handler{
 		templates:      tmpl,
 		userService:    userService,
 		sessionService: sessionService,
+		twoFactor:      twoFactor,
+		oauth:          oauthService,
+		loginGuard:     loginGuard,
+		apiTokens:      apiTokens,
+		audit:          auditService,
+		impersonation:  impersonationService,
 		emailQueue:     emailQueue,
+		rateLimiter:    rateLimiter,
 		cfg:            cfg,
 	}
 }
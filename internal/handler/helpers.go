@@ -1,47 +1,26 @@
 package handler
 
 import (
-	"net"
-	"net/http"
-	"strings"
+	"context"
+	"log/slog"
+
+	"github.com/udisondev/learn-go/internal/email"
+	"github.com/udisondev/learn-go/internal/user"
 )
 
-// getRealIP извлекает реальный IP адрес клиента
-// WHY: RemoteAddr может содержать IP прокси, а не клиента
-// HOW: Проверяем заголовки от reverse proxy (nginx, cloudflare)
-//
-// Порядок проверки:
-// 1. X-Forwarded-For (стандартный заголовок от nginx/proxy)
-// 2. X-Real-IP (альтернативный заголовок)
-// 3. RemoteAddr (fallback, если нет прокси)
+// notifyUser ставит в очередь письмо-уведомление о событии в аккаунте
+// WHY: Пользователь должен узнать об изменениях безопасности,
+// даже если их сделал не он (например, отключение 2FA атакующим)
+// HOW: EmailTypeNotification с темой и текстом в payload
 //
-// Returns: IP адрес без порта
-func getRealIP(r *http.Request) string {
-	// X-Forwarded-For может содержать несколько IP через запятую
-	// Формат: "client, proxy1, proxy2"
-	// Берем первый IP (это реальный клиент)
-	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		ips := strings.Split(xff, ",")
-		if len(ips) > 0 {
-			ip := strings.TrimSpace(ips[0])
-			if ip != "" {
-				return ip
-			}
-		}
+// Ошибка не прерывает основное действие - только логируется
+func (h *Handler) notifyUser(ctx context.Context, u *user.User, subject, message string) {
+	payload := map[string]string{
+		"subject": subject,
+		"message": message,
 	}
 
-	// X-Real-IP - альтернативный заголовок от некоторых proxy
-	if xrip := r.Header.Get("X-Real-IP"); xrip != "" {
-		return strings.TrimSpace(xrip)
+	if err := h.emailQueue.Enqueue(ctx, email.EmailTypeNotification, u.Email, &u.ID, payload); err != nil {
+		slog.Error("Failed to enqueue notification email", "error", err, "user_id", u.ID, "subject", subject)
 	}
-
-	// Fallback: берем RemoteAddr и убираем порт
-	// RemoteAddr формат: "192.168.1.1:12345" или "[::1]:12345"
-	ip, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		// Если не удалось распарсить (нет порта), возвращаем как есть
-		return r.RemoteAddr
-	}
-
-	return ip
 }
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/impersonation"
+	"github.com/udisondev/learn-go/internal/middleware"
+	"github.com/udisondev/learn-go/internal/session"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// PostAdminImpersonate начинает просмотр сайта от имени пользователя
+// Действует ограниченное время (IMPERSONATION_TTL) и только в текущей сессии администратора
+func (h *Handler) PostAdminImpersonate(w http.ResponseWriter, r *http.Request) {
+	admin, _ := user.FromCtx(r.Context())
+	sess, ok := session.FromCtx(r.Context())
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	target, ok := h.adminTarget(w, r)
+	if !ok {
+		return
+	}
+
+	imp, err := h.impersonation.Start(r.Context(), admin, target, sess.ID)
+	switch {
+	case errors.Is(err, impersonation.ErrSelf):
+		h.renderAdminUserCard(w, r, target, "Нельзя войти от имени самого себя")
+		return
+	case errors.Is(err, impersonation.ErrTargetAdmin):
+		h.renderAdminUserCard(w, r, target, "Нельзя войти от имени другого администратора")
+		return
+	case err != nil:
+		slog.Error("Failed to start impersonation", "error", err, "admin_id", admin.ID, "user_id", target.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	h.audit.Record(r, audit.EventTypeImpersonationStarted, &target.ID, h.adminDetails(r, map[string]string{
+		"expires_at": imp.ExpiresAt.Format(time.RFC3339),
+	}))
+	slog.Info("Impersonation started", "admin_id", admin.ID, "user_id", target.ID, "expires_at", imp.ExpiresAt)
+
+	// Администратор видит сайт глазами пользователя - начинаем с главной
+	w.Header().Set("HX-Redirect", "/")
+	w.WriteHeader(http.StatusOK)
+}
+
+// PostStopImpersonation завершает просмотр от имени пользователя (кнопка в плашке)
+// Администратор возвращается в карточку пользователя в админке
+func (h *Handler) PostStopImpersonation(w http.ResponseWriter, r *http.Request) {
+	act, ok := impersonation.FromCtx(r.Context())
+	sess, hasSession := session.FromCtx(r.Context())
+	if !ok || !hasSession {
+		// Уже истекло (middleware завершил) или не начиналось
+		redirectHome(w, r)
+		return
+	}
+
+	_, err := h.impersonation.Stop(r.Context(), sess.ID)
+	if err != nil && !errors.Is(err, impersonation.ErrNotActive) {
+		slog.Error("Failed to stop impersonation", "error", err, "admin_id", act.ActorID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if err == nil {
+		h.audit.Record(r, audit.EventTypeImpersonationStopped, &act.TargetID, map[string]string{
+			"admin_id": strconv.FormatInt(act.ActorID, 10),
+			"reason":   "stopped",
+		})
+		slog.Info("Impersonation stopped", "admin_id", act.ActorID, "user_id", act.TargetID)
+	}
+
+	location := "/admin/users/" + strconv.FormatInt(act.TargetID, 10)
+	if middleware.IsHTMX(r) {
+		w.Header().Set("HX-Redirect", location)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Redirect(w, r, location, http.StatusSeeOther)
+}
+
+// ImpersonationDenied отвечает на изменение настроек безопасности во время просмотра от имени пользователя
+// HTMX получает плашку в #flash (как при ошибке CSRF), обычный запрос - 403 текстом
+func (h *Handler) ImpersonationDenied(w http.ResponseWriter, r *http.Request) {
+	if !middleware.IsHTMX(r) {
+		http.Error(w, "Forbidden - not allowed while impersonating", http.StatusForbidden)
+		return
+	}
+
+	w.Header().Set("HX-Retarget", "#flash")
+	w.Header().Set("HX-Reswap", "innerHTML")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+
+	if err := h.templates.RenderComponent(w, "impersonation-denied.html", nil); err != nil {
+		slog.Error("Failed to render impersonation denied message", "error", err)
+	}
+}
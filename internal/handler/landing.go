@@ -17,7 +17,7 @@ func (h *Handler) HandleLanding(w http.ResponseWriter, r *http.Request) {
 		User: u, // nil if not authenticated
 	}
 
-	if err := h.templates.RenderLanding(w, data); err != nil {
+	if err := h.templates.RenderLanding(w, r, data); err != nil {
 		slog.Error("Failed to render landing page", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
@@ -1,28 +1,47 @@
 package handler
 
 import (
+	"errors"
 	"log/slog"
+	"math"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/clientip"
+	"github.com/udisondev/learn-go/internal/email"
+	"github.com/udisondev/learn-go/internal/loginguard"
+	"github.com/udisondev/learn-go/internal/middleware"
 	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/twofactor"
 	"github.com/udisondev/learn-go/internal/user"
-	"golang.org/x/crypto/bcrypt"
 )
 
 // GetLogin отображает страницу входа
 func (h *Handler) GetLogin(w http.ResponseWriter, r *http.Request) {
-	// Если пользователь уже авторизован - редирект на главную
+	// Куда вернуть пользователя после входа (подставляет middleware.RequireAuth)
+	returnTo := middleware.SafeReturnTo(r.URL.Query().Get("return_to"))
+
+	// Если пользователь уже авторизован - сразу туда
 	if _, ok := user.FromCtx(r.Context()); ok {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+		http.Redirect(w, r, returnTo, http.StatusSeeOther)
 		return
 	}
 
-	data := templates.LoginData{
-		Errors: make(map[string]string),
-	}
+	h.renderLoginPage(w, r, templates.LoginData{
+		Errors:   make(map[string]string),
+		ReturnTo: returnTo,
+	})
+}
 
-	if err := h.templates.Render(w, "login.html", data); err != nil {
+// renderLoginPage отображает страницу входа целиком
+// Используется также после OAuth callback - ошибки провайдера и ввод кода 2FA
+func (h *Handler) renderLoginPage(w http.ResponseWriter, r *http.Request, data templates.LoginData) {
+	data.Providers = h.oauth.Providers()
+
+	if err := h.templates.Render(w, r, "login.html", data); err != nil {
 		slog.Error("Failed to render login page", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
@@ -39,111 +58,359 @@ func (h *Handler) PostLogin(w http.ResponseWriter, r *http.Request) {
 
 	email := strings.TrimSpace(r.FormValue("email"))
 	password := r.FormValue("password")
+	returnTo := middleware.SafeReturnTo(r.FormValue("return_to"))
 
 	// Валидация
-	errors := make(map[string]string)
+	formErrors := make(map[string]string)
 
 	if email == "" {
-		errors["email"] = "Email обязателен для заполнения"
+		formErrors["email"] = "Email обязателен для заполнения"
 	}
 
 	if password == "" {
-		errors["password"] = "Пароль обязателен для заполнения"
+		formErrors["password"] = "Пароль обязателен для заполнения"
 	}
 
 	// Если есть ошибки валидации - отправляем форму обратно
-	if len(errors) > 0 {
-		data := templates.LoginData{
-			Email:  email,
-			Errors: errors,
-		}
+	if len(formErrors) > 0 {
+		h.renderLoginForm(w, templates.LoginData{
+			Email:    email,
+			Errors:   formErrors,
+			ReturnTo: returnTo,
+		})
+		return
+	}
 
-		w.WriteHeader(http.StatusOK)
-		if err := h.templates.RenderComponent(w, "login-form.html", data); err != nil {
-			slog.Error("Failed to render login form", "error", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	// Защита от перебора: задержки и блокировка по аккаунту и по IP
+	// Проверяется до поиска пользователя - ответ одинаков для любых email
+	ip := clientip.FromRequest(r)
+	decision, err := h.loginGuard.Check(r.Context(), email, ip)
+	if err != nil {
+		slog.Error("Failed to check login attempts", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if !decision.Allowed {
+		slog.Warn("Login attempt throttled", "email", email, "ip", ip, "retry_after", decision.RetryAfter)
+		h.audit.Record(r, audit.EventTypeLoginFailed, nil, map[string]string{"email": email, "reason": "throttled"})
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(decision.RetryAfter.Seconds()))))
+		formErrors["form"] = "Слишком много попыток входа. Повторите через " + formatRetryAfter(decision.RetryAfter) + "."
+		h.renderLoginForm(w, templates.LoginData{
+			Email:    email,
+			Errors:   formErrors,
+			ReturnTo: returnTo,
+		})
+		return
+	}
+
+	// Проверяем email и пароль
+	// Для неизвестного email ответ тот же и приходит за то же время -
+	// нельзя выяснить, есть ли такой аккаунт
+	foundUser, err := h.userService.Authenticate(r.Context(), email, password)
+	if errors.Is(err, user.ErrInvalidCredentials) {
+		slog.Warn("Invalid password attempt", "email", email, "ip", ip)
+		h.recordLoginFailure(r, email, ip, foundUser)
+
+		var userID *int64
+		if foundUser != nil {
+			userID = &foundUser.ID
 		}
+		h.audit.Record(r, audit.EventTypeLoginFailed, userID, map[string]string{"email": email, "reason": "invalid_password"})
+
+		formErrors["password"] = "Неверный email или пароль"
+		h.renderLoginForm(w, templates.LoginData{
+			Email:    email,
+			Errors:   formErrors,
+			ReturnTo: returnTo,
+		})
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to authenticate user", "error", err, "email", email)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Аккаунт заблокирован администратором
+	// Проверяется после пароля - иначе по ответу можно узнать о блокировке чужого аккаунта
+	if foundUser.IsSuspended() {
+		slog.Warn("Login attempt to suspended account", "user_id", foundUser.ID)
+		formErrors["form"] = suspendedMessage
+		h.renderLoginForm(w, templates.LoginData{
+			Email:    email,
+			Errors:   formErrors,
+			ReturnTo: returnTo,
+		})
 		return
 	}
 
-	// Ищем пользователя по email
-	foundUser, err := h.userService.GetUserByEmail(r.Context(), email)
+	// Проверяем что email верифицирован
+	if !foundUser.IsVerified {
+		formErrors["email"] = "Email не подтвержден. Проверьте почту."
+		h.renderLoginForm(w, templates.LoginData{
+			Email:      email,
+			Errors:     formErrors,
+			Unverified: true,
+			ReturnTo:   returnTo,
+		})
+		return
+	}
+
+	// Включена двухфакторная аутентификация - сессию создаем только после кода
+	twoFactorEnabled, err := h.twoFactor.IsEnabled(r.Context(), foundUser.ID)
 	if err != nil {
-		slog.Error("Failed to find user", "error", err, "email", email)
-		errors["email"] = "Неверный email или пароль"
+		slog.Error("Failed to check two-factor status", "error", err, "user_id", foundUser.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if twoFactorEnabled {
+		challenge, err := h.twoFactor.StartChallenge(r.Context(), foundUser.ID)
+		if err != nil {
+			slog.Error("Failed to start two-factor challenge", "error", err, "user_id", foundUser.ID)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
 
-		data := templates.LoginData{
-			Email:  email,
-			Errors: errors,
+		data := templates.TwoFactorLoginData{
+			Token:    challenge,
+			Errors:   make(map[string]string),
+			ReturnTo: returnTo,
 		}
 
-		w.WriteHeader(http.StatusOK)
-		if err := h.templates.RenderComponent(w, "login-form.html", data); err != nil {
-			slog.Error("Failed to render login form", "error", err)
+		if err := h.templates.RenderComponent(w, "two-factor-form.html", data); err != nil {
+			slog.Error("Failed to render two-factor form", "error", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
 		return
 	}
 
-	// Проверяем пароль
-	if err := bcrypt.CompareHashAndPassword([]byte(foundUser.PasswordHash), []byte(password)); err != nil {
-		slog.Warn("Invalid password attempt", "email", email)
-		errors["password"] = "Неверный email или пароль"
+	// Вход завершен - счетчик неудачных попыток аккаунта сбрасывается
+	// С 2FA - только после верного кода: иначе знающий пароль сбрасывал бы
+	// блокировку перед каждой серией подбора кода
+	if err := h.loginGuard.RecordSuccess(r.Context(), email); err != nil {
+		slog.Error("Failed to reset login attempts", "error", err, "user_id", foundUser.ID)
+	}
 
-		data := templates.LoginData{
-			Email:  email,
-			Errors: errors,
+	if err := h.startSession(w, r, foundUser.ID, "password"); err != nil {
+		if errors.Is(err, user.ErrAccountSuspended) {
+			h.renderSuspended(w, returnTo)
+			return
 		}
+		slog.Error("Failed to create session", "error", err, "user_id", foundUser.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("User logged in successfully", "user_id", foundUser.ID, "email", foundUser.Email)
 
-		w.WriteHeader(http.StatusOK)
-		if err := h.templates.RenderComponent(w, "login-form.html", data); err != nil {
-			slog.Error("Failed to render login form", "error", err)
+	// Редирект на страницу, с которой отправили на вход (или на главную)
+	w.Header().Set("HX-Redirect", returnTo)
+	w.WriteHeader(http.StatusOK)
+}
+
+// PostLoginTwoFactor проверяет код второго фактора и завершает вход
+// Второй шаг логина: пароль уже проверен в PostLogin, здесь - TOTP или recovery code
+func (h *Handler) PostLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		slog.Error("Failed to parse two-factor form", "error", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	data := templates.TwoFactorLoginData{
+		Token:    r.FormValue("token"),
+		Errors:   make(map[string]string),
+		ReturnTo: middleware.SafeReturnTo(r.FormValue("return_to")),
+	}
+	code := strings.TrimSpace(r.FormValue("code"))
+
+	if code == "" {
+		data.Errors["code"] = "Введите код из приложения или код восстановления"
+		h.renderTwoFactorForm(w, data)
+		return
+	}
+
+	userID, err := h.twoFactor.CompleteChallenge(r.Context(), data.Token, code)
+	if err != nil {
+		switch {
+		case errors.Is(err, twofactor.ErrInvalidCode):
+			slog.Warn("Invalid two-factor code attempt")
+			h.audit.Record(r, audit.EventTypeLoginFailed, &userID, map[string]string{"reason": "invalid_two_factor_code"})
+			// Неверный код считается неудачной попыткой входа в аккаунт, как неверный пароль
+			h.recordTwoFactorFailure(r, userID)
+			data.Errors["code"] = "Неверный код"
+			h.renderTwoFactorForm(w, data)
+		case errors.Is(err, twofactor.ErrChallengeNotFound):
+			// Время на ввод кода вышло или исчерпаны попытки - начинаем вход заново
+			h.renderLoginForm(w, templates.LoginData{
+				Errors: map[string]string{
+					"form": "Время на ввод кода истекло. Войдите снова.",
+				},
+				ReturnTo: data.ReturnTo,
+			})
+		default:
+			slog.Error("Failed to complete two-factor challenge", "error", err)
 			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		}
 		return
 	}
 
-	// Проверяем что email верифицирован
-	if !foundUser.IsVerified {
-		errors["email"] = "Email не подтвержден. Проверьте почту."
+	// Второй фактор пройден - теперь можно сбросить счетчик неудачных попыток
+	u, err := h.userService.GetUserByID(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to get user after two-factor", "error", err, "user_id", userID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	if err := h.loginGuard.RecordSuccess(r.Context(), u.Email); err != nil {
+		slog.Error("Failed to reset login attempts", "error", err, "user_id", userID)
+	}
 
-		data := templates.LoginData{
-			Email:  email,
-			Errors: errors,
+	if err := h.startSession(w, r, userID, "two_factor"); err != nil {
+		if errors.Is(err, user.ErrAccountSuspended) {
+			h.renderSuspended(w, data.ReturnTo)
+			return
 		}
+		slog.Error("Failed to create session", "error", err, "user_id", userID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
 
-		w.WriteHeader(http.StatusOK)
-		if err := h.templates.RenderComponent(w, "login-form.html", data); err != nil {
-			slog.Error("Failed to render login form", "error", err)
-			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		}
+	slog.Info("User logged in with two-factor", "user_id", userID)
+
+	w.Header().Set("HX-Redirect", data.ReturnTo)
+	w.WriteHeader(http.StatusOK)
+}
+
+// GetLoginUnlock снимает блокировку входа по ссылке из письма
+// Одно нажатие: после перехода сразу показываем форму входа
+func (h *Handler) GetLoginUnlock(w http.ResponseWriter, r *http.Request) {
+	data := templates.LoginData{
+		Errors: make(map[string]string),
+	}
+
+	err := h.loginGuard.Unlock(r.Context(), r.URL.Query().Get("token"))
+	switch {
+	case errors.Is(err, loginguard.ErrInvalidToken):
+		data.Errors["form"] = "Ссылка недействительна: блокировка уже снята или истекла. Попробуйте войти."
+	case err != nil:
+		slog.Error("Failed to unlock login", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
+	default:
+		slog.Info("Login unlocked by email link")
+		data.Notice = "Вход разблокирован. Теперь вы можете войти."
 	}
 
-	// Создаем сессию
-	realIP := getRealIP(r)
-	userAgent := r.UserAgent()
+	h.renderLoginPage(w, r, data)
+}
 
-	sessionToken, err := h.sessionService.CreateSession(r.Context(), foundUser.ID, realIP, userAgent)
+// recordLoginFailure учитывает неудачную попытку входа
+// Если аккаунт только что заблокирован - отправляет владельцу письмо со ссылкой разблокировки
+//
+// Письмо уходит только существующему пользователю, но ответ формы от этого не меняется
+func (h *Handler) recordLoginFailure(r *http.Request, address, ip string, u *user.User) {
+	lockout, err := h.loginGuard.RecordFailure(r.Context(), address, ip)
 	if err != nil {
-		slog.Error("Failed to create session", "error", err, "user_id", foundUser.ID)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		slog.Error("Failed to record login failure", "error", err)
 		return
 	}
+	if lockout == nil {
+		return
+	}
+
+	slog.Warn("Account locked after failed login attempts", "email", lockout.Email, "locked_until", lockout.LockedUntil)
+
+	if u == nil {
+		return
+	}
+
+	payload := map[string]string{
+		"token":           lockout.Token,
+		"user_name":       u.Name,
+		"lockout_minutes": strconv.Itoa(int(math.Ceil(time.Until(lockout.LockedUntil).Minutes()))),
+	}
 
-	// Устанавливаем cookie
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    sessionToken.String(),
-		Path:     "/",
-		HttpOnly: true,
-		Secure:   h.cfg.Session.Secure,
-		SameSite: http.SameSiteLaxMode,
+	if err := h.emailQueue.Enqueue(r.Context(), email.EmailTypeAccountUnlock, u.Email, &u.ID, payload); err != nil {
+		slog.Error("Failed to enqueue account unlock email", "error", err, "user_id", u.ID)
+	}
+}
+
+// recordTwoFactorFailure учитывает неверный код второго фактора в loginguard
+// WHY: Попытки на один challenge ограничены, но новый challenge выдается
+// после каждого верного пароля - без общего счетчика подбор кода ограничен только лимитом по IP
+func (h *Handler) recordTwoFactorFailure(r *http.Request, userID int64) {
+	u, err := h.userService.GetUserByID(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to get user for two-factor failure", "error", err, "user_id", userID)
+		return
+	}
+	h.recordLoginFailure(r, u.Email, clientip.FromRequest(r), u)
+}
+
+// suspendedMessage - ответ на вход в заблокированный аккаунт, одинаковый для всех способов входа
+const suspendedMessage = "Аккаунт заблокирован. Напишите в поддержку."
+
+// renderSuspended отвечает на вход в заблокированный аккаунт формой входа с ошибкой
+func (h *Handler) renderSuspended(w http.ResponseWriter, returnTo string) {
+	h.renderLoginForm(w, templates.LoginData{
+		Errors:   map[string]string{"form": suspendedMessage},
+		ReturnTo: returnTo,
 	})
+}
 
-	slog.Info("User logged in successfully", "user_id", foundUser.ID, "email", foundUser.Email)
+// renderLoginForm отдает форму входа для HTMX
+func (h *Handler) renderLoginForm(w http.ResponseWriter, data templates.LoginData) {
+	if err := h.templates.RenderComponent(w, "login-form.html", data); err != nil {
+		slog.Error("Failed to render login form", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
 
-	// Редирект на главную
-	w.Header().Set("HX-Redirect", "/")
-	w.WriteHeader(http.StatusOK)
+// formatRetryAfter форматирует время ожидания для сообщения об ошибке
+func formatRetryAfter(d time.Duration) string {
+	if d < time.Minute {
+		return strconv.Itoa(int(math.Ceil(d.Seconds()))) + " сек."
+	}
+	return strconv.Itoa(int(math.Ceil(d.Minutes()))) + " мин."
+}
+
+// renderTwoFactorForm отдает форму ввода кода второго фактора с ошибками
+func (h *Handler) renderTwoFactorForm(w http.ResponseWriter, data templates.TwoFactorLoginData) {
+	if err := h.templates.RenderComponent(w, "two-factor-form.html", data); err != nil {
+		slog.Error("Failed to render two-factor form", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// startSession создает сессию, устанавливает cookie и записывает вход в журнал аудита
+// Используется всеми способами входа (пароль, 2FA, верификация email)
+// method попадает в журнал: password, two_factor, magic_link, oauth:github...
+//
+// Заблокированному аккаунту сессия не создается - user.ErrAccountSuspended.
+// Способы входа проверяют блокировку и сами, здесь - последний рубеж:
+// иначе сессия создалась бы, а GetUserBySessionID ее отфильтровал бы,
+// и пользователь видел бы бесконечный редирект на вход
+func (h *Handler) startSession(w http.ResponseWriter, r *http.Request, userID int64, method string) error {
+	u, err := h.userService.GetUserByID(r.Context(), userID)
+	if err != nil {
+		return err
+	}
+	if u.IsSuspended() {
+		slog.Warn("Login attempt to suspended account", "user_id", userID, "method", method)
+		return user.ErrAccountSuspended
+	}
+
+	sess, err := h.sessionService.CreateSession(r.Context(), userID, clientip.FromRequest(r), r.UserAgent())
+	if err != nil {
+		return err
+	}
+
+	h.audit.Record(r, audit.EventTypeLoginSucceeded, &userID, map[string]string{"method": method})
+
+	// Срок жизни cookie - по политике сессий
+	http.SetCookie(w, h.sessionService.Cookie(sess))
+	return nil
 }
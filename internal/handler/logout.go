@@ -3,8 +3,14 @@ package handler
 import (
 	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/google/uuid"
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/impersonation"
+	"github.com/udisondev/learn-go/internal/middleware"
+	"github.com/udisondev/learn-go/internal/session"
+	"github.com/udisondev/learn-go/internal/user"
 )
 
 // HandleLogout handles user logout
@@ -12,10 +18,10 @@ import (
 // HOW: Get session ID from cookie, delete from DB, clear cookie, redirect
 func (h *Handler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	// Get session cookie
-	cookie, err := r.Cookie("session_id")
+	cookie, err := r.Cookie(session.CookieName)
 	if err != nil {
 		// No session cookie - just redirect to home
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+		redirectHome(w, r)
 		return
 	}
 
@@ -23,11 +29,21 @@ func (h *Handler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	sessionID, err := uuid.Parse(cookie.Value)
 	if err != nil {
 		// Invalid session ID - clear cookie and redirect
-		clearSessionCookie(w)
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+		session.ClearCookie(w)
+		redirectHome(w, r)
 		return
 	}
 
+	// Admin logs out while impersonating - impersonation ends with the session
+	if act, ok := impersonation.FromCtx(r.Context()); ok {
+		if _, err := h.impersonation.Stop(r.Context(), sessionID); err == nil {
+			h.audit.Record(r, audit.EventTypeImpersonationStopped, &act.TargetID, map[string]string{
+				"admin_id": strconv.FormatInt(act.ActorID, 10),
+				"reason":   "logout",
+			})
+		}
+	}
+
 	// Delete session from database
 	if err := h.sessionService.DeleteSession(r.Context(), sessionID); err != nil {
 		slog.Error("Failed to delete session", "error", err, "session_id", sessionID)
@@ -35,21 +51,30 @@ func (h *Handler) HandleLogout(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Clear session cookie
-	clearSessionCookie(w)
+	session.ClearCookie(w)
+
+	// Logout belongs to the session owner, not to the impersonated user
+	if act, ok := impersonation.FromCtx(r.Context()); ok {
+		h.audit.Record(r, audit.EventTypeLogout, &act.ActorID, nil)
+	} else if u, ok := user.FromCtx(r.Context()); ok {
+		h.audit.Record(r, audit.EventTypeLogout, &u.ID, nil)
+	}
 
 	slog.Info("User logged out", "session_id", sessionID)
 
 	// Redirect to home page
-	http.Redirect(w, r, "/", http.StatusSeeOther)
+	redirectHome(w, r)
 }
 
-// clearSessionCookie removes session cookie
-func clearSessionCookie(w http.ResponseWriter) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-	})
+// redirectHome отправляет на главную после выхода
+// Кнопка выхода отправляет hx-post (нужен CSRF-заголовок из hx-headers),
+// HTMX не переходит по 303 сам - ему нужен HX-Redirect
+func redirectHome(w http.ResponseWriter, r *http.Request) {
+	if middleware.IsHTMX(r) {
+		w.Header().Set("HX-Redirect", "/")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -0,0 +1,204 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/udisondev/learn-go/internal/email"
+	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// GetMagicLink отображает форму запроса ссылки для входа без пароля
+func (h *Handler) GetMagicLink(w http.ResponseWriter, r *http.Request) {
+	if _, ok := user.FromCtx(r.Context()); ok {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	h.renderMagicLinkPage(w, r, templates.MagicLinkData{
+		Errors: make(map[string]string),
+	})
+}
+
+// PostMagicLink создает ссылку для входа и ставит письмо в очередь
+//
+// Ответ одинаковый для существующих и несуществующих email (как в PostForgotPassword),
+// cooldown на адрес применяется до поиска пользователя
+func (h *Handler) PostMagicLink(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		slog.Error("Failed to parse magic link form", "error", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	address := strings.TrimSpace(r.FormValue("email"))
+
+	data := templates.MagicLinkData{
+		Email:  address,
+		Errors: make(map[string]string),
+	}
+
+	if address != "" {
+		if msg, ok := h.allowEmail(w, r, "magic-link:"+strings.ToLower(address)); !ok {
+			data.Errors["email"] = msg
+			h.renderMagicLinkForm(w, data)
+			return
+		}
+	}
+
+	req, err := h.userService.RequestMagicLink(r.Context(), address)
+	if err != nil {
+		var validationErrs user.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			slog.Error("Failed to request magic link", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		for _, ve := range validationErrs {
+			data.Errors[ve.Field] = ve.Message
+		}
+		h.renderMagicLinkForm(w, data)
+		return
+	}
+
+	// req == nil - такого email нет, письмо не отправляем, но ответ тот же
+	if req != nil {
+		payload := map[string]any{
+			"token":       req.Token,
+			"user_name":   req.UserName,
+			"ttl_minutes": int(user.MagicLinkTTL.Minutes()),
+		}
+
+		if err := h.emailQueue.Enqueue(r.Context(), email.EmailTypeMagicLink, req.Email, &req.UserID, payload); err != nil {
+			slog.Error("Failed to enqueue magic link email", "error", err, "user_id", req.UserID)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		slog.Info("Magic link requested", "user_id", req.UserID)
+	}
+
+	data.Sent = true
+	h.renderMagicLinkForm(w, data)
+}
+
+// GetMagicLinkConfirm показывает промежуточную страницу с кнопкой "Войти"
+//
+// Почему не входим сразу по GET:
+// - Почтовые сканеры и превью ссылок (Outlook Safe Links, мессенджеры)
+// открывают ссылки из писем заранее и "сжигают" одноразовый токен
+// - Вход происходит только по POST с этой страницы, а его сканеры не делают
+//
+// Токен только проверяется, не используется
+func (h *Handler) GetMagicLinkConfirm(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	data := templates.MagicLinkData{
+		Token:   token,
+		Confirm: true,
+		Errors:  make(map[string]string),
+	}
+
+	if err := h.userService.CheckMagicLinkToken(r.Context(), token); err != nil {
+		if !errors.Is(err, user.ErrInvalidToken) {
+			slog.Error("Failed to check magic link", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		data.InvalidToken = true
+	}
+
+	h.renderMagicLinkPage(w, r, data)
+}
+
+// PostMagicLinkConfirm использует ссылку и завершает вход
+// Ссылка заменяет пароль, но не второй фактор - при включенной 2FA
+// показываем форму кода (как после входа через GitHub)
+func (h *Handler) PostMagicLinkConfirm(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		slog.Error("Failed to parse magic link confirm form", "error", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	userID, err := h.userService.ConsumeMagicLink(r.Context(), r.FormValue("token"))
+	if errors.Is(err, user.ErrInvalidToken) {
+		h.renderMagicLinkForm(w, templates.MagicLinkData{
+			Confirm:      true,
+			InvalidToken: true,
+			Errors:       make(map[string]string),
+		})
+		return
+	}
+	if errors.Is(err, user.ErrAccountSuspended) {
+		slog.Warn("Magic link login to suspended account")
+		h.renderSuspended(w, "/")
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to consume magic link", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Ссылка могла подтвердить email - в кэше сессий пользователь еще не подтвержден
+	h.sessionService.InvalidateUser(userID)
+
+	twoFactorEnabled, err := h.twoFactor.IsEnabled(r.Context(), userID)
+	if err != nil {
+		slog.Error("Failed to check two-factor status", "error", err, "user_id", userID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if twoFactorEnabled {
+		challenge, err := h.twoFactor.StartChallenge(r.Context(), userID)
+		if err != nil {
+			slog.Error("Failed to start two-factor challenge", "error", err, "user_id", userID)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		h.renderTwoFactorForm(w, templates.TwoFactorLoginData{
+			Token:    challenge,
+			Errors:   make(map[string]string),
+			ReturnTo: "/",
+		})
+		return
+	}
+
+	if err := h.startSession(w, r, userID, "magic_link"); err != nil {
+		if errors.Is(err, user.ErrAccountSuspended) {
+			h.renderSuspended(w, "/")
+			return
+		}
+		slog.Error("Failed to create session", "error", err, "user_id", userID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("User logged in with magic link", "user_id", userID)
+
+	w.Header().Set("HX-Redirect", "/")
+	w.WriteHeader(http.StatusOK)
+}
+
+// renderMagicLinkPage отображает страницу входа по ссылке
+func (h *Handler) renderMagicLinkPage(w http.ResponseWriter, r *http.Request, data templates.MagicLinkData) {
+	if err := h.templates.Render(w, r, "magic-link.html", data); err != nil {
+		slog.Error("Failed to render magic link page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// renderMagicLinkForm отдает форму входа по ссылке для HTMX
+func (h *Handler) renderMagicLinkForm(w http.ResponseWriter, data templates.MagicLinkData) {
+	if err := h.templates.RenderComponent(w, "magic-link-form.html", data); err != nil {
+		slog.Error("Failed to render magic link form", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
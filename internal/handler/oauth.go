@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/udisondev/learn-go/internal/oauth"
+	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// GetOAuthStart перенаправляет на страницу согласия провайдера
+// Обычная ссылка (не HTMX) - браузер должен уйти на домен провайдера
+func (h *Handler) GetOAuthStart(w http.ResponseWriter, r *http.Request) {
+	if _, ok := user.FromCtx(r.Context()); ok {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	authURL, err := h.oauth.Begin(w, chi.URLParam(r, "provider"))
+	if errors.Is(err, oauth.ErrUnknownProvider) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to start oauth flow", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, authURL, http.StatusSeeOther)
+}
+
+// GetOAuthCallback обрабатывает возврат пользователя от провайдера
+//
+// Процесс:
+// 1. Проверка state и обмен кода на профиль (oauth.Service)
+// 2. Поиск/привязка/регистрация аккаунта (user.Service.SignInWithIdentity)
+// 3. Если включена 2FA - страница входа с формой кода, иначе сессия и редирект
+//
+// Ошибки показываются на странице входа, а не отдельной страницей -
+// пользователь сразу может войти по паролю или попробовать снова
+func (h *Handler) GetOAuthCallback(w http.ResponseWriter, r *http.Request) {
+	providerName := chi.URLParam(r, "provider")
+
+	identity, err := h.oauth.Complete(r.Context(), w, r, providerName)
+	if err != nil {
+		switch {
+		case errors.Is(err, oauth.ErrUnknownProvider):
+			http.NotFound(w, r)
+		case errors.Is(err, oauth.ErrAccessDenied):
+			h.renderOAuthError(w, r, "Вход отменён. Вы можете войти по email и паролю.")
+		case errors.Is(err, oauth.ErrStateMismatch):
+			slog.Warn("OAuth state mismatch", "provider", providerName)
+			h.renderOAuthError(w, r, "Время на вход истекло. Попробуйте снова.")
+		default:
+			slog.Error("Failed to complete oauth flow", "error", err, "provider", providerName)
+			h.renderOAuthError(w, r, "Не удалось войти через внешний сервис. Попробуйте позже.")
+		}
+		return
+	}
+
+	u, err := h.userService.SignInWithIdentity(r.Context(), *identity)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrIdentityEmailUnverified):
+			h.renderOAuthError(w, r, "Основной email в аккаунте провайдера не подтверждён. Подтвердите его и попробуйте снова.")
+		case errors.Is(err, user.ErrAccountSuspended):
+			slog.Warn("OAuth login to suspended account", "provider", providerName)
+			h.renderOAuthError(w, r, suspendedMessage)
+		case errors.Is(err, user.ErrIdentityLinkUnverified):
+			h.renderOAuthError(w, r, "Аккаунт с этим email уже существует, но email не подтверждён. Войдите по паролю, подтвердите email и повторите вход.")
+		default:
+			slog.Error("Failed to sign in with identity", "error", err, "provider", providerName)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Внешний вход заменяет пароль, но не второй фактор
+	twoFactorEnabled, err := h.twoFactor.IsEnabled(r.Context(), u.ID)
+	if err != nil {
+		slog.Error("Failed to check two-factor status", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if twoFactorEnabled {
+		challenge, err := h.twoFactor.StartChallenge(r.Context(), u.ID)
+		if err != nil {
+			slog.Error("Failed to start two-factor challenge", "error", err, "user_id", u.ID)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		h.renderLoginPage(w, r, templates.LoginData{
+			Errors: make(map[string]string),
+			TwoFactor: &templates.TwoFactorLoginData{
+				Token:    challenge,
+				Errors:   make(map[string]string),
+				ReturnTo: "/",
+			},
+		})
+		return
+	}
+
+	if err := h.startSession(w, r, u.ID, "oauth:"+providerName); err != nil {
+		if errors.Is(err, user.ErrAccountSuspended) {
+			h.renderOAuthError(w, r, suspendedMessage)
+			return
+		}
+		slog.Error("Failed to create session", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("User logged in with oauth", "user_id", u.ID, "provider", providerName)
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// renderOAuthError показывает страницу входа с общей ошибкой над формой
+func (h *Handler) renderOAuthError(w http.ResponseWriter, r *http.Request, message string) {
+	h.renderLoginPage(w, r, templates.LoginData{
+		Errors: map[string]string{"form": message},
+	})
+}
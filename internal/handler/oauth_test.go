@@ -0,0 +1,371 @@
+package handler
+
+import (
+	"crypto/rand"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/caarlos0/env/v11"
+	"github.com/go-chi/chi/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/udisondev/learn-go/internal/apitoken"
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/email"
+	"github.com/udisondev/learn-go/internal/impersonation"
+	"github.com/udisondev/learn-go/internal/loginguard"
+	"github.com/udisondev/learn-go/internal/oauth"
+	"github.com/udisondev/learn-go/internal/oauth/oauthtest"
+	"github.com/udisondev/learn-go/internal/password"
+	"github.com/udisondev/learn-go/internal/ratelimit"
+	"github.com/udisondev/learn-go/internal/session"
+	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/twofactor"
+	"github.com/udisondev/learn-go/internal/user"
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// testEmailDomain - users created by these tests, deleted before and after each
+const testEmailDomain = "@oauth-test.example"
+
+// testConfig returns configuration defaults, ignoring the environment
+func testConfig(t *testing.T) *config.Config {
+	t.Helper()
+
+	cfg := &config.Config{}
+	if err := env.ParseWithOptions(cfg, env.Options{Environment: map[string]string{}}); err != nil {
+		t.Fatalf("default config: %v", err)
+	}
+	cfg.App.Env = "test"
+	return cfg
+}
+
+// loadTemplates parses page templates, they are looked up relative to the repository root
+func loadTemplates(t *testing.T) *templates.Templates {
+	t.Helper()
+
+	t.Chdir("../..")
+	tmpl, err := templates.Init()
+	if err != nil {
+		t.Fatalf("init templates: %v", err)
+	}
+	return tmpl
+}
+
+// testDB connects to TEST_DATABASE_URL - a migrated database (make db-migrate-up)
+func testDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := pgxpool.New(t.Context(), url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(db.Close)
+	return db
+}
+
+// newOAuthHandler wires the handler with GitHub pointed at gh
+// Without db only the flow before account lookup works (state checks, errors from the provider)
+func newOAuthHandler(t *testing.T, gh *oauthtest.GitHub, db *pgxpool.Pool) *Handler {
+	t.Helper()
+
+	cfg := testConfig(t)
+	cfg.OAuth.GitHub = gh.Config()
+	h := &Handler{
+		templates: loadTemplates(t),
+		oauth:     oauth.NewService(cfg.OAuth, "http://learn-go.test", false),
+		cfg:       cfg,
+	}
+	if db == nil {
+		return h
+	}
+
+	passwords, err := password.NewHasher(cfg.Password)
+	if err != nil {
+		t.Fatalf("password hasher: %v", err)
+	}
+	policy, err := password.NewPolicy(cfg.Password)
+	if err != nil {
+		t.Fatalf("password policy: %v", err)
+	}
+	users := user.NewService(db, passwords, policy)
+
+	store, err := session.NewStore(cfg.Session, db, users)
+	if err != nil {
+		t.Fatalf("session store: %v", err)
+	}
+	locator, err := session.NewGeoLocator("")
+	if err != nil {
+		t.Fatalf("geo locator: %v", err)
+	}
+	twoFactor, err := twofactor.NewService(db, cfg.TwoFactor)
+	if err != nil {
+		t.Fatalf("two-factor service: %v", err)
+	}
+	rateLimitStore, err := ratelimit.NewStore(cfg.RateLimit.Store, db)
+	if err != nil {
+		t.Fatalf("rate limit store: %v", err)
+	}
+
+	return New(
+		h.templates,
+		users,
+		session.NewService(store, cfg.Session, locator),
+		twoFactor,
+		h.oauth,
+		loginguard.NewService(db, cfg.Login),
+		apitoken.NewService(db, cfg.APIToken),
+		audit.NewService(db, cfg.Audit),
+		impersonation.NewService(db, cfg.Impersonation, users),
+		email.NewQueue(db),
+		ratelimit.New(rateLimitStore, cfg.RateLimit),
+		cfg,
+	)
+}
+
+// oauthRoutes mounts the OAuth handlers the way the router does
+func oauthRoutes(h *Handler) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/auth/{provider}", h.GetOAuthStart)
+	r.Get("/auth/{provider}/callback", h.GetOAuthCallback)
+	return r
+}
+
+// oauthLogin clicks "Sign in with GitHub", consents at the fake and returns the callback response
+// tamper may change the callback request before it is sent
+func oauthLogin(t *testing.T, routes http.Handler, tamper func(*http.Request)) *httptest.ResponseRecorder {
+	t.Helper()
+
+	start := httptest.NewRecorder()
+	routes.ServeHTTP(start, httptest.NewRequest(http.MethodGet, "/auth/github", nil))
+	if start.Code != http.StatusSeeOther {
+		t.Fatalf("start: status %d", start.Code)
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Get(start.Header().Get("Location"))
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d", resp.StatusCode)
+	}
+
+	callback, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatalf("callback url: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, callback.RequestURI(), nil)
+	for _, c := range start.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	if tamper != nil {
+		tamper(req)
+	}
+
+	rec := httptest.NewRecorder()
+	routes.ServeHTTP(rec, req)
+	return rec
+}
+
+// sessionCookie returns the session set by the response, empty if none
+func sessionCookie(rec *httptest.ResponseRecorder) string {
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == session.CookieName && c.MaxAge >= 0 {
+			return c.Value
+		}
+	}
+	return ""
+}
+
+var githubIDs atomic.Int64
+
+// newGitHubUser returns a GitHub account with a unique ID and address in testEmailDomain
+func newGitHubUser(verified bool) oauthtest.User {
+	id := time.Now().UnixNano()/1000 + githubIDs.Add(1)
+	login := "gopher-" + strings.ToLower(rand.Text()[:8])
+	return oauthtest.User{
+		ID:            id,
+		Login:         login,
+		Name:          "Test Gopher",
+		Email:         login + testEmailDomain,
+		EmailVerified: verified,
+	}
+}
+
+// cleanTestUsers deletes users created by these tests, identities and sessions go with them
+func cleanTestUsers(t *testing.T, db *pgxpool.Pool) {
+	t.Helper()
+
+	clean := func() {
+		if _, err := db.Exec(t.Context(), "DELETE FROM users WHERE email LIKE '%' || $1", testEmailDomain); err != nil {
+			t.Fatalf("delete test users: %v", err)
+		}
+	}
+	clean()
+	t.Cleanup(clean)
+}
+
+// insertUser creates a password account
+func insertUser(t *testing.T, db *pgxpool.Pool, address string, verified, suspended bool) int64 {
+	t.Helper()
+
+	var id int64
+	err := db.QueryRow(t.Context(), `
+		INSERT INTO users (name, email, password_hash, registered_at, updated_at, sub_plan, score, is_verified, suspended_at)
+		VALUES ('Existing', $1, '', NOW(), NOW(), 'free', 0, $2, CASE WHEN $3::boolean THEN NOW() END)
+		RETURNING id`, address, verified, suspended).Scan(&id)
+	if err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	return id
+}
+
+// identityOwner returns the user linked to the GitHub account, 0 if not linked
+func identityOwner(t *testing.T, db *pgxpool.Pool, gh oauthtest.User) int64 {
+	t.Helper()
+
+	var userID int64
+	err := db.QueryRow(t.Context(), `
+		SELECT COALESCE(MAX(user_id), 0) FROM user_identities
+		WHERE provider = 'github' AND subject = $1`, fmt.Sprint(gh.ID)).Scan(&userID)
+	if err != nil {
+		t.Fatalf("select identity: %v", err)
+	}
+	return userID
+}
+
+func TestOAuthCallbackStateMismatch(t *testing.T) {
+	gh := oauthtest.NewGitHub(t)
+	gh.SignIn(newGitHubUser(true))
+	routes := oauthRoutes(newOAuthHandler(t, gh, nil))
+
+	rec := oauthLogin(t, routes, func(r *http.Request) {
+		q := r.URL.Query()
+		q.Set("state", "forged")
+		r.URL.RawQuery = q.Encode()
+	})
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want the login page", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "Время на вход истекло") {
+		t.Error("login page has no state mismatch error")
+	}
+	if sessionCookie(rec) != "" {
+		t.Error("session created despite state mismatch")
+	}
+	if n := len(gh.Verifiers()); n != 0 {
+		t.Errorf("code exchanged %d times despite state mismatch", n)
+	}
+}
+
+func TestOAuthSignIn(t *testing.T) {
+	db := testDB(t)
+	cleanTestUsers(t, db)
+
+	gh := oauthtest.NewGitHub(t)
+	h := newOAuthHandler(t, gh, db)
+	routes := oauthRoutes(h)
+
+	t.Run("new user is created", func(t *testing.T) {
+		account := newGitHubUser(true)
+		gh.SignIn(account)
+
+		rec := oauthLogin(t, routes, nil)
+		if rec.Code != http.StatusSeeOther || sessionCookie(rec) == "" {
+			t.Fatalf("status %d, session %q; want redirect with a session", rec.Code, sessionCookie(rec))
+		}
+
+		u, err := h.userService.GetUserByEmail(t.Context(), account.Email)
+		if err != nil {
+			t.Fatalf("registered user: %v", err)
+		}
+		if owner := identityOwner(t, db, account); owner != u.ID {
+			t.Errorf("identity linked to user %d, want %d", owner, u.ID)
+		}
+	})
+
+	t.Run("existing verified email is linked", func(t *testing.T) {
+		account := newGitHubUser(true)
+		gh.SignIn(account)
+		userID := insertUser(t, db, account.Email, true, false)
+
+		rec := oauthLogin(t, routes, nil)
+		if rec.Code != http.StatusSeeOther || sessionCookie(rec) == "" {
+			t.Fatalf("status %d, session %q; want redirect with a session", rec.Code, sessionCookie(rec))
+		}
+		if owner := identityOwner(t, db, account); owner != userID {
+			t.Errorf("identity linked to user %d, want existing user %d", owner, userID)
+		}
+	})
+
+	t.Run("unverified IdP email is not linked", func(t *testing.T) {
+		account := newGitHubUser(false)
+		gh.SignIn(account)
+		insertUser(t, db, account.Email, true, false)
+
+		rec := oauthLogin(t, routes, nil)
+		if sessionCookie(rec) != "" {
+			t.Error("session created for an unverified provider email")
+		}
+		if !strings.Contains(rec.Body.String(), "не подтверждён") {
+			t.Error("login page has no unverified email error")
+		}
+		if owner := identityOwner(t, db, account); owner != 0 {
+			t.Errorf("identity linked to user %d", owner)
+		}
+	})
+
+	t.Run("unverified local account is not linked", func(t *testing.T) {
+		account := newGitHubUser(true)
+		gh.SignIn(account)
+		insertUser(t, db, account.Email, false, false)
+
+		rec := oauthLogin(t, routes, nil)
+		if sessionCookie(rec) != "" {
+			t.Error("session created for an account with unconfirmed email")
+		}
+		if owner := identityOwner(t, db, account); owner != 0 {
+			t.Errorf("identity linked to user %d", owner)
+		}
+	})
+
+	t.Run("suspended user gets no session", func(t *testing.T) {
+		account := newGitHubUser(true)
+		gh.SignIn(account)
+		userID := insertUser(t, db, account.Email, true, false)
+
+		// Linked while active
+		if rec := oauthLogin(t, routes, nil); sessionCookie(rec) == "" {
+			t.Fatalf("first login: status %d, no session", rec.Code)
+		}
+
+		if _, err := db.Exec(t.Context(), "UPDATE users SET suspended_at = NOW() WHERE id = $1", userID); err != nil {
+			t.Fatalf("suspend: %v", err)
+		}
+
+		rec := oauthLogin(t, routes, nil)
+		if sessionCookie(rec) != "" {
+			t.Error("suspended user got a session")
+		}
+		if !strings.Contains(rec.Body.String(), suspendedMessage) {
+			t.Error("login page has no suspension error")
+		}
+	})
+}
@@ -0,0 +1,191 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/email"
+	"github.com/udisondev/learn-go/internal/session"
+	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// GetForgotPassword отображает форму запроса ссылки для сброса пароля
+func (h *Handler) GetForgotPassword(w http.ResponseWriter, r *http.Request) {
+	// Если пользователь уже авторизован - редирект на главную
+	if _, ok := user.FromCtx(r.Context()); ok {
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	data := templates.ForgotPasswordData{
+		Errors: make(map[string]string),
+	}
+
+	if err := h.templates.Render(w, r, "forgot-password.html", data); err != nil {
+		slog.Error("Failed to render forgot password page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// PostForgotPassword создает токен сброса и ставит письмо в очередь
+//
+// Ответ одинаковый для существующих и несуществующих email,
+// чтобы форму нельзя было использовать для перебора аккаунтов
+func (h *Handler) PostForgotPassword(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		slog.Error("Failed to parse forgot password form", "error", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	emailAddr := strings.TrimSpace(r.FormValue("email"))
+
+	data := templates.ForgotPasswordData{
+		Email:  emailAddr,
+		Errors: make(map[string]string),
+	}
+
+	req, err := h.userService.RequestPasswordReset(r.Context(), emailAddr)
+	if err != nil {
+		var validationErrs user.ValidationErrors
+		if !errors.As(err, &validationErrs) {
+			slog.Error("Failed to request password reset", "error", err, "email", emailAddr)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		for _, ve := range validationErrs {
+			data.Errors[ve.Field] = ve.Message
+		}
+
+		if err := h.templates.RenderComponent(w, "forgot-password-form.html", data); err != nil {
+			slog.Error("Failed to render forgot password form", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// req == nil - такого email нет, письмо не отправляем, но ответ тот же
+	// Лимит писем на адрес исчерпан - тоже молча не отправляем:
+	// защищает почтовый ящик от спама, не выдавая существование аккаунта
+	if req != nil && !h.allowEmailSilently(r, "password-reset:"+strings.ToLower(req.Email)) {
+		slog.Info("Password reset email rate limited", "user_id", req.UserID)
+		req = nil
+	}
+
+	if req != nil {
+		payload := map[string]string{
+			"token":     req.Token,
+			"user_name": req.UserName,
+		}
+
+		if err := h.emailQueue.Enqueue(r.Context(), email.EmailTypePasswordReset, req.Email, &req.UserID, payload); err != nil {
+			slog.Error("Failed to enqueue password reset email", "error", err, "user_id", req.UserID)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		slog.Info("Password reset requested", "user_id", req.UserID)
+	}
+
+	data.Sent = true
+	if err := h.templates.RenderComponent(w, "forgot-password-form.html", data); err != nil {
+		slog.Error("Failed to render forgot password form", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// GetResetPassword отображает форму нового пароля по ссылке из письма
+// Токен проверяется сразу, чтобы не заставлять пользователя вводить пароль
+// для устаревшей ссылки
+func (h *Handler) GetResetPassword(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+
+	data := templates.ResetPasswordData{
+		Token:             token,
+		Errors:            make(map[string]string),
+		PasswordMinLength: h.cfg.Password.MinLength,
+	}
+
+	if err := h.userService.CheckPasswordResetToken(r.Context(), token); err != nil {
+		if !errors.Is(err, user.ErrInvalidToken) {
+			slog.Error("Failed to check password reset token", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		data.InvalidToken = true
+	}
+
+	if err := h.templates.Render(w, r, "reset-password.html", data); err != nil {
+		slog.Error("Failed to render reset password page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// PostResetPassword устанавливает новый пароль и завершает все сессии пользователя
+func (h *Handler) PostResetPassword(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		slog.Error("Failed to parse reset password form", "error", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	input := user.ResetPasswordInput{
+		Token:           r.FormValue("token"),
+		Password:        r.FormValue("password"),
+		PasswordConfirm: r.FormValue("password_confirm"),
+	}
+
+	data := templates.ResetPasswordData{
+		Token:             input.Token,
+		Errors:            make(map[string]string),
+		PasswordMinLength: h.cfg.Password.MinLength,
+	}
+
+	userID, err := h.userService.ResetPassword(r.Context(), input)
+	if err != nil {
+		var validationErrs user.ValidationErrors
+		switch {
+		case errors.As(err, &validationErrs):
+			for _, ve := range validationErrs {
+				data.Errors[ve.Field] = ve.Message
+			}
+		case errors.Is(err, user.ErrInvalidToken):
+			data.InvalidToken = true
+		default:
+			slog.Error("Failed to reset password", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		if err := h.templates.RenderComponent(w, "reset-password-form.html", data); err != nil {
+			slog.Error("Failed to render reset password form", "error", err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	// Завершаем все сессии - если пароль сбрасывают из-за взлома,
+	// атакующий не должен остаться залогиненным
+	if err := h.sessionService.DeleteUserSessions(r.Context(), userID); err != nil {
+		slog.Error("Failed to revoke sessions after password reset", "error", err, "user_id", userID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// Текущий браузер тоже разлогинен
+	session.ClearCookie(w)
+
+	slog.Info("Password reset successfully", "user_id", userID)
+	h.audit.Record(r, audit.EventTypePasswordChanged, &userID, map[string]string{"method": "reset_link"})
+
+	data.Done = true
+	if err := h.templates.RenderComponent(w, "reset-password-form.html", data); err != nil {
+		slog.Error("Failed to render reset password form", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/udisondev/learn-go/internal/middleware"
+	"github.com/udisondev/learn-go/internal/ratelimit"
+	"github.com/udisondev/learn-go/internal/templates"
+)
+
+// RateLimited отвечает на запрос, превысивший лимит (429)
+// WHY: Формы входа и регистрации отправляются через HTMX - текст http.Error
+// HTMX не покажет, и кнопка просто "не работает"
+// HOW: HTMX получает плашку в #flash (HX-Retarget, 429 показывает скрипт в layout),
+// обычный запрос - 429 текстом; Retry-After уже выставил middleware
+func (h *Handler) RateLimited(w http.ResponseWriter, r *http.Request) {
+	res, _ := ratelimit.FromCtx(r.Context())
+
+	if !middleware.IsHTMX(r) {
+		http.Error(w, "Too many requests. Please try again later.", http.StatusTooManyRequests)
+		return
+	}
+
+	w.Header().Set("HX-Retarget", "#flash")
+	w.Header().Set("HX-Reswap", "innerHTML")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusTooManyRequests)
+
+	data := templates.RateLimitedData{Wait: waitText(res.RetryAfter)}
+	if err := h.templates.RenderComponent(w, "rate-limited.html", data); err != nil {
+		slog.Error("Failed to render rate limit error", "error", err)
+	}
+}
+
+// allowEmail проверяет лимит писем на адрес (политика resend-email)
+// Если лимит исчерпан, возвращает текст ошибки для поля email
+// Ошибка хранилища пропускает запрос, как и в middleware.RateLimit
+func (h *Handler) allowEmail(w http.ResponseWriter, r *http.Request, key string) (string, bool) {
+	res, err := h.rateLimiter.Allow(r.Context(), ratelimit.PolicyResendEmail, key)
+	if err != nil {
+		slog.Error("Rate limit check failed", "error", err, "policy", ratelimit.PolicyResendEmail)
+		return "", true
+	}
+
+	middleware.SetRateLimitHeaders(w, res)
+	if !res.Allowed {
+		return "Письмо уже отправлено недавно. Повторите попытку " + waitText(res.RetryAfter), false
+	}
+	return "", true
+}
+
+// allowEmailSilently проверяет тот же лимит писем на адрес, что и allowEmail,
+// но ничего не пишет в ответ
+// WHY: В форме восстановления пароля ответ не должен зависеть от того,
+// есть ли аккаунт - ни сообщение, ни заголовки X-RateLimit-* не должны его выдать
+func (h *Handler) allowEmailSilently(r *http.Request, key string) bool {
+	res, err := h.rateLimiter.Allow(r.Context(), ratelimit.PolicyResendEmail, key)
+	if err != nil {
+		slog.Error("Rate limit check failed", "error", err, "policy", ratelimit.PolicyResendEmail)
+		return true
+	}
+	return res.Allowed
+}
+
+// waitText - через сколько можно повторить запрос, для сообщений пользователю
+func waitText(d time.Duration) string {
+	switch {
+	case d <= time.Minute:
+		return fmt.Sprintf("через %d сек.", max(int(d.Round(time.Second).Seconds()), 1))
+	case d <= time.Hour:
+		return fmt.Sprintf("через %d мин.", int((d + time.Minute - 1).Minutes()))
+	default:
+		return fmt.Sprintf("через %d ч.", int((d + time.Hour - 1).Hours()))
+	}
+}
@@ -18,9 +18,12 @@ func (h *Handler) HandleRegisterPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	data := &templates.RegisterData{}
+	data := &templates.RegisterData{
+		Providers:         h.oauth.Providers(),
+		PasswordMinLength: h.cfg.Password.MinLength,
+	}
 
-	if err := h.templates.RenderRegister(w, data); err != nil {
+	if err := h.templates.RenderRegister(w, r, data); err != nil {
 		slog.Error("Failed to render register page", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
@@ -58,10 +61,11 @@ func (h *Handler) HandleRegisterSubmit(w http.ResponseWriter, r *http.Request) {
 		if errors.As(err, &validationErrs) {
 			// Validation errors - отображаем в форме
 			data := &templates.RegisterData{
-				Errors: make(map[string]string),
-				Name:   input.Name,
-				Email:  input.Email,
-				Phone:  input.Phone,
+				Errors:            make(map[string]string),
+				Name:              input.Name,
+				Email:             input.Email,
+				Phone:             input.Phone,
+				PasswordMinLength: h.cfg.Password.MinLength,
 			}
 
 			// Преобразуем ValidationErrors в map для template
@@ -0,0 +1,217 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/twofactor"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// GetSecurity отображает страницу "Безопасность" в профиле
+// Управление двухфакторной аутентификацией и ссылка на активные устройства
+func (h *Handler) GetSecurity(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+
+	data, err := h.securityData(r, u)
+	if err != nil {
+		slog.Error("Failed to load two-factor status", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.templates.Render(w, r, "security.html", data); err != nil {
+		slog.Error("Failed to render security page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// PostTwoFactorSetup начинает подключение 2FA: генерирует секрет и QR код
+func (h *Handler) PostTwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+
+	data, err := h.securityData(r, u)
+	if err != nil {
+		slog.Error("Failed to load two-factor status", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	setup, err := h.twoFactor.BeginSetup(r.Context(), u.ID, u.Email)
+	if err != nil && !errors.Is(err, twofactor.ErrAlreadyEnabled) {
+		slog.Error("Failed to begin two-factor setup", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+	data.Setup = setupView(setup)
+
+	h.renderTwoFactorSection(w, data)
+}
+
+// PostTwoFactorConfirm подтверждает подключение 2FA первым кодом из приложения
+// При успехе показывает коды восстановления (единственный раз) и отправляет письмо
+func (h *Handler) PostTwoFactorConfirm(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	code := strings.TrimSpace(r.FormValue("code"))
+
+	codes, err := h.twoFactor.Confirm(r.Context(), u.ID, code)
+	if err != nil && !errors.Is(err, twofactor.ErrInvalidCode) && !errors.Is(err, twofactor.ErrSetupNotStarted) && !errors.Is(err, twofactor.ErrAlreadyEnabled) {
+		slog.Error("Failed to confirm two-factor setup", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data, statusErr := h.securityData(r, u)
+	if statusErr != nil {
+		slog.Error("Failed to load two-factor status", "error", statusErr, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if errors.Is(err, twofactor.ErrInvalidCode) {
+		// Показываем тот же QR код еще раз, секрет уже отсканирован
+		setup, setupErr := h.twoFactor.ResumeSetup(r.Context(), u.ID, u.Email)
+		if setupErr != nil {
+			slog.Error("Failed to resume two-factor setup", "error", setupErr, "user_id", u.ID)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+		data.Setup = setupView(setup)
+		data.Errors["code"] = "Неверный код. Проверьте время на телефоне и попробуйте снова."
+		h.renderTwoFactorSection(w, data)
+		return
+	}
+
+	if err == nil {
+		data.RecoveryCodes = codes
+
+		slog.Info("Two-factor authentication enabled", "user_id", u.ID)
+		h.audit.Record(r, audit.EventTypeTwoFactorEnabled, &u.ID, nil)
+		h.notifyUser(r.Context(), u,
+			"Двухфакторная аутентификация включена",
+			"Для вашего аккаунта включена двухфакторная аутентификация. Если это были не вы, немедленно смените пароль и свяжитесь с поддержкой.",
+		)
+	}
+
+	h.renderTwoFactorSection(w, data)
+}
+
+// PostTwoFactorDisable отключает 2FA после проверки текущего кода
+func (h *Handler) PostTwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	code := strings.TrimSpace(r.FormValue("code"))
+
+	err := h.twoFactor.Disable(r.Context(), u.ID, code)
+	if err != nil && !errors.Is(err, twofactor.ErrInvalidCode) && !errors.Is(err, twofactor.ErrNotEnabled) {
+		slog.Error("Failed to disable two-factor", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data, statusErr := h.securityData(r, u)
+	if statusErr != nil {
+		slog.Error("Failed to load two-factor status", "error", statusErr, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if errors.Is(err, twofactor.ErrInvalidCode) {
+		data.Errors["disable_code"] = "Неверный код"
+	} else if err == nil {
+		slog.Info("Two-factor authentication disabled", "user_id", u.ID)
+		h.audit.Record(r, audit.EventTypeTwoFactorDisabled, &u.ID, nil)
+		h.notifyUser(r.Context(), u,
+			"Двухфакторная аутентификация отключена",
+			"Для вашего аккаунта отключена двухфакторная аутентификация. Если это были не вы, немедленно смените пароль и свяжитесь с поддержкой.",
+		)
+	}
+
+	h.renderTwoFactorSection(w, data)
+}
+
+// PostRecoveryCodes выпускает новый набор кодов восстановления
+// Старые коды перестают работать
+func (h *Handler) PostRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+	code := strings.TrimSpace(r.FormValue("code"))
+
+	codes, err := h.twoFactor.RegenerateRecoveryCodes(r.Context(), u.ID, code)
+	if err != nil && !errors.Is(err, twofactor.ErrInvalidCode) && !errors.Is(err, twofactor.ErrNotEnabled) {
+		slog.Error("Failed to regenerate recovery codes", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	data, statusErr := h.securityData(r, u)
+	if statusErr != nil {
+		slog.Error("Failed to load two-factor status", "error", statusErr, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if errors.Is(err, twofactor.ErrInvalidCode) {
+		data.Errors["recovery_code"] = "Неверный код"
+	} else if err == nil {
+		data.RecoveryCodes = codes
+		slog.Info("Recovery codes regenerated", "user_id", u.ID)
+	}
+
+	h.renderTwoFactorSection(w, data)
+}
+
+// renderTwoFactorSection отдает блок 2FA для HTMX
+func (h *Handler) renderTwoFactorSection(w http.ResponseWriter, data *templates.SecurityData) {
+	if err := h.templates.RenderComponent(w, "two-factor-section.html", data); err != nil {
+		slog.Error("Failed to render two-factor section", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// securityData собирает данные для страницы безопасности
+func (h *Handler) securityData(r *http.Request, u *user.User) (*templates.SecurityData, error) {
+	status, err := h.twoFactor.Status(r.Context(), u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &templates.SecurityData{
+		User:      u,
+		TwoFactor: status,
+		Errors:    make(map[string]string),
+	}, nil
+}
+
+// setupView готовит данные подключения 2FA для шаблона
+// QR код встраивается как data URI - секрет не уходит на сторонние сервисы
+func setupView(setup *twofactor.Setup) *templates.TwoFactorSetupView {
+	if setup == nil {
+		return nil
+	}
+
+	return &templates.TwoFactorSetupView{
+		Secret: setup.Secret,
+		URI:    setup.URI,
+		QRCode: template.URL("data:image/png;base64," + base64.StdEncoding.EncodeToString(setup.QRCodePNG)),
+	}
+}
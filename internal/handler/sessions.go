@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/session"
+	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// GetSessions отображает страницу "Активные устройства"
+// Список всех сессий пользователя с браузером, ОС, местоположением
+// и временем последней активности
+func (h *Handler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+	current, _ := session.FromCtx(r.Context())
+
+	data, err := h.sessionsData(r, u, current)
+	if err != nil {
+		slog.Error("Failed to list sessions", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.templates.Render(w, r, "sessions.html", data); err != nil {
+		slog.Error("Failed to render sessions page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// PostRevokeSession завершает одну сессию пользователя по ее публичному handle
+func (h *Handler) PostRevokeSession(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+	current, _ := session.FromCtx(r.Context())
+
+	handle := chi.URLParam(r, "handle")
+
+	err := h.sessionService.RevokeDevice(r.Context(), u.ID, handle)
+	if err != nil && !errors.Is(err, session.ErrSessionNotFound) {
+		slog.Error("Failed to revoke session", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if err == nil {
+		h.audit.Record(r, audit.EventTypeSessionRevoked, &u.ID, map[string]string{"handle": handle})
+	}
+
+	// Пользователь завершил собственную сессию - он больше не залогинен
+	if current != nil && current.Handle() == handle {
+		session.ClearCookie(w)
+		w.Header().Set("HX-Redirect", "/login")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	slog.Info("Session revoked", "user_id", u.ID, "handle", handle)
+
+	h.renderSessionsList(w, r, u, current)
+}
+
+// PostRevokeOtherSessions завершает все сессии пользователя, кроме текущей
+func (h *Handler) PostRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+	current, ok := session.FromCtx(r.Context())
+	if !ok {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.sessionService.RevokeOtherDevices(r.Context(), u.ID, current.ID); err != nil {
+		slog.Error("Failed to revoke other sessions", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("Other sessions revoked", "user_id", u.ID)
+	h.audit.Record(r, audit.EventTypeSessionsRevoked, &u.ID, nil)
+
+	h.renderSessionsList(w, r, u, current)
+}
+
+// renderSessionsList отдает обновленный список сессий для HTMX
+func (h *Handler) renderSessionsList(w http.ResponseWriter, r *http.Request, u *user.User, current *session.Session) {
+	data, err := h.sessionsData(r, u, current)
+	if err != nil {
+		slog.Error("Failed to list sessions", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.templates.RenderComponent(w, "sessions-list.html", data); err != nil {
+		slog.Error("Failed to render sessions list", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// sessionsData собирает данные для страницы и списка сессий
+func (h *Handler) sessionsData(r *http.Request, u *user.User, current *session.Session) (*templates.SessionsData, error) {
+	currentID := uuid.Nil
+	if current != nil {
+		currentID = current.ID
+	}
+
+	devices, err := h.sessionService.ListDevices(r.Context(), u.ID, currentID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &templates.SessionsData{
+		User:    u,
+		Devices: devices,
+	}, nil
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/udisondev/learn-go/internal/apitoken"
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// tokenExpiryOptions - сроки действия токена, доступные в форме (в днях, 0 - бессрочно)
+var tokenExpiryOptions = []int{30, 90, 365, 0}
+
+// GetTokens отображает страницу "Токены доступа" в профиле
+// Токены нужны консольному клиенту и плагинам редакторов
+func (h *Handler) GetTokens(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+
+	data, err := h.tokensData(r, u)
+	if err != nil {
+		slog.Error("Failed to list access tokens", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.templates.Render(w, r, "tokens.html", data); err != nil {
+		slog.Error("Failed to render tokens page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// PostCreateToken выпускает новый токен
+// Секрет показывается один раз, в БД хранится только его hash
+func (h *Handler) PostCreateToken(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	name := strings.TrimSpace(r.FormValue("name"))
+	formErrors := make(map[string]string)
+
+	if name == "" {
+		formErrors["name"] = "Укажите название токена"
+	} else if utf8.RuneCountInString(name) > 64 {
+		formErrors["name"] = "Название должно быть не длиннее 64 символов"
+	}
+
+	var scopes []apitoken.Scope
+	for _, raw := range r.Form["scopes"] {
+		scope, err := apitoken.ParseScope(raw)
+		if err != nil {
+			formErrors["scopes"] = "Неизвестное право доступа"
+			break
+		}
+		scopes = append(scopes, scope)
+	}
+	if len(scopes) == 0 && formErrors["scopes"] == "" {
+		formErrors["scopes"] = "Выберите хотя бы одно право доступа"
+	}
+
+	days, err := strconv.Atoi(r.FormValue("expires"))
+	if err != nil || !slices.Contains(tokenExpiryOptions, days) {
+		formErrors["expires"] = "Выберите срок действия"
+	}
+
+	if len(formErrors) > 0 {
+		h.renderTokensSection(w, r, u, func(data *templates.TokensData) {
+			data.Name = name
+			data.Errors = formErrors
+		})
+		return
+	}
+
+	token, secret, err := h.apiTokens.Create(r.Context(), u.ID, name, scopes, time.Duration(days)*24*time.Hour)
+	if errors.Is(err, apitoken.ErrTooManyTokens) {
+		h.renderTokensSection(w, r, u, func(data *templates.TokensData) {
+			data.Name = name
+			data.Errors = map[string]string{"form": "Достигнут лимит токенов. Отзовите ненужные и попробуйте снова."}
+		})
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to create access token", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("Access token created", "user_id", u.ID, "token_id", token.ID)
+	h.audit.Record(r, audit.EventTypeAccessTokenCreated, &u.ID, map[string]string{"token_id": strconv.FormatInt(token.ID, 10), "name": token.Name})
+
+	h.notifyUser(r.Context(), u,
+		"Создан токен доступа",
+		fmt.Sprintf("В ваш аккаунт добавлен токен доступа «%s». Если это были не вы, отзовите его в профиле и смените пароль.", name),
+	)
+
+	h.renderTokensSection(w, r, u, func(data *templates.TokensData) {
+		data.Created = &templates.CreatedTokenView{Name: token.Name, Secret: secret}
+	})
+}
+
+// PostRevokeToken отзывает токен, он перестает работать сразу
+func (h *Handler) PostRevokeToken(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+
+	tokenID, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	err = h.apiTokens.Revoke(r.Context(), u.ID, tokenID)
+	if err != nil && !errors.Is(err, apitoken.ErrTokenNotFound) {
+		slog.Error("Failed to revoke access token", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if err == nil {
+		slog.Info("Access token revoked", "user_id", u.ID, "token_id", tokenID)
+		h.audit.Record(r, audit.EventTypeAccessTokenRevoked, &u.ID, map[string]string{"token_id": strconv.FormatInt(tokenID, 10)})
+	}
+
+	h.renderTokensSection(w, r, u, nil)
+}
+
+// renderTokensSection отдает обновленный блок токенов для HTMX
+// modify дополняет данные (ошибки формы, только что созданный токен)
+func (h *Handler) renderTokensSection(w http.ResponseWriter, r *http.Request, u *user.User, modify func(*templates.TokensData)) {
+	data, err := h.tokensData(r, u)
+	if err != nil {
+		slog.Error("Failed to list access tokens", "error", err, "user_id", u.ID)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if modify != nil {
+		modify(data)
+	}
+
+	if err := h.templates.RenderComponent(w, "tokens-section.html", data); err != nil {
+		slog.Error("Failed to render tokens section", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// tokensData собирает данные для страницы и блока токенов
+func (h *Handler) tokensData(r *http.Request, u *user.User) (*templates.TokensData, error) {
+	tokens, err := h.apiTokens.List(r.Context(), u.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &templates.TokensData{
+		User:          u,
+		Tokens:        tokens,
+		Scopes:        apitoken.ScopeNames(),
+		ExpiryOptions: tokenExpiryOptions,
+		Now:           time.Now(),
+		Errors:        make(map[string]string),
+	}, nil
+}
@@ -0,0 +1,34 @@
+package handler
+
+import (
+	"log/slog"
+	"net/http"
+
+	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// allSubPlans - все тарифы по возрастанию (страница тарифов, смена тарифа в админке)
+var allSubPlans = []user.SubPlan{user.SubPlanFree, user.SubPlanBasic, user.SubPlanStandard, user.SubPlanPremium}
+
+// GetUpgrade отображает предложение повысить тариф
+// Сюда отправляет middleware.RequireSubPlan, когда тариф пользователя ниже нужного
+func (h *Handler) GetUpgrade(w http.ResponseWriter, r *http.Request) {
+	u, _ := user.FromCtx(r.Context())
+
+	required, err := user.ParseSubPlan(r.URL.Query().Get("plan"))
+	if err != nil {
+		required = user.SubPlanBasic
+	}
+
+	data := &templates.UpgradeData{
+		User:     u,
+		Required: required,
+		Plans:    allSubPlans,
+	}
+
+	if err := h.templates.Render(w, r, "upgrade.html", data); err != nil {
+		slog.Error("Failed to render upgrade page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
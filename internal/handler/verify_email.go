@@ -1,8 +1,15 @@
 package handler
 
 import (
+	"errors"
 	"log/slog"
 	"net/http"
+	"strings"
+
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/email"
+	"github.com/udisondev/learn-go/internal/templates"
+	"github.com/udisondev/learn-go/internal/user"
 )
 
 // HandleVerifyEmail обрабатывает верификацию email по токену из ссылки
@@ -10,50 +17,133 @@ func (h *Handler) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
 	// Получаем токен из query параметра
 	token := r.URL.Query().Get("token")
 	if token == "" {
-		http.Error(w, "Missing verification token", http.StatusBadRequest)
+		// Без токена - сюда отправляет middleware.RequireVerified
+		h.renderVerifyEmailPage(w, r, true)
 		return
 	}
 
 	// Вызываем service для верификации
 	userID, err := h.userService.VerifyEmail(r.Context(), token)
+	if errors.Is(err, user.ErrInvalidToken) {
+		// Ссылка истекла или уже использована - предлагаем отправить новое письмо
+		slog.Warn("Invalid or expired verification link")
+		h.renderVerifyEmailPage(w, r, false)
+		return
+	}
 	if err != nil {
-		slog.Error("Failed to verify email", "error", err, "token", token)
-		// TODO: Render error page with message "Invalid or expired token"
-		http.Error(w, "Invalid or expired verification link", http.StatusBadRequest)
+		slog.Error("Failed to verify email", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
 	// Верификация успешна
 	slog.Info("Email verified successfully", "user_id", userID)
+	h.audit.Record(r, audit.EventTypeEmailVerified, &userID, nil)
+
+	// В кэше сессий пользователь ещё не подтверждён
+	h.sessionService.InvalidateUser(userID)
 
 	// Создаем сессию для автологина после верификации
-	sessionID, err := h.sessionService.CreateSession(
-		r.Context(),
-		userID,
-		getRealIP(r),
-		r.Header.Get("User-Agent"),
-	)
-	if err != nil {
+	// Срок жизни cookie совпадает со сроком жизни сессии (absolute/idle timeout)
+	if err := h.startSession(w, r, userID, "email_verification"); err != nil {
+		if errors.Is(err, user.ErrAccountSuspended) {
+			h.renderLoginPage(w, r, templates.LoginData{
+				Errors: map[string]string{"form": suspendedMessage},
+			})
+			return
+		}
 		slog.Error("Failed to create session after verification", "error", err, "user_id", userID)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	// Устанавливаем session cookie
-	// MaxAge = 0 означает session cookie (удалится при закрытии браузера)
-	// Но сессия в БД безграничная, пользователь может вернуться позже
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    sessionID.String(),
-		Path:     "/",
-		MaxAge:   0,                     // Session cookie (browser lifetime)
-		HttpOnly: true,                  // Защита от XSS
-		Secure:   h.cfg.Session.Secure, // HTTPS only в production
-		SameSite: http.SameSiteLaxMode,  // CSRF защита
-	})
-
-	slog.Info("Session created after verification", "user_id", userID, "session_id", sessionID)
+	slog.Info("Session created after verification", "user_id", userID)
 
 	// Редирект на главную (пользователь уже залогинен)
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
+
+// PostResendVerification повторно отправляет письмо для подтверждения email
+// Вызывается со страницы истекшей ссылки и из формы входа
+//
+// Почему ответ одинаковый для любого email:
+// - Нельзя раскрывать, зарегистрирован ли email и подтвержден ли он
+// - Cooldown тоже применяется к любому email, до поиска пользователя
+func (h *Handler) PostResendVerification(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		slog.Error("Failed to parse resend verification form", "error", err)
+		http.Error(w, "Bad Request", http.StatusBadRequest)
+		return
+	}
+
+	address := strings.TrimSpace(r.FormValue("email"))
+	data := templates.ResendVerificationData{
+		Errors: make(map[string]string),
+	}
+
+	// Cooldown на email: защищает почтовый ящик от спама и нас от блокировки SMTP
+	if address != "" {
+		if msg, ok := h.allowEmail(w, r, "verification:"+strings.ToLower(address)); !ok {
+			data.Errors["email"] = msg
+			h.renderResendVerification(w, data)
+			return
+		}
+	}
+
+	req, err := h.userService.ResendVerification(r.Context(), address)
+	if err != nil {
+		var validationErrs user.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			for _, ve := range validationErrs {
+				data.Errors[ve.Field] = ve.Message
+			}
+			h.renderResendVerification(w, data)
+			return
+		}
+
+		slog.Error("Failed to resend verification", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	// req == nil: email не найден или уже подтвержден - письмо не нужно
+	if req != nil {
+		payload := map[string]string{
+			"token":     req.Token,
+			"user_name": req.UserName,
+		}
+
+		if err := h.emailQueue.Enqueue(r.Context(), email.EmailTypeVerification, req.Email, &req.UserID, payload); err != nil {
+			slog.Error("Failed to enqueue verification email", "error", err, "user_id", req.UserID)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
+		slog.Info("Verification email resent", "user_id", req.UserID)
+	}
+
+	data.Sent = true
+	h.renderResendVerification(w, data)
+}
+
+// renderVerifyEmailPage показывает страницу с формой повторной отправки письма
+// pending: email ждет подтверждения (иначе - ссылка истекла или использована)
+func (h *Handler) renderVerifyEmailPage(w http.ResponseWriter, r *http.Request, pending bool) {
+	data := templates.ResendVerificationData{
+		Pending: pending,
+		Errors:  make(map[string]string),
+	}
+
+	if err := h.templates.Render(w, r, "verify-email.html", data); err != nil {
+		slog.Error("Failed to render verify email page", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// renderResendVerification отдает результат повторной отправки для HTMX
+func (h *Handler) renderResendVerification(w http.ResponseWriter, data templates.ResendVerificationData) {
+	if err := h.templates.RenderComponent(w, "resend-verification.html", data); err != nil {
+		slog.Error("Failed to render resend verification result", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
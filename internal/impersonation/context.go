@@ -0,0 +1,21 @@
+package impersonation
+
+import "context"
+
+// ctxKey is a type-safe context key for the running impersonation
+type ctxKey struct{}
+
+// WithCtx adds running impersonation to context
+// WHY: user.FromCtx returns the impersonated user, code that must know
+// the real actor (audit log, sensitive action guards, banner) reads it from here
+// HOW: Set by Impersonate middleware together with user.WithCtx(target)
+func WithCtx(ctx context.Context, a *Active) context.Context {
+	return context.WithValue(ctx, ctxKey{}, a)
+}
+
+// FromCtx retrieves running impersonation from context
+// Returns (impersonation, true) if an admin acts as another user, (nil, false) otherwise
+func FromCtx(ctx context.Context) (*Active, bool) {
+	a, ok := ctx.Value(ctxKey{}).(*Active)
+	return a, ok
+}
@@ -0,0 +1,43 @@
+package impersonation
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+var (
+	// ErrNotActive - session has no running impersonation
+	ErrNotActive = errors.New("impersonation is not active")
+
+	// ErrExpired - impersonation ran out of time, it has just been ended
+	ErrExpired = errors.New("impersonation expired")
+
+	// ErrSelf - admin tried to impersonate themselves
+	ErrSelf = errors.New("cannot impersonate yourself")
+
+	// ErrTargetAdmin - target is an admin too
+	// Acting as another admin would hide who actually did admin actions
+	ErrTargetAdmin = errors.New("cannot impersonate an admin")
+)
+
+// Impersonation is an admin session acting as another user
+type Impersonation struct {
+	ID        int64
+	ActorID   int64     // Admin who started it
+	TargetID  int64     // User the admin acts as
+	SessionID uuid.UUID // Admin's session, impersonation ends with it
+	StartedAt time.Time
+	ExpiresAt time.Time
+	EndedAt   *time.Time // nil - still running (unless expired)
+}
+
+// Active is the impersonation of the current request
+// Target is what user.FromCtx returns, Actor is the real user behind the request
+type Active struct {
+	Impersonation
+	Actor  *user.User
+	Target *user.User
+}
@@ -0,0 +1,119 @@
+package impersonation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+// Repository handles impersonations data access
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+// NewRepository creates new impersonation repository
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// Create ends running impersonation of the session (if any) and saves a new one
+// Both in one transaction - the partial unique index allows one active row per session
+func (r *Repository) Create(ctx context.Context, imp *Impersonation) error {
+	return pgx.BeginTxFunc(ctx, r.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		endQuery, endArgs, err := psql.
+			Update("impersonations").
+			Set("ended_at", imp.StartedAt).
+			Where(sq.Eq{"session_id": imp.SessionID, "ended_at": nil}).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build update query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, endQuery, endArgs...); err != nil {
+			return fmt.Errorf("failed to end previous impersonation: %w", err)
+		}
+
+		query, args, err := psql.
+			Insert("impersonations").
+			Columns("actor_id", "target_id", "session_id", "started_at", "expires_at").
+			Values(imp.ActorID, imp.TargetID, imp.SessionID, imp.StartedAt, imp.ExpiresAt).
+			Suffix("RETURNING id").
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build insert query: %w", err)
+		}
+
+		if err := tx.QueryRow(ctx, query, args...).Scan(&imp.ID); err != nil {
+			return fmt.Errorf("failed to create impersonation: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetActive returns not ended impersonation of the session, expired or not
+// Returns ErrNotActive if there is none
+func (r *Repository) GetActive(ctx context.Context, sessionID uuid.UUID) (*Impersonation, error) {
+	query, args, err := psql.
+		Select("id", "actor_id", "target_id", "session_id", "started_at", "expires_at", "ended_at").
+		From("impersonations").
+		Where(sq.Eq{"session_id": sessionID, "ended_at": nil}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var imp Impersonation
+	err = r.db.QueryRow(ctx, query, args...).Scan(
+		&imp.ID,
+		&imp.ActorID,
+		&imp.TargetID,
+		&imp.SessionID,
+		&imp.StartedAt,
+		&imp.ExpiresAt,
+		&imp.EndedAt,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotActive
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get impersonation: %w", err)
+	}
+
+	return &imp, nil
+}
+
+// End marks impersonation as ended
+// Returns ErrNotActive if it was already ended (concurrent stop or expiry)
+func (r *Repository) End(ctx context.Context, id int64, at time.Time) error {
+	query, args, err := psql.
+		Update("impersonations").
+		Set("ended_at", at).
+		Where(sq.Eq{"id": id, "ended_at": nil}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to end impersonation: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotActive
+	}
+
+	return nil
+}
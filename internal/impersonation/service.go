@@ -0,0 +1,114 @@
+package impersonation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/udisondev/learn-go/internal/user"
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// UserLoader loads impersonated users
+// Implemented by user.Service
+type UserLoader interface {
+	GetUserByID(ctx context.Context, userID int64) (*user.User, error)
+}
+
+// Service lets admins act as another user for a limited time
+// WHY: Support needs to see exactly what a learner sees ("my exercise won't unlock")
+// without asking for their password
+// HOW: Impersonation is bound to the admin's session and stored in the DB,
+// Impersonate middleware swaps the user in context on every request of that session.
+// It ends on Stop, on expiry, or together with the session
+type Service struct {
+	repo  *Repository
+	users UserLoader
+	ttl   time.Duration
+}
+
+// NewService creates new impersonation service
+func NewService(db *pgxpool.Pool, cfg config.ImpersonationConfig, users UserLoader) *Service {
+	return &Service{
+		repo:  NewRepository(db),
+		users: users,
+		ttl:   cfg.TTL,
+	}
+}
+
+// Start begins impersonation of target by actor in the actor's session
+// A running impersonation of the same session is replaced
+func (s *Service) Start(ctx context.Context, actor, target *user.User, sessionID uuid.UUID) (*Impersonation, error) {
+	if actor.ID == target.ID {
+		return nil, ErrSelf
+	}
+	if target.IsAdmin() {
+		return nil, ErrTargetAdmin
+	}
+
+	now := time.Now().UTC()
+	imp := &Impersonation{
+		ActorID:   actor.ID,
+		TargetID:  target.ID,
+		SessionID: sessionID,
+		StartedAt: now,
+		ExpiresAt: now.Add(s.ttl),
+	}
+
+	if err := s.repo.Create(ctx, imp); err != nil {
+		return nil, err
+	}
+
+	return imp, nil
+}
+
+// Resolve returns running impersonation of the session and its target
+//
+// Returns:
+// - ErrNotActive if the session doesn't impersonate anyone
+// - ErrExpired together with the impersonation if time ran out -
+// it is ended here, caller records the stop
+func (s *Service) Resolve(ctx context.Context, sessionID uuid.UUID) (*Impersonation, *user.User, error) {
+	imp, err := s.repo.GetActive(ctx, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now().UTC()
+	if !now.Before(imp.ExpiresAt) {
+		if err := s.repo.End(ctx, imp.ID, imp.ExpiresAt); err != nil {
+			return nil, nil, err
+		}
+		return imp, nil, ErrExpired
+	}
+
+	target, err := s.users.GetUserByID(ctx, imp.TargetID)
+	if errors.Is(err, user.ErrUserNotFound) {
+		return nil, nil, ErrNotActive
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load impersonated user: %w", err)
+	}
+
+	return imp, target, nil
+}
+
+// Stop ends running impersonation of the session
+// Returns the ended impersonation, ErrNotActive if there was none
+func (s *Service) Stop(ctx context.Context, sessionID uuid.UUID) (*Impersonation, error) {
+	imp, err := s.repo.GetActive(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if err := s.repo.End(ctx, imp.ID, now); err != nil {
+		return nil, err
+	}
+	imp.EndedAt = &now
+
+	return imp, nil
+}
@@ -0,0 +1,197 @@
+package loginguard
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+// Repository handles failed login and lockout data access
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+// NewRepository creates new login guard repository
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// RecordFailure stores a failed password attempt
+func (r *Repository) RecordFailure(ctx context.Context, email, ip string) error {
+	query, args, err := psql.
+		Insert("failed_logins").
+		Columns("email", "ip_address", "created_at").
+		Values(email, ip, time.Now().UTC()).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to record failed login: %w", err)
+	}
+
+	return nil
+}
+
+// CountFailures returns number of failures and time of the latest one since given time
+// column is "email" or "ip_address"
+func (r *Repository) CountFailures(ctx context.Context, column, value string, since time.Time) (int, *time.Time, error) {
+	query, args, err := psql.
+		Select("COUNT(*)", "MAX(created_at)").
+		From("failed_logins").
+		Where(sq.Eq{column: value}).
+		Where(sq.Gt{"created_at": since}).
+		ToSql()
+
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var count int
+	var last *time.Time
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&count, &last); err != nil {
+		return 0, nil, fmt.Errorf("failed to count failed logins: %w", err)
+	}
+
+	return count, last, nil
+}
+
+// ClearFailures forgets failed attempts of an account
+// Called after successful login - delays start from scratch
+func (r *Repository) ClearFailures(ctx context.Context, email string) error {
+	query, args, err := psql.
+		Delete("failed_logins").
+		Where(sq.Eq{"email": email}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to clear failed logins: %w", err)
+	}
+
+	return nil
+}
+
+// GetLockout returns end of an active lockout or nil if account is not locked
+func (r *Repository) GetLockout(ctx context.Context, email string) (*time.Time, error) {
+	query, args, err := psql.
+		Select("locked_until").
+		From("login_lockouts").
+		Where(sq.Eq{"email": email}).
+		Where(sq.Gt{"locked_until": time.Now().UTC()}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var lockedUntil time.Time
+	err = r.db.QueryRow(ctx, query, args...).Scan(&lockedUntil)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get lockout: %w", err)
+	}
+
+	return &lockedUntil, nil
+}
+
+// Lock locks an account until lockedUntil
+// WHY: Failures are cleared in the same transaction, so after the lockout
+// ends the account gets a fresh set of attempts instead of being
+// locked again by the very next typo
+func (r *Repository) Lock(ctx context.Context, email, tokenHash string, lockedUntil time.Time) error {
+	return pgx.BeginTxFunc(ctx, r.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		query, args, err := psql.
+			Insert("login_lockouts").
+			Columns("email", "locked_until", "unlock_token_hash", "created_at").
+			Values(email, lockedUntil, tokenHash, time.Now().UTC()).
+			Suffix(`ON CONFLICT (email) DO UPDATE
+				SET locked_until = EXCLUDED.locked_until,
+				    unlock_token_hash = EXCLUDED.unlock_token_hash,
+				    created_at = EXCLUDED.created_at`).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build insert query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to lock account: %w", err)
+		}
+
+		deleteQuery, deleteArgs, err := psql.
+			Delete("failed_logins").
+			Where(sq.Eq{"email": email}).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build delete query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, deleteQuery, deleteArgs...); err != nil {
+			return fmt.Errorf("failed to clear failed logins: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Unlock removes an active lockout by token from the unlock email
+// Returns ErrInvalidToken if token is unknown or the lockout already ended
+func (r *Repository) Unlock(ctx context.Context, tokenHash string) error {
+	query, args, err := psql.
+		Delete("login_lockouts").
+		Where(sq.Eq{"unlock_token_hash": tokenHash}).
+		Where(sq.Gt{"locked_until": time.Now().UTC()}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrInvalidToken
+	}
+
+	return nil
+}
+
+// DeleteExpired removes attempts older than before and ended lockouts
+func (r *Repository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	var deleted int64
+
+	for _, q := range []sq.DeleteBuilder{
+		psql.Delete("failed_logins").Where(sq.Lt{"created_at": before}),
+		psql.Delete("login_lockouts").Where(sq.Lt{"locked_until": time.Now().UTC()}),
+	} {
+		query, args, err := q.ToSql()
+		if err != nil {
+			return deleted, fmt.Errorf("failed to build delete query: %w", err)
+		}
+
+		tag, err := r.db.Exec(ctx, query, args...)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to delete expired login data: %w", err)
+		}
+		deleted += tag.RowsAffected()
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,203 @@
+package loginguard
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// ErrInvalidToken - unlock link is unknown, already used or the lockout has ended
+var ErrInvalidToken = errors.New("invalid or expired unlock token")
+
+// Decision is the result of Check
+type Decision struct {
+	Allowed    bool
+	RetryAfter time.Duration // how long to wait when not allowed
+}
+
+// Lockout is returned by RecordFailure when the account has just been locked
+// Token goes to the unlock email
+type Lockout struct {
+	Email       string
+	Token       string
+	LockedUntil time.Time
+}
+
+// Service protects password login from brute force
+// WHY: In-memory limits reset on every deploy and are per process;
+// attempts must survive restarts and be shared by all instances
+// HOW: Failed attempts are stored in Postgres and counted per account
+// (normalized email) and per IP within a sliding window:
+// - account: exponential delay after DelayAfter failures, lockout after LockoutThreshold
+// - IP: blocked for the window after IPMaxFailures (password spraying across accounts)
+//
+// Accounts are keyed by the typed email, existing or not, so responses
+// for unknown accounts are indistinguishable from real ones
+type Service struct {
+	repo *Repository
+	cfg  config.LoginGuardConfig
+}
+
+// NewService creates new login guard service
+func NewService(db *pgxpool.Pool, cfg config.LoginGuardConfig) *Service {
+	return &Service{
+		repo: NewRepository(db),
+		cfg:  cfg,
+	}
+}
+
+// Check decides whether a login attempt may proceed to password verification
+func (s *Service) Check(ctx context.Context, email, ip string) (Decision, error) {
+	email = normalizeEmail(email)
+	now := time.Now().UTC()
+	since := now.Add(-s.cfg.Window)
+
+	lockedUntil, err := s.repo.GetLockout(ctx, email)
+	if err != nil {
+		return Decision{}, err
+	}
+	if lockedUntil != nil {
+		return Decision{RetryAfter: lockedUntil.Sub(now)}, nil
+	}
+
+	if s.cfg.IPMaxFailures > 0 {
+		ipFailures, _, err := s.repo.CountFailures(ctx, "ip_address", ip, since)
+		if err != nil {
+			return Decision{}, err
+		}
+		if ipFailures >= s.cfg.IPMaxFailures {
+			return Decision{RetryAfter: s.cfg.Window}, nil
+		}
+	}
+
+	failures, last, err := s.repo.CountFailures(ctx, "email", email, since)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	if last != nil {
+		if wait := last.Add(s.delay(failures)).Sub(now); wait > 0 {
+			return Decision{RetryAfter: wait}, nil
+		}
+	}
+
+	return Decision{Allowed: true}, nil
+}
+
+// RecordFailure stores a failed attempt and locks the account when the threshold is reached
+// Returns non-nil Lockout only at the moment the account gets locked
+func (s *Service) RecordFailure(ctx context.Context, email, ip string) (*Lockout, error) {
+	email = normalizeEmail(email)
+
+	if err := s.repo.RecordFailure(ctx, email, ip); err != nil {
+		return nil, err
+	}
+
+	if s.cfg.LockoutThreshold <= 0 {
+		return nil, nil
+	}
+
+	since := time.Now().UTC().Add(-s.cfg.Window)
+	failures, _, err := s.repo.CountFailures(ctx, "email", email, since)
+	if err != nil {
+		return nil, err
+	}
+	if failures < s.cfg.LockoutThreshold {
+		return nil, nil
+	}
+
+	token := generateToken()
+	lockedUntil := time.Now().UTC().Add(s.cfg.LockoutDuration)
+
+	if err := s.repo.Lock(ctx, email, hashToken(token), lockedUntil); err != nil {
+		return nil, err
+	}
+
+	return &Lockout{
+		Email:       email,
+		Token:       token,
+		LockedUntil: lockedUntil,
+	}, nil
+}
+
+// RecordSuccess resets account failures after a correct password
+func (s *Service) RecordSuccess(ctx context.Context, email string) error {
+	return s.repo.ClearFailures(ctx, normalizeEmail(email))
+}
+
+// Unlock lifts a lockout by token from the unlock email
+func (s *Service) Unlock(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrInvalidToken
+	}
+	return s.repo.Unlock(ctx, hashToken(token))
+}
+
+// RunSweeper periodically deletes old attempts and ended lockouts until ctx is cancelled
+func (s *Service) RunSweeper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.repo.DeleteExpired(ctx, time.Now().UTC().Add(-s.cfg.Window))
+			if err != nil {
+				slog.Error("Failed to sweep login attempts", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.Info("Login attempts swept", "count", deleted)
+			}
+		}
+	}
+}
+
+// delay returns required pause after the latest failure
+// Zero until DelayAfter failures, then DelayBase doubling up to DelayMax
+func (s *Service) delay(failures int) time.Duration {
+	if failures < s.cfg.DelayAfter || s.cfg.DelayBase <= 0 {
+		return 0
+	}
+
+	d := s.cfg.DelayBase
+	for i := s.cfg.DelayAfter; i < failures; i++ {
+		d *= 2
+		if d >= s.cfg.DelayMax {
+			return s.cfg.DelayMax
+		}
+	}
+
+	return min(d, s.cfg.DelayMax)
+}
+
+// normalizeEmail makes "User@Mail.com " and "user@mail.com" the same account key
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// generateToken creates unlock link token (same scheme as email tokens in user.Repository)
+func generateToken() string {
+	hash := sha256.Sum256([]byte(rand.Text()))
+	return hex.EncodeToString(hash[:])
+}
+
+// hashToken hashes unlock token for storage
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -20,7 +20,7 @@ func Auth(sessionService *session.Service) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Try to get session cookie
-			cookie, err := r.Cookie("session_id")
+			cookie, err := r.Cookie(session.CookieName)
 			if err != nil {
 				// No cookie - continue as anonymous user
 				next.ServeHTTP(w, r)
@@ -31,35 +31,38 @@ func Auth(sessionService *session.Service) func(http.Handler) http.Handler {
 			sessionID, err := uuid.Parse(cookie.Value)
 			if err != nil {
 				// Invalid UUID - clear cookie and continue as anonymous
-				clearSessionCookie(w)
+				session.ClearCookie(w)
 				next.ServeHTTP(w, r)
 				return
 			}
 
 			// Get user by session ID
-			u, err := sessionService.GetUserBySessionID(r.Context(), sessionID)
+			u, sess, err := sessionService.GetUserBySessionID(r.Context(), sessionID)
 			if err != nil {
 				// Invalid session - clear cookie and continue as anonymous
 				slog.Debug("Invalid session", "session_id", sessionID, "error", err)
-				clearSessionCookie(w)
+				session.ClearCookie(w)
 				next.ServeHTTP(w, r)
 				return
 			}
 
-			// Add user to context
+			// Record activity (active devices page, idle timeout)
+			// Touch is throttled by session service, most requests don't write
+			touched, err := sessionService.Touch(r.Context(), sess)
+			if err != nil {
+				// Not critical - request is still authenticated
+				slog.Warn("Failed to touch session", "session_id", sessionID, "error", err)
+			}
+
+			// Sliding renewal: idle deadline moved, move cookie expiry with it
+			if touched {
+				http.SetCookie(w, sessionService.Cookie(sess))
+			}
+
+			// Add user and session to context
 			ctx := user.WithCtx(r.Context(), u)
+			ctx = session.WithCtx(ctx, sess)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
-
-// clearSessionCookie removes session cookie
-func clearSessionCookie(w http.ResponseWriter) {
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_id",
-		Value:    "",
-		Path:     "/",
-		MaxAge:   -1,
-		HttpOnly: true,
-	})
-}
@@ -3,21 +3,63 @@ package middleware
 import (
 	"html/template"
 	"net/http"
+	"strings"
 
 	"github.com/gorilla/csrf"
 	"github.com/udisondev/learn-go/pkg/config"
 )
 
+// CSRFHeader is the request header HTMX sends the token in (hx-headers on <body>)
+const CSRFHeader = "X-CSRF-Token"
+
+// apiPathPrefix - routes where bearer-authenticated requests skip CSRF check
+const apiPathPrefix = "/api/"
+
 // CSRF returns configured CSRF protection middleware
-func CSRF(cfg *config.CSRFConfig) func(http.Handler) http.Handler {
-	csrfMiddleware := csrf.Protect(
+// WHY: Session cookie is sent with cross-site form posts, so every
+// state-changing request must prove it came from our own page
+// HOW: gorilla/csrf double-submit token - cookie + form field or X-CSRF-Token header,
+// plus Origin/Referer checks; failure renders onFailure (403)
+//
+// Exemptions and adjustments before the check:
+// - Bearer requests to /api/ are skipped - browsers can't attach Authorization cross-site, the token authenticates, not the cookie
+// - CSP reports are skipped - browsers send them without token, they change nothing
+// - Without Secure (local HTTP) request is marked plaintext, otherwise Origin http://... never matches
+func CSRF(cfg *config.CSRFConfig, onFailure http.Handler) func(http.Handler) http.Handler {
+	protect := csrf.Protect(
 		[]byte(cfg.Secret),
 		csrf.Secure(cfg.Secure),
 		csrf.SameSite(csrf.SameSiteStrictMode),
 		csrf.Path("/"),
+		csrf.RequestHeader(CSRFHeader),
+		csrf.TrustedOrigins(cfg.TrustedOrigins),
+		csrf.ErrorHandler(onFailure),
 	)
 
-	return csrfMiddleware
+	return func(next http.Handler) http.Handler {
+		protected := protect(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if isBearerAPIRequest(r) || r.URL.Path == CSPReportPath {
+				r = csrf.UnsafeSkipCheck(r)
+			}
+			if !cfg.Secure {
+				r = csrf.PlaintextHTTPRequest(r)
+			}
+
+			protected.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isBearerAPIRequest reports whether request is an API call authenticated by access token
+func isBearerAPIRequest(r *http.Request) bool {
+	if !strings.HasPrefix(r.URL.Path, apiPathPrefix) {
+		return false
+	}
+
+	scheme, _, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	return ok && strings.EqualFold(scheme, "Bearer")
 }
 
 // CSRFToken returns the CSRF token for the current request
@@ -29,3 +71,8 @@ func CSRFToken(r *http.Request) string {
 func CSRFTemplateTag(r *http.Request) template.HTML {
 	return csrf.TemplateField(r)
 }
+
+// CSRFFailureReason returns why CSRF check failed (for logs in the failure handler)
+func CSRFFailureReason(r *http.Request) error {
+	return csrf.FailureReason(r)
+}
@@ -0,0 +1,153 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+const testCSRFSecret = "0123456789abcdef0123456789abcdef"
+
+// csrfServer serves GET /token (returns the token) and POST on any path (returns "ok"),
+// failures answer 403 "csrf failed"
+func csrfServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	onFailure := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "csrf failed", http.StatusForbidden)
+	})
+
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			io.WriteString(w, CSRFToken(r))
+			return
+		}
+		io.WriteString(w, "ok")
+	})
+
+	srv := httptest.NewServer(CSRF(&config.CSRFConfig{Secret: testCSRFSecret}, onFailure)(app))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// fetchToken loads the page and returns the token with the CSRF cookie
+func fetchToken(t *testing.T, srv *httptest.Server) (string, []*http.Cookie) {
+	t.Helper()
+
+	resp, err := http.Get(srv.URL + "/token")
+	if err != nil {
+		t.Fatalf("get token: %v", err)
+	}
+	defer resp.Body.Close()
+
+	token, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("read token: %v", err)
+	}
+	return string(token), resp.Cookies()
+}
+
+// post sends POST with cookies and the given form and headers, returns status code
+func post(t *testing.T, srv *httptest.Server, path string, cookies []*http.Cookie, form url.Values, header http.Header) int {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		t.Fatalf("new request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestCSRFFormPost(t *testing.T) {
+	srv := csrfServer(t)
+	token, cookies := fetchToken(t, srv)
+
+	tests := []struct {
+		name    string
+		cookies []*http.Cookie
+		form    url.Values
+		want    int
+	}{
+		{"with token", cookies, url.Values{"gorilla.csrf.Token": {token}}, http.StatusOK},
+		{"without token", cookies, url.Values{}, http.StatusForbidden},
+		{"wrong token", cookies, url.Values{"gorilla.csrf.Token": {"bogus"}}, http.StatusForbidden},
+		{"token without cookie", nil, url.Values{"gorilla.csrf.Token": {token}}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := post(t, srv, "/profile/tokens", tt.cookies, tt.form, nil); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCSRFHTMXHeader(t *testing.T) {
+	srv := csrfServer(t)
+	token, cookies := fetchToken(t, srv)
+
+	header := http.Header{"Hx-Request": {"true"}, CSRFHeader: {token}}
+	if got := post(t, srv, "/login", cookies, nil, header); got != http.StatusOK {
+		t.Errorf("hx-post with header: status = %d, want %d", got, http.StatusOK)
+	}
+
+	header = http.Header{"Hx-Request": {"true"}}
+	if got := post(t, srv, "/login", cookies, nil, header); got != http.StatusForbidden {
+		t.Errorf("hx-post without header: status = %d, want %d", got, http.StatusForbidden)
+	}
+}
+
+func TestCSRFCrossOrigin(t *testing.T) {
+	srv := csrfServer(t)
+	token, cookies := fetchToken(t, srv)
+
+	header := http.Header{"Origin": {"https://evil.example"}}
+	form := url.Values{"gorilla.csrf.Token": {token}}
+	if got := post(t, srv, "/login", cookies, form, header); got != http.StatusForbidden {
+		t.Errorf("foreign origin: status = %d, want %d", got, http.StatusForbidden)
+	}
+}
+
+func TestCSRFBearerAPIExempt(t *testing.T) {
+	srv := csrfServer(t)
+
+	tests := []struct {
+		name   string
+		path   string
+		header http.Header
+		want   int
+	}{
+		{"bearer api", "/api/progress", http.Header{"Authorization": {"Bearer lg_token"}}, http.StatusOK},
+		{"api without bearer", "/api/progress", nil, http.StatusForbidden},
+		{"basic auth api", "/api/progress", http.Header{"Authorization": {"Basic dXNlcjpwYXNz"}}, http.StatusForbidden},
+		{"bearer outside api", "/profile/tokens", http.Header{"Authorization": {"Bearer lg_token"}}, http.StatusForbidden},
+		{"csp report", CSPReportPath, nil, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := post(t, srv, tt.path, nil, nil, tt.header); got != tt.want {
+				t.Errorf("status = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
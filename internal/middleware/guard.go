@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/udisondev/learn-go/internal/impersonation"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// Guards block requests that don't satisfy access rules
+// WHY: Auth() only loads the user, every protected handler would repeat
+// the same "not logged in / not verified / plan too low" checks
+// HOW: Small composable middlewares on top of user.FromCtx, mounted on route groups:
+//
+//	r.Group(func(r chi.Router) {
+//		r.Use(mw.RequireAuth, mw.RequireVerified)
+//		r.With(mw.RequireSubPlan(user.SubPlanBasic)).Get("/course/{slug}", h.GetLesson)
+//	})
+//
+// Denied requests are redirected: HTMX requests get HX-Redirect (a plain 3xx
+// would be followed by XHR and swapped into the page), others get 303
+
+// RequireAuth blocks anonymous requests and sends them to the login page
+// The current page is passed as return_to, so the user comes back after login
+func RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := user.FromCtx(r.Context()); !ok {
+			redirect(w, r, withReturnTo("/login", r))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireVerified blocks users with unconfirmed email
+// Includes RequireAuth check
+func RequireVerified(next http.Handler) http.Handler {
+	return RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u, _ := user.FromCtx(r.Context())
+		if !u.IsVerified {
+			redirect(w, r, "/verify-email")
+			return
+		}
+		next.ServeHTTP(w, r)
+	}))
+}
+
+// RequireSubPlan blocks users whose plan is lower than min
+// Shows the upgrade prompt with the required plan instead of a bare 403
+// Includes RequireAuth check
+func RequireSubPlan(min user.SubPlan) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, _ := user.FromCtx(r.Context())
+			if !u.HasSubPlan(min) {
+				redirect(w, r, "/upgrade?plan="+url.QueryEscape(min.String()))
+				return
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// RequireRole blocks users without any of the given roles (admin, author areas)
+// Responds 403 - there is nothing the user can do to get access
+// Includes RequireAuth check
+func RequireRole(roles ...user.Role) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			u, _ := user.FromCtx(r.Context())
+			if !u.HasRole(roles...) {
+				slog.Warn("Access denied: missing role", "user_id", u.ID, "path", r.URL.Path, "roles", roles)
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// DenyImpersonated blocks the request while an admin impersonates the user
+// WHY: Impersonation is for looking, not for taking over the account -
+// password, email, 2FA, tokens and sessions are changed only by the owner
+// HOW: onDenied writes the response (HTMX flash message), like CSRF failures
+func DenyImpersonated(onDenied http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if act, ok := impersonation.FromCtx(r.Context()); ok {
+				slog.Warn("Sensitive action blocked during impersonation", "admin_id", act.ActorID, "user_id", act.TargetID, "path", r.URL.Path)
+				onDenied.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SafeReturnTo validates return_to value before redirecting to it
+// WHY: return_to comes from the query string - without checks it is an
+// open redirect (/login?return_to=https://evil.example)
+// HOW: Only same-site absolute paths are accepted, anything else becomes "/"
+func SafeReturnTo(raw string) string {
+	if raw == "" || !strings.HasPrefix(raw, "/") {
+		return "/"
+	}
+
+	// "//evil.example" and "/\evil.example" are protocol-relative URLs in browsers
+	if strings.HasPrefix(raw, "//") || strings.HasPrefix(raw, "/\\") {
+		return "/"
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return "/"
+	}
+
+	return u.RequestURI()
+}
+
+// IsHTMX reports whether request was made by HTMX
+func IsHTMX(r *http.Request) bool {
+	return r.Header.Get("HX-Request") == "true"
+}
+
+// redirect sends the client to target the way it can follow it
+func redirect(w http.ResponseWriter, r *http.Request, target string) {
+	if IsHTMX(r) {
+		w.Header().Set("HX-Redirect", target)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// withReturnTo appends the page the user was on as return_to
+//
+// Which page:
+// - HTMX request: the page in the address bar (HX-Current-URL), not the fragment endpoint
+// - GET request: the requested URL itself
+// - other methods: nothing - a form POST can't be replayed by redirect
+func withReturnTo(target string, r *http.Request) string {
+	var current string
+
+	switch {
+	case IsHTMX(r):
+		if u, err := url.Parse(r.Header.Get("HX-Current-URL")); err == nil {
+			current = u.RequestURI()
+		}
+	case r.Method == http.MethodGet:
+		current = r.URL.RequestURI()
+	}
+
+	if current == "" || current == "/" || SafeReturnTo(current) != current {
+		return target
+	}
+
+	return target + "?return_to=" + url.QueryEscape(current)
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/impersonation"
+	"github.com/udisondev/learn-go/internal/session"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// Impersonate swaps the user in context when an admin impersonates someone
+// WHY: Handlers and guards keep using user.FromCtx and see exactly what
+// the impersonated user would see
+// HOW: Mount after Auth. For admins only (others can't start one, so no extra
+// query for them) look up running impersonation of the session, then put the
+// target into user.WithCtx and the real actor into impersonation.WithCtx
+//
+// Expired impersonation is ended on the first request after expiry,
+// the stop is recorded in the audit log and the admin continues as themselves
+func Impersonate(svc *impersonation.Service, auditLog *audit.Service) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actor, ok := user.FromCtx(r.Context())
+			if !ok || !actor.IsAdmin() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			sess, ok := session.FromCtx(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			imp, target, err := svc.Resolve(r.Context(), sess.ID)
+			switch {
+			case errors.Is(err, impersonation.ErrNotActive):
+				next.ServeHTTP(w, r)
+				return
+			case errors.Is(err, impersonation.ErrExpired):
+				slog.Info("Impersonation expired", "admin_id", imp.ActorID, "user_id", imp.TargetID)
+				auditLog.Record(r, audit.EventTypeImpersonationStopped, &imp.TargetID, map[string]string{
+					"admin_id": strconv.FormatInt(imp.ActorID, 10),
+					"reason":   "expired",
+				})
+				next.ServeHTTP(w, r)
+				return
+			case err != nil:
+				// Fail closed: don't let the admin act as themselves on a page
+				// they expect to see as another user
+				slog.Error("Failed to resolve impersonation", "error", err, "admin_id", actor.ID)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			ctx := user.WithCtx(r.Context(), target)
+			ctx = impersonation.WithCtx(ctx, &impersonation.Active{
+				Impersonation: *imp,
+				Actor:         actor,
+				Target:        target,
+			})
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
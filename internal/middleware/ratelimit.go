@@ -1,135 +1,93 @@
 package middleware
 
 import (
+	"log/slog"
+	"math"
 	"net/http"
-	"sync"
+	"strconv"
 	"time"
+
+	"github.com/udisondev/learn-go/internal/clientip"
+	"github.com/udisondev/learn-go/internal/ratelimit"
+	"github.com/udisondev/learn-go/internal/user"
 )
 
-// RateLimiter implements in-memory rate limiting
-type RateLimiter struct {
-	mu       sync.RWMutex
-	clients  map[string]*client
-	limit    int           // max requests
-	window   time.Duration // time window
-	cleanupInterval time.Duration
-}
+// KeyFunc extracts the rate limit key of the request (IP, user ID)
+// Empty key skips the limit
+type KeyFunc func(r *http.Request) string
 
-type client struct {
-	requests []time.Time
-	mu       sync.Mutex
+// ByIP keys requests by client IP
+func ByIP(r *http.Request) string {
+	return clientip.FromRequest(r)
 }
 
-// NewRateLimiter creates a new rate limiter
-// limit: maximum number of requests
-// window: time window (e.g., 15 minutes)
-func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
-	rl := &RateLimiter{
-		clients: make(map[string]*client),
-		limit:   limit,
-		window:  window,
-		cleanupInterval: 5 * time.Minute,
+// ByUser keys requests by logged in user, anonymous ones by IP
+func ByUser(r *http.Request) string {
+	if u, ok := user.FromCtx(r.Context()); ok {
+		return "user:" + strconv.FormatInt(u.ID, 10)
 	}
-
-	// Start cleanup goroutine to remove old entries
-	go rl.cleanup()
-
-	return rl
-}
-
-// Middleware returns HTTP middleware that limits requests by IP
-func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := getIP(r)
-
-		if !rl.allow(ip) {
-			http.Error(w, "Too many requests. Please try again later.", http.StatusTooManyRequests)
-			return
-		}
-
-		next.ServeHTTP(w, r)
-	})
-}
-
-// AllowEmail checks if email-based rate limit allows the request
-func (rl *RateLimiter) AllowEmail(email string) bool {
-	key := "email:" + email
-	return rl.allow(key)
+	return "ip:" + clientip.FromRequest(r)
 }
 
-// allow checks if the request is allowed
-func (rl *RateLimiter) allow(key string) bool {
-	now := time.Now()
+// RateLimit limits requests by the named policy
+// WHY: Login, registration and submissions are cheap to send and expensive
+// (or dangerous) to serve - brute force, spam accounts, sandbox load
+// HOW: One request is taken from the bucket of key(r) per call; every response
+// carries RateLimit-* headers, a refused one also gets Retry-After and is
+// handled by onLimited (429) with the decision in ratelimit.FromCtx
+//
+// Store errors let the request through - the limiter must not take the site
+// down with the database it protects
+func RateLimit(limiter *ratelimit.Limiter, policyName string, key KeyFunc, onLimited http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k := key(r)
+			if k == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
 
-	rl.mu.Lock()
-	c, exists := rl.clients[key]
-	if !exists {
-		c = &client{
-			requests: []time.Time{},
-		}
-		rl.clients[key] = c
-	}
-	rl.mu.Unlock()
+			res, err := limiter.Allow(r.Context(), policyName, k)
+			if err != nil {
+				slog.Error("Rate limit check failed", "error", err, "policy", policyName)
+				next.ServeHTTP(w, r)
+				return
+			}
 
-	c.mu.Lock()
-	defer c.mu.Unlock()
+			SetRateLimitHeaders(w, res)
 
-	// Remove requests outside the time window
-	cutoff := now.Add(-rl.window)
-	validRequests := []time.Time{}
-	for _, t := range c.requests {
-		if t.After(cutoff) {
-			validRequests = append(validRequests, t)
-		}
-	}
-	c.requests = validRequests
+			if !res.Allowed {
+				slog.Warn("Rate limit exceeded",
+					"policy", policyName,
+					"path", r.URL.Path,
+					"ip", clientip.FromRequest(r),
+				)
+				onLimited.ServeHTTP(w, r.WithContext(ratelimit.WithCtx(r.Context(), res)))
+				return
+			}
 
-	// Check if limit exceeded
-	if len(c.requests) >= rl.limit {
-		return false
+			next.ServeHTTP(w, r)
+		})
 	}
-
-	// Add current request
-	c.requests = append(c.requests, now)
-	return true
 }
 
-// cleanup periodically removes old clients
-func (rl *RateLimiter) cleanup() {
-	ticker := time.NewTicker(rl.cleanupInterval)
-	defer ticker.Stop()
-
-	for range ticker.C {
-		rl.mu.Lock()
-		now := time.Now()
-		cutoff := now.Add(-rl.window)
-
-		for key, c := range rl.clients {
-			c.mu.Lock()
-			// Remove if no requests in the window
-			if len(c.requests) == 0 || c.requests[len(c.requests)-1].Before(cutoff) {
-				delete(rl.clients, key)
-			}
-			c.mu.Unlock()
-		}
-		rl.mu.Unlock()
+// SetRateLimitHeaders writes RateLimit-* headers (IETF httpapi ratelimit-headers draft)
+// and Retry-After for refused requests
+// Used by RateLimit and by handlers that check limits themselves
+func SetRateLimitHeaders(w http.ResponseWriter, res ratelimit.Result) {
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.ResetAfter)))
+	h.Set("RateLimit-Policy", strconv.Itoa(res.Limit)+";w="+strconv.Itoa(ceilSeconds(res.Window)))
+
+	if !res.Allowed {
+		h.Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
 	}
 }
 
-// getIP extracts IP address from request
-func getIP(r *http.Request) string {
-	// Check X-Forwarded-For header (for proxies)
-	forwarded := r.Header.Get("X-Forwarded-For")
-	if forwarded != "" {
-		return forwarded
-	}
-
-	// Check X-Real-IP header
-	realIP := r.Header.Get("X-Real-IP")
-	if realIP != "" {
-		return realIP
-	}
-
-	// Fallback to RemoteAddr
-	return r.RemoteAddr
+// ceilSeconds rounds duration up to whole seconds - header values are integers
+// and rounding down would invite a retry that is still refused
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
 }
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// CSPReportPath is where browsers send Content-Security-Policy violation reports
+const CSPReportPath = "/csp-report"
+
+// Environments with relaxed headers (plain HTTP on localhost), any other APP_ENV is treated as production
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+)
+
+// cspNonceKey is a type-safe context key for the CSP nonce of the request
+type cspNonceKey struct{}
+
+// SecurityHeaders sets CSP and other browser security headers
+// WHY: Pages render user-controlled content (names, future lesson comments) -
+// CSP is the last line of defense if something slips through html/template escaping
+// HOW: Generate a nonce per request, allow only scripts carrying it (plus CDN and 'self'),
+// templates read the nonce with CSPNonce via templates.Page
+//
+// Header set depends on APP_ENV:
+// - production: full set, HSTS, upgrade-insecure-requests
+// - development/test: same CSP and headers, without HSTS and upgrade-insecure-requests (plain HTTP on localhost)
+func SecurityHeaders(env string, cfg config.SecurityConfig) func(http.Handler) http.Handler {
+	production := env != EnvDevelopment && env != EnvTest
+
+	cspHeader := "Content-Security-Policy"
+	if cfg.CSPReportOnly {
+		cspHeader = "Content-Security-Policy-Report-Only"
+	}
+
+	hsts := ""
+	if production && cfg.HSTSMaxAge > 0 {
+		hsts = "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds())) + "; includeSubDomains"
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			nonce := newNonce()
+
+			h := w.Header()
+			h.Set(cspHeader, contentSecurityPolicy(nonce, production))
+			h.Set("Reporting-Endpoints", `csp-endpoint="`+CSPReportPath+`"`)
+			h.Set("X-Content-Type-Options", "nosniff")
+			h.Set("X-Frame-Options", "DENY")
+			h.Set("Referrer-Policy", "strict-origin-when-cross-origin")
+			h.Set("Permissions-Policy", "camera=(), microphone=(), geolocation=(), payment=(), usb=()")
+			h.Set("Cross-Origin-Opener-Policy", "same-origin")
+			if hsts != "" {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+
+			ctx := context.WithValue(r.Context(), cspNonceKey{}, nonce)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// contentSecurityPolicy builds policy for one request
+//
+// Non-obvious sources:
+// - 'unsafe-eval' in script-src: Alpine evaluates x-data/@click expressions with new Function
+// - no CDN host in script-src: unpkg.com would allow any package on it, our script tags carry the nonce
+// - 'unsafe-inline' in style-src: style="" attributes and the <style> HTMX injects for indicators
+// - img-src https: - avatars from OAuth providers; data: - 2FA QR code
+// - frame-src youtube - video on the landing page
+func contentSecurityPolicy(nonce string, production bool) string {
+	directives := []string{
+		"default-src 'self'",
+		"script-src 'self' 'nonce-" + nonce + "' 'unsafe-eval'",
+		"style-src 'self' 'unsafe-inline'",
+		"img-src 'self' data: https:",
+		"font-src 'self'",
+		"connect-src 'self'",
+		"frame-src https://www.youtube.com",
+		"object-src 'none'",
+		"base-uri 'self'",
+		"form-action 'self'",
+		"frame-ancestors 'none'",
+		"report-uri " + CSPReportPath,
+		"report-to csp-endpoint",
+	}
+	if production {
+		directives = append(directives, "upgrade-insecure-requests")
+	}
+
+	return strings.Join(directives, "; ")
+}
+
+// newNonce returns 128 random bits, base64url encoded (no "+" for html/template to escape)
+func newNonce() string {
+	b := make([]byte, 16)
+	rand.Read(b) // never fails since Go 1.24
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// CSPNonce returns the CSP nonce for the current request
+// Empty if SecurityHeaders is not mounted (no CSP - nothing to match)
+func CSPNonce(r *http.Request) string {
+	nonce, _ := r.Context().Value(cspNonceKey{}).(string)
+	return nonce
+}
@@ -0,0 +1,131 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/udisondev/learn-go/internal/user"
+	"github.com/udisondev/learn-go/pkg/config"
+	"golang.org/x/oauth2"
+)
+
+// GitHub implements Provider for GitHub OAuth apps
+// WHY: GitHub is not an OIDC issuer - no id_token, profile and emails
+// come from the REST API
+// HOW: Authorization code + PKCE, then GET /user and GET /user/emails
+type GitHub struct {
+	oauth  *oauth2.Config
+	apiURL string
+}
+
+// NewGitHub creates GitHub provider
+// Endpoints come from config so tests can point it at a fake IdP
+func NewGitHub(cfg config.GitHubOAuthConfig, redirectURL string) *GitHub {
+	return &GitHub{
+		oauth: &oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  redirectURL,
+			Scopes:       []string{"read:user", "user:email"},
+			Endpoint: oauth2.Endpoint{
+				AuthURL:   cfg.AuthURL,
+				TokenURL:  cfg.TokenURL,
+				AuthStyle: oauth2.AuthStyleInParams,
+			},
+		},
+		apiURL: strings.TrimRight(cfg.APIURL, "/"),
+	}
+}
+
+func (g *GitHub) Name() string  { return "github" }
+func (g *GitHub) Label() string { return "GitHub" }
+
+func (g *GitHub) AuthCodeURL(state, verifier string) string {
+	return g.oauth.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+}
+
+// githubUser - subset of GET /user response
+type githubUser struct {
+	ID        int64  `json:"id"`
+	Login     string `json:"login"`
+	Name      string `json:"name"`
+	AvatarURL string `json:"avatar_url"`
+}
+
+// githubEmail - item of GET /user/emails response
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+func (g *GitHub) Exchange(ctx context.Context, code, verifier string) (*user.ExternalIdentity, error) {
+	token, err := g.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("exchange code: %w", err)
+	}
+
+	client := g.oauth.Client(ctx, token)
+
+	var profile githubUser
+	if err := g.get(ctx, client, "/user", &profile); err != nil {
+		return nil, err
+	}
+
+	// /user returns only the public email (often empty) without verification flag
+	var emails []githubEmail
+	if err := g.get(ctx, client, "/user/emails", &emails); err != nil {
+		return nil, err
+	}
+
+	identity := &user.ExternalIdentity{
+		Provider: g.Name(),
+		Subject:  strconv.FormatInt(profile.ID, 10),
+		Name:     profile.Name,
+	}
+	if identity.Name == "" {
+		identity.Name = profile.Login
+	}
+	if profile.AvatarURL != "" {
+		identity.AvatarURL = &profile.AvatarURL
+	}
+
+	for _, e := range emails {
+		if e.Primary {
+			identity.Email = e.Email
+			identity.EmailVerified = e.Verified
+			break
+		}
+	}
+
+	return identity, nil
+}
+
+// get calls GitHub REST API and decodes JSON response into v
+func (g *GitHub) get(ctx context.Context, client *http.Client, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, g.apiURL+path, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("github %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("github %s: unexpected status %d", path, resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("github %s: decode: %w", path, err)
+	}
+
+	return nil
+}
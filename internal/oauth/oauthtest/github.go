@@ -0,0 +1,219 @@
+// Package oauthtest provides a fake GitHub for tests of the OAuth login flow
+package oauthtest
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// Client credentials the fake accepts
+const (
+	ClientID     = "test-client"
+	ClientSecret = "test-secret"
+)
+
+// User - GitHub account that signs in on the consent page
+type User struct {
+	ID            int64
+	Login         string
+	Name          string
+	Email         string // primary email
+	EmailVerified bool
+}
+
+// GitHub serves the endpoints of config.GitHubOAuthConfig:
+// - /login/oauth/authorize consents at once as the current user and redirects back with a code
+// - /login/oauth/access_token trades a code for a token, checking the PKCE verifier
+// - /api/user and /api/user/emails return the token's user
+type GitHub struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	user      User
+	grants    map[string]grant // by code, single-use
+	tokens    map[string]User
+	verifiers []string
+}
+
+// grant - issued code waiting for the token request
+type grant struct {
+	user        User
+	challenge   string
+	redirectURI string
+}
+
+// NewGitHub starts fake GitHub, closed when the test ends
+func NewGitHub(t testing.TB) *GitHub {
+	t.Helper()
+
+	g := &GitHub{
+		grants: make(map[string]grant),
+		tokens: make(map[string]User),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /login/oauth/authorize", g.authorize)
+	mux.HandleFunc("POST /login/oauth/access_token", g.accessToken)
+	mux.HandleFunc("GET /api/user", g.profile)
+	mux.HandleFunc("GET /api/user/emails", g.emails)
+
+	g.Server = httptest.NewServer(mux)
+	t.Cleanup(g.Close)
+	return g
+}
+
+// Config points the GitHub provider at the fake
+func (g *GitHub) Config() config.GitHubOAuthConfig {
+	return config.GitHubOAuthConfig{
+		ClientID:     ClientID,
+		ClientSecret: ClientSecret,
+		AuthURL:      g.URL + "/login/oauth/authorize",
+		TokenURL:     g.URL + "/login/oauth/access_token",
+		APIURL:       g.URL + "/api",
+	}
+}
+
+// SignIn sets the account that consents on the next authorize requests
+func (g *GitHub) SignIn(u User) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.user = u
+}
+
+// Verifiers returns code_verifier of every token request, accepted or not
+func (g *GitHub) Verifiers() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.verifiers...)
+}
+
+func (g *GitHub) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != ClientID {
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		http.Error(w, "PKCE S256 challenge required", http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirect.Host == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := rand.Text()
+	g.mu.Lock()
+	g.grants[code] = grant{user: g.user, challenge: q.Get("code_challenge"), redirectURI: redirect.String()}
+	g.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (g *GitHub) accessToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, "invalid_request")
+		return
+	}
+	if r.PostForm.Get("client_id") != ClientID || r.PostForm.Get("client_secret") != ClientSecret {
+		tokenError(w, "invalid_client")
+		return
+	}
+
+	code := r.PostForm.Get("code")
+	verifier := r.PostForm.Get("code_verifier")
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.verifiers = append(g.verifiers, verifier)
+	gr, ok := g.grants[code]
+	delete(g.grants, code)
+
+	sum := sha256.Sum256([]byte(verifier))
+	switch {
+	case !ok, r.PostForm.Get("redirect_uri") != gr.redirectURI:
+		tokenError(w, "invalid_grant")
+		return
+	case base64.RawURLEncoding.EncodeToString(sum[:]) != gr.challenge:
+		tokenError(w, "invalid_grant")
+		return
+	}
+
+	token := rand.Text()
+	g.tokens[token] = gr.user
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": token,
+		"token_type":   "bearer",
+		"scope":        "read:user,user:email",
+	})
+}
+
+func (g *GitHub) profile(w http.ResponseWriter, r *http.Request) {
+	u, ok := g.authenticate(r)
+	if !ok {
+		http.Error(w, "Bad credentials", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"id":         u.ID,
+		"login":      u.Login,
+		"name":       u.Name,
+		"avatar_url": "",
+	})
+}
+
+func (g *GitHub) emails(w http.ResponseWriter, r *http.Request) {
+	u, ok := g.authenticate(r)
+	if !ok {
+		http.Error(w, "Bad credentials", http.StatusUnauthorized)
+		return
+	}
+
+	// A secondary address first - only the primary one may be used
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode([]map[string]any{
+		{"email": u.Login + "@users.noreply.github.com", "primary": false, "verified": true},
+		{"email": u.Email, "primary": true, "verified": u.EmailVerified},
+	})
+}
+
+// authenticate returns the user of the bearer token
+func (g *GitHub) authenticate(r *http.Request) (User, bool) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return User{}, false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	u, ok := g.tokens[token]
+	return u, ok
+}
+
+// tokenError answers a token request the way OAuth 2.0 servers do (RFC 6749 section 5.2)
+func tokenError(w http.ResponseWriter, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+}
@@ -0,0 +1,45 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+var (
+	// ErrUnknownProvider - provider is not configured (no client id) or doesn't exist
+	ErrUnknownProvider = errors.New("unknown oauth provider")
+
+	// ErrStateMismatch - callback state doesn't match the one stored in the browser
+	// Either the flow expired, was started in another browser, or it is a CSRF attempt
+	ErrStateMismatch = errors.New("oauth state mismatch")
+
+	// ErrAccessDenied - user cancelled the consent screen at the provider
+	ErrAccessDenied = errors.New("oauth access denied")
+)
+
+// Provider is an external identity provider
+// WHY: GitHub first, but Google/GitLab/any OIDC issuer must plug in
+// without touching handlers or user.Service
+// HOW: Provider only knows its endpoints and how to map its profile
+// to user.ExternalIdentity; state, PKCE and account linking live elsewhere
+type Provider interface {
+	// Name is the URL segment and user_identities.provider value ("github")
+	Name() string
+
+	// Label is shown on the login button ("GitHub")
+	Label() string
+
+	// AuthCodeURL returns the consent page URL with state and PKCE challenge
+	AuthCodeURL(state, verifier string) string
+
+	// Exchange trades the authorization code for a token and loads the profile
+	Exchange(ctx context.Context, code, verifier string) (*user.ExternalIdentity, error)
+}
+
+// ProviderInfo describes an enabled provider for templates
+type ProviderInfo struct {
+	Name  string
+	Label string
+}
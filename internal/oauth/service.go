@@ -0,0 +1,135 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/udisondev/learn-go/internal/user"
+	"github.com/udisondev/learn-go/pkg/config"
+	"golang.org/x/oauth2"
+)
+
+// stateCookieName - cookie holding state and PKCE verifier between redirect and callback
+const stateCookieName = "oauth_state"
+
+// Service runs the authorization code flow for configured providers
+// WHY: State and PKCE handling is the same for every provider and easy
+// to get subtly wrong - keep it in one place
+// HOW: state and code_verifier are generated per login attempt and kept in a
+// short-lived HttpOnly cookie scoped to the provider's callback path.
+// The callback must present the same state (CSRF protection) and the
+// token request includes the verifier (stolen codes are useless, PKCE)
+type Service struct {
+	providers    map[string]Provider
+	order        []string
+	stateTTL     time.Duration
+	secureCookie bool
+}
+
+// NewService creates oauth service with all providers that have client id configured
+func NewService(cfg config.OAuthConfig, baseURL string, secureCookie bool) *Service {
+	s := &Service{
+		providers:    make(map[string]Provider),
+		stateTTL:     cfg.StateTTL,
+		secureCookie: secureCookie,
+	}
+
+	baseURL = strings.TrimRight(baseURL, "/")
+
+	if cfg.GitHub.ClientID != "" {
+		s.register(NewGitHub(cfg.GitHub, baseURL+"/auth/github/callback"))
+	}
+
+	return s
+}
+
+func (s *Service) register(p Provider) {
+	s.providers[p.Name()] = p
+	s.order = append(s.order, p.Name())
+}
+
+// Providers returns enabled providers for login buttons
+func (s *Service) Providers() []ProviderInfo {
+	infos := make([]ProviderInfo, 0, len(s.order))
+	for _, name := range s.order {
+		p := s.providers[name]
+		infos = append(infos, ProviderInfo{Name: p.Name(), Label: p.Label()})
+	}
+	return infos
+}
+
+// Begin starts the flow: stores state cookie and returns provider consent URL
+func (s *Service) Begin(w http.ResponseWriter, name string) (string, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return "", ErrUnknownProvider
+	}
+
+	state := rand.Text()
+	verifier := oauth2.GenerateVerifier()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName,
+		Value:    state + "." + verifier,
+		Path:     callbackPath(name),
+		MaxAge:   int(s.stateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   s.secureCookie,
+		SameSite: http.SameSiteLaxMode, // provider redirects back with top-level GET
+	})
+
+	return p.AuthCodeURL(state, verifier), nil
+}
+
+// Complete handles provider callback: checks state, exchanges code, loads profile
+// State cookie is cleared in any case - every attempt is single-use
+func (s *Service) Complete(ctx context.Context, w http.ResponseWriter, r *http.Request, name string) (*user.ExternalIdentity, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     stateCookieName,
+		Value:    "",
+		Path:     callbackPath(name),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   s.secureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	query := r.URL.Query()
+	if query.Get("error") == "access_denied" {
+		return nil, ErrAccessDenied
+	}
+
+	cookie, err := r.Cookie(stateCookieName)
+	if err != nil {
+		return nil, ErrStateMismatch
+	}
+
+	state, verifier, ok := strings.Cut(cookie.Value, ".")
+	if !ok || state == "" || verifier == "" {
+		return nil, ErrStateMismatch
+	}
+
+	if subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+		return nil, ErrStateMismatch
+	}
+
+	code := query.Get("code")
+	if code == "" {
+		return nil, ErrStateMismatch
+	}
+
+	return p.Exchange(ctx, code, verifier)
+}
+
+func callbackPath(name string) string {
+	return "/auth/" + name + "/callback"
+}
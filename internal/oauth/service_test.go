@@ -0,0 +1,227 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/udisondev/learn-go/internal/oauth/oauthtest"
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+var octocat = oauthtest.User{
+	ID:            42,
+	Login:         "octocat",
+	Name:          "The Octocat",
+	Email:         "octocat@example.com",
+	EmailVerified: true,
+}
+
+func newTestService(t *testing.T) (*Service, *oauthtest.GitHub) {
+	t.Helper()
+
+	gh := oauthtest.NewGitHub(t)
+	gh.SignIn(octocat)
+
+	cfg := config.OAuthConfig{StateTTL: 10 * time.Minute, GitHub: gh.Config()}
+	return NewService(cfg, "http://learn-go.test", false), gh
+}
+
+// startLogin begins the flow and consents at the fake GitHub,
+// returns the callback request as the browser would send it
+func startLogin(t *testing.T, s *Service) *http.Request {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	authURL, err := s.Begin(rec, "github")
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Get(authURL)
+	if err != nil {
+		t.Fatalf("authorize: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusFound {
+		t.Fatalf("authorize: status %d", resp.StatusCode)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, resp.Header.Get("Location"), nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+	return req
+}
+
+// setQuery replaces a callback query parameter, empty value removes it
+func setQuery(r *http.Request, key, value string) {
+	q := r.URL.Query()
+	if value == "" {
+		q.Del(key)
+	} else {
+		q.Set(key, value)
+	}
+	r.URL.RawQuery = q.Encode()
+}
+
+func TestCompleteReturnsIdentity(t *testing.T) {
+	s, gh := newTestService(t)
+
+	identity, err := s.Complete(t.Context(), httptest.NewRecorder(), startLogin(t, s), "github")
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	if identity.Provider != "github" || identity.Subject != "42" {
+		t.Errorf("identity = %s/%s, want github/42", identity.Provider, identity.Subject)
+	}
+	if identity.Email != octocat.Email || !identity.EmailVerified {
+		t.Errorf("email = %q verified %v, want the verified primary %q", identity.Email, identity.EmailVerified, octocat.Email)
+	}
+	if identity.Name != octocat.Name {
+		t.Errorf("name = %q, want %q", identity.Name, octocat.Name)
+	}
+
+	// Without a display name the login is used
+	gh.SignIn(oauthtest.User{ID: 7, Login: "noname", Email: "noname@example.com", EmailVerified: true})
+	identity, err = s.Complete(t.Context(), httptest.NewRecorder(), startLogin(t, s), "github")
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if identity.Name != "noname" {
+		t.Errorf("name = %q, want login %q", identity.Name, "noname")
+	}
+}
+
+func TestCompleteUnverifiedEmail(t *testing.T) {
+	s, gh := newTestService(t)
+	gh.SignIn(oauthtest.User{ID: 43, Login: "unverified", Email: "unverified@example.com"})
+
+	identity, err := s.Complete(t.Context(), httptest.NewRecorder(), startLogin(t, s), "github")
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if identity.EmailVerified {
+		t.Error("unverified primary email reported as verified")
+	}
+}
+
+func TestCompleteSendsPKCEVerifier(t *testing.T) {
+	s, gh := newTestService(t)
+	req := startLogin(t, s)
+
+	cookie, err := req.Cookie(stateCookieName)
+	if err != nil {
+		t.Fatalf("state cookie: %v", err)
+	}
+	_, verifier, _ := strings.Cut(cookie.Value, ".")
+
+	// The fake rejects a verifier that doesn't match the S256 challenge
+	if _, err := s.Complete(t.Context(), httptest.NewRecorder(), req, "github"); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	got := gh.Verifiers()
+	if len(got) != 1 || got[0] != verifier {
+		t.Errorf("token requests sent verifiers %q, want [%q]", got, verifier)
+	}
+}
+
+func TestCompleteRejectsStateMismatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, s *Service, r *http.Request)
+	}{
+		{"wrong state", func(t *testing.T, s *Service, r *http.Request) {
+			setQuery(r, "state", "forged")
+		}},
+		{"missing state", func(t *testing.T, s *Service, r *http.Request) {
+			setQuery(r, "state", "")
+		}},
+		{"missing code", func(t *testing.T, s *Service, r *http.Request) {
+			setQuery(r, "code", "")
+		}},
+		{"no state cookie", func(t *testing.T, s *Service, r *http.Request) {
+			r.Header.Del("Cookie")
+		}},
+		{"cookie from another attempt", func(t *testing.T, s *Service, r *http.Request) {
+			other := startLogin(t, s)
+			r.Header.Set("Cookie", other.Header.Get("Cookie"))
+		}},
+		{"cookie without verifier", func(t *testing.T, s *Service, r *http.Request) {
+			state := r.URL.Query().Get("state")
+			r.Header.Set("Cookie", stateCookieName+"="+state)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, gh := newTestService(t)
+			req := startLogin(t, s)
+			tt.tamper(t, s, req)
+
+			_, err := s.Complete(t.Context(), httptest.NewRecorder(), req, "github")
+			if !errors.Is(err, ErrStateMismatch) {
+				t.Errorf("err = %v, want ErrStateMismatch", err)
+			}
+			if n := len(gh.Verifiers()); n != 0 {
+				t.Errorf("code exchanged %d times despite the mismatch", n)
+			}
+		})
+	}
+}
+
+func TestCompleteAccessDenied(t *testing.T) {
+	s, _ := newTestService(t)
+	req := startLogin(t, s)
+	setQuery(req, "code", "")
+	setQuery(req, "error", "access_denied")
+
+	if _, err := s.Complete(t.Context(), httptest.NewRecorder(), req, "github"); !errors.Is(err, ErrAccessDenied) {
+		t.Errorf("err = %v, want ErrAccessDenied", err)
+	}
+}
+
+func TestCompleteIsSingleUse(t *testing.T) {
+	s, _ := newTestService(t)
+	req := startLogin(t, s)
+
+	rec := httptest.NewRecorder()
+	if _, err := s.Complete(t.Context(), rec, req, "github"); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	cleared := false
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == stateCookieName && c.MaxAge < 0 {
+			cleared = true
+		}
+	}
+	if !cleared {
+		t.Error("state cookie not cleared after the callback")
+	}
+
+	// Replayed callback: the code is spent
+	if _, err := s.Complete(t.Context(), httptest.NewRecorder(), req, "github"); err == nil {
+		t.Error("replayed callback signed in again")
+	}
+}
+
+func TestUnknownProvider(t *testing.T) {
+	s, _ := newTestService(t)
+
+	if _, err := s.Begin(httptest.NewRecorder(), "gitlab"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("begin: err = %v, want ErrUnknownProvider", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/auth/gitlab/callback?code=x&state=y", nil)
+	if _, err := s.Complete(t.Context(), httptest.NewRecorder(), req, "gitlab"); !errors.Is(err, ErrUnknownProvider) {
+		t.Errorf("complete: err = %v, want ErrUnknownProvider", err)
+	}
+}
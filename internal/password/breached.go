@@ -0,0 +1,75 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// prefixLength - hex characters of SHA-1 in a range file name (k-anonymity prefix)
+const prefixLength = 5
+
+// Corpus is an offline breached password list in k-anonymity range format
+// WHY: Asking an online service at registration sends (a prefix of) every new
+// password hash outside and makes registration depend on it
+// HOW: The same layout the Pwned Passwords range API serves, stored on disk:
+// SHA-1 of the password is split into a 5-character prefix and the rest,
+// the prefix names the file, the file lists suffixes:
+//
+//	<dir>/21BD1.txt
+//	0018A45C4D1DEF81644B54AB7F969B88D65:21
+//	00D4F6E8FA6EECAD2A3AA415EEC418D38EC:2
+//
+// Only one small file is read per check, nothing is loaded into memory
+// A missing range file means no breached password has that prefix
+type Corpus struct {
+	dir string
+}
+
+// OpenCorpus opens corpus directory
+func OpenCorpus(dir string) (*Corpus, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password corpus: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breached password corpus %q is not a directory", dir)
+	}
+
+	return &Corpus{dir: dir}, nil
+}
+
+// Contains reports whether the password is in the corpus
+func (c *Corpus) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	f, err := os.Open(filepath.Join(c.dir, prefix+".txt"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to open range %s: %w", prefix, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), ":")
+		if strings.EqualFold(strings.TrimSpace(line), suffix) {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read range %s: %w", prefix, err)
+	}
+
+	return false, nil
+}
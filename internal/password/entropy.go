@@ -0,0 +1,76 @@
+package password
+
+import (
+	"math"
+	"unicode"
+)
+
+// Character pool sizes by class
+const (
+	poolLower  = 26
+	poolUpper  = 26
+	poolDigit  = 10
+	poolSymbol = 33  // printable ASCII punctuation and space
+	poolOther  = 100 // any other letters (Cyrillic...) - rough, but an attacker must guess the alphabet too
+)
+
+// Entropy estimates password strength in bits
+// WHY: Class rules ("a letter and a digit") reject long passphrases
+// and accept Password1; what matters is how many guesses an attacker needs
+// HOW: Every character is worth log2 of the pool of classes used in the password,
+// except characters that repeat or neighbour the previous one
+// (aaa, abc, 321) - those are worth 1 bit
+//
+// It's an upper bound: dictionary words are not detected, the breached corpus
+// catches the common ones
+func Entropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	for _, r := range password {
+		switch {
+		case r >= 'a' && r <= 'z':
+			lower = true
+		case r >= 'A' && r <= 'Z':
+			upper = true
+		case r >= '0' && r <= '9':
+			digit = true
+		case r <= unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+	}
+
+	pool := 0
+	for _, c := range []struct {
+		used bool
+		size int
+	}{
+		{lower, poolLower},
+		{upper, poolUpper},
+		{digit, poolDigit},
+		{symbol, poolSymbol},
+		{other, poolOther},
+	} {
+		if c.used {
+			pool += c.size
+		}
+	}
+	if pool == 0 {
+		return 0
+	}
+
+	perChar := math.Log2(float64(pool))
+
+	var bits float64
+	var prev rune
+	for i, r := range password {
+		if i > 0 && (r == prev || r == prev+1 || r == prev-1) {
+			bits++
+		} else {
+			bits += perChar
+		}
+		prev = r
+	}
+
+	return bits
+}
@@ -0,0 +1,115 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/udisondev/learn-go/pkg/config"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrUnknownFormat - stored hash is neither argon2id PHC string nor bcrypt
+var ErrUnknownFormat = errors.New("unknown password hash format")
+
+// Hasher hashes and verifies passwords
+// WHY: bcrypt is limited to 72 bytes of password and its cost can't be tuned
+// for memory, which is what makes GPU cracking expensive
+// HOW: New hashes are argon2id in PHC string format - parameters and salt are
+// stored in the hash itself, so they can change without breaking old hashes:
+//
+//	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+//
+// bcrypt hashes ($2a$, $2b$, $2y$) from before the migration are still verified;
+// Verify reports them (and argon2id hashes with other parameters) as needing
+// rehash, the caller stores a fresh Hash while it has the plaintext
+type Hasher struct {
+	params Params
+	dummy  string
+}
+
+// NewHasher creates hasher with parameters from configuration
+func NewHasher(cfg config.PasswordConfig) (*Hasher, error) {
+	p := Params{
+		Memory:      cfg.Memory,
+		Iterations:  cfg.Iterations,
+		Parallelism: cfg.Parallelism,
+		SaltLength:  cfg.SaltLength,
+		KeyLength:   cfg.KeyLength,
+	}
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+
+	h := &Hasher{params: p}
+
+	// Hash of a random password for VerifyDummy, with the current parameters
+	dummy, err := h.Hash(rand.Text())
+	if err != nil {
+		return nil, err
+	}
+	h.dummy = dummy
+
+	return h, nil
+}
+
+// Hash returns argon2id PHC string of the password with a random salt
+func (h *Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.params.Iterations, h.params.Memory, h.params.Parallelism, h.params.KeyLength)
+
+	return encodePHC(h.params, salt, key), nil
+}
+
+// Verify checks password against the stored hash
+// needsRehash is true for a matching password whose hash is bcrypt or argon2id
+// with parameters other than the configured ones
+//
+// Empty hash (account created through OAuth, no password yet) never matches
+func (h *Hasher) Verify(encoded, password string) (ok, needsRehash bool, err error) {
+	switch {
+	case encoded == "":
+		// Same work as for a real hash - the response time must not reveal passwordless accounts
+		h.VerifyDummy(password)
+		return false, false, nil
+
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		p, salt, key, err := decodePHC(encoded)
+		if err != nil {
+			return false, false, err
+		}
+
+		candidate := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, uint32(len(key)))
+		if subtle.ConstantTimeCompare(candidate, key) != 1 {
+			return false, false, nil
+		}
+		return true, p != h.params, nil
+
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		if err != nil {
+			return false, false, fmt.Errorf("failed to verify bcrypt hash: %w", err)
+		}
+		return true, true, nil
+
+	default:
+		return false, false, ErrUnknownFormat
+	}
+}
+
+// VerifyDummy does the work of Verify against a hash of a random password
+// WHY: Without it the response for an unknown email comes noticeably faster,
+// and registered addresses could be enumerated by timing
+func (h *Hasher) VerifyDummy(password string) {
+	_, _, _ = h.Verify(h.dummy, password)
+}
@@ -0,0 +1,88 @@
+package password
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// Params - argon2id parameters, stored in every hash
+type Params struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32 // bytes
+	KeyLength   uint32 // bytes
+}
+
+// validate rejects parameters argon2 would accept but that make hashes weak
+// (or, for zero values, panic)
+func (p Params) validate() error {
+	switch {
+	case p.Iterations < 1:
+		return errors.New("argon2 iterations must be at least 1")
+	case p.Parallelism < 1:
+		return errors.New("argon2 parallelism must be at least 1")
+	case p.Memory < 8*uint32(p.Parallelism):
+		return errors.New("argon2 memory must be at least 8 KiB per lane")
+	case p.SaltLength < 8:
+		return errors.New("argon2 salt must be at least 8 bytes")
+	case p.KeyLength < 16:
+		return errors.New("argon2 key must be at least 16 bytes")
+	}
+	return nil
+}
+
+// encodePHC formats the hash as a PHC string, base64 without padding
+func encodePHC(p Params, salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+// decodePHC parses argon2id PHC string
+// Salt and key lengths are taken from the hash, not from configuration
+func decodePHC(encoded string) (Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Params{}, nil, nil, ErrUnknownFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return Params{}, nil, nil, fmt.Errorf("invalid argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return Params{}, nil, nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	var p Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return Params{}, nil, nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Params{}, nil, nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return Params{}, nil, nil, fmt.Errorf("invalid argon2id key: %w", err)
+	}
+
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	if err := p.validate(); err != nil {
+		return Params{}, nil, nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+
+	return p, salt, key, nil
+}
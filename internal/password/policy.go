@@ -0,0 +1,133 @@
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// maxLength caps password length - hashing is the same for any length,
+// but there is no reason to accept megabytes in a form field
+const maxLength = 256
+
+// minPersonalLength - shorter name parts ("Al", "io") occur in too many passwords by chance
+const minPersonalLength = 3
+
+// Violation is the first policy rule a password breaks
+type Violation int
+
+const (
+	ViolationNone Violation = iota
+	ViolationTooShort
+	ViolationTooLong
+	ViolationPersonalInfo // contains the user's name or email
+	ViolationTooWeak      // estimated entropy below minimum
+	ViolationBreached     // found in the breached password corpus
+)
+
+// Personal - account data the password must not contain
+type Personal struct {
+	Name  string
+	Email string
+}
+
+// Policy decides whether a new password is acceptable
+// WHY: "a letter and a digit" accepts Password1 and qwerty123 - the first
+// passwords in every cracking dictionary
+// HOW: Rules from cheap to expensive, the first broken one is returned
+// (forms show one message per field):
+// length, name/email inside, estimated entropy, breached corpus lookup
+//
+// Applied to new passwords only (registration, reset), never at login
+type Policy struct {
+	minLength  int
+	minEntropy float64
+	breached   *Corpus
+}
+
+// NewPolicy creates policy from configuration
+// Fails if the breached corpus directory is configured but unreadable
+func NewPolicy(cfg config.PasswordConfig) (*Policy, error) {
+	if cfg.MinLength < 1 || cfg.MinLength > maxLength {
+		return nil, fmt.Errorf("password min length must be between 1 and %d", maxLength)
+	}
+
+	p := &Policy{
+		minLength:  cfg.MinLength,
+		minEntropy: cfg.MinEntropy,
+	}
+
+	if cfg.BreachedDir != "" {
+		corpus, err := OpenCorpus(cfg.BreachedDir)
+		if err != nil {
+			return nil, err
+		}
+		p.breached = corpus
+	}
+
+	return p, nil
+}
+
+// MinLength returns minimum password length in characters
+func (p *Policy) MinLength() int {
+	return p.minLength
+}
+
+// Check returns the first rule the password breaks, ViolationNone if it is acceptable
+// Error means the breached corpus could not be read
+func (p *Policy) Check(password string, personal Personal) (Violation, error) {
+	length := utf8.RuneCountInString(password)
+	switch {
+	case length < p.minLength:
+		return ViolationTooShort, nil
+	case length > maxLength:
+		return ViolationTooLong, nil
+	case containsPersonal(password, personal):
+		return ViolationPersonalInfo, nil
+	case Entropy(password) < p.minEntropy:
+		return ViolationTooWeak, nil
+	}
+
+	if p.breached != nil {
+		found, err := p.breached.Contains(password)
+		if err != nil {
+			return ViolationNone, fmt.Errorf("failed to check breached passwords: %w", err)
+		}
+		if found {
+			return ViolationBreached, nil
+		}
+	}
+
+	return ViolationNone, nil
+}
+
+// containsPersonal reports whether password contains a part of the name or email
+// Case-insensitive; email is split into local part words and the domain name
+func containsPersonal(password string, personal Personal) bool {
+	password = strings.ToLower(password)
+
+	var parts []string
+	parts = append(parts, strings.FieldsFunc(strings.ToLower(personal.Name), isSeparator)...)
+
+	local, domain, _ := strings.Cut(strings.ToLower(personal.Email), "@")
+	parts = append(parts, local)
+	parts = append(parts, strings.FieldsFunc(local, isSeparator)...)
+	if name, _, ok := strings.Cut(domain, "."); ok {
+		parts = append(parts, name)
+	}
+
+	for _, part := range parts {
+		if utf8.RuneCountInString(part) >= minPersonalLength && strings.Contains(password, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSeparator splits names and email local parts into words
+func isSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
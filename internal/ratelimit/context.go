@@ -0,0 +1,20 @@
+package ratelimit
+
+import "context"
+
+// ctxKey is a type-safe context key for the rate limit decision
+type ctxKey struct{}
+
+// WithCtx adds rate limit decision to context
+// WHY: The handler rendering the "too many requests" fragment needs the wait time
+// HOW: Set by RateLimit middleware before calling its onLimited handler
+func WithCtx(ctx context.Context, res Result) context.Context {
+	return context.WithValue(ctx, ctxKey{}, res)
+}
+
+// FromCtx retrieves rate limit decision from context
+// Returns (result, true) if the request went through RateLimit middleware, (zero, false) otherwise
+func FromCtx(ctx context.Context) (Result, bool) {
+	res, ok := ctx.Value(ctxKey{}).(Result)
+	return res, ok
+}
@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// Policy names, one per protected action
+const (
+	PolicyLogin       = "login"
+	PolicyRegister    = "register"
+	PolicySubmit      = "submit"
+	PolicyResendEmail = "resend-email"
+	PolicyCSPReport   = "csp-report"
+)
+
+// Policy allows Limit requests per Period for one key
+// The whole Limit may be spent at once, then requests are replenished
+// evenly: one every Period/Limit
+type Policy struct {
+	Name   string
+	Limit  int
+	Period time.Duration
+}
+
+// interval is the time one request "costs"
+func (p Policy) interval() time.Duration {
+	return p.Period / time.Duration(p.Limit)
+}
+
+// Result describes the decision and the state of the key after it
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Window     time.Duration // Period of the policy
+	Remaining  int
+	ResetAfter time.Duration // until the whole Limit is available again
+	RetryAfter time.Duration // until the next request is allowed, zero if Allowed
+}
+
+// Limiter applies named policies to keys (IP, user ID, email address)
+// WHY: The old limiter kept a sliding log per key in process memory - every
+// replica had its own counters and a deploy reset them
+// HOW: GCRA (generic cell rate algorithm, a token bucket without the refill loop):
+// for every key the store keeps one timestamp - TAT, the theoretical arrival time
+// at which the bucket is full again. A request moves TAT forward by Period/Limit
+// and is allowed while TAT stays within Period from now
+type Limiter struct {
+	store    Store
+	policies map[string]Policy
+}
+
+// New creates limiter with policies from configuration
+func New(store Store, cfg config.RateLimitConfig) *Limiter {
+	l := &Limiter{
+		store:    store,
+		policies: make(map[string]Policy),
+	}
+
+	for name, rate := range map[string]config.Rate{
+		PolicyLogin:       cfg.Login,
+		PolicyRegister:    cfg.Register,
+		PolicySubmit:      cfg.Submit,
+		PolicyResendEmail: cfg.ResendEmail,
+		PolicyCSPReport:   cfg.CSPReport,
+	} {
+		l.policies[name] = Policy{Name: name, Limit: rate.Count, Period: rate.Period}
+	}
+
+	return l
+}
+
+// Allow takes one request from the key's bucket of the policy
+// Keys of different policies never collide: the store key is "policy:key"
+func (l *Limiter) Allow(ctx context.Context, policyName, key string) (Result, error) {
+	p, ok := l.policies[policyName]
+	if !ok {
+		return Result{}, fmt.Errorf("unknown rate limit policy %q", policyName)
+	}
+
+	now := time.Now().UTC()
+	interval := p.interval()
+
+	tat, allowed, err := l.store.Take(ctx, p.Name+":"+key, now, interval, p.Period)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to take from rate limit bucket: %w", err)
+	}
+
+	res := Result{
+		Allowed:    allowed,
+		Limit:      p.Limit,
+		Window:     p.Period,
+		ResetAfter: max(tat.Sub(now), 0),
+	}
+
+	if allowed {
+		res.Remaining = int((p.Period - tat.Sub(now)) / interval)
+	} else {
+		// The next request would move TAT to tat+interval, it fits once that is within Period
+		res.RetryAfter = tat.Add(interval).Sub(now) - p.Period
+	}
+
+	return res, nil
+}
+
+// RunSweeper periodically deletes keys whose buckets are full again until ctx is cancelled
+// Such keys behave exactly like missing ones
+func (l *Limiter) RunSweeper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := l.store.DeleteExpired(ctx, time.Now().UTC())
+			if err != nil {
+				slog.Error("Failed to sweep rate limit keys", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.Debug("Rate limit keys swept", "count", deleted)
+			}
+		}
+	}
+}
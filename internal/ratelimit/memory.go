@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps TAT of keys in process memory
+// WHY: Local dev and a single instance without the rate_limits table
+// HOW: Map guarded by Mutex, Take holds the lock for check and update
+//
+// Every instance has its own counters, don't use it with several replicas
+type MemoryStore struct {
+	mu   sync.Mutex
+	tats map[string]time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates empty in-memory rate limit store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tats: make(map[string]time.Time),
+	}
+}
+
+// Take moves TAT of the key forward if the request fits into burst
+func (m *MemoryStore) Take(_ context.Context, key string, now time.Time, interval, burst time.Duration) (time.Time, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	tat, ok := m.tats[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+
+	newTAT := tat.Add(interval)
+	if newTAT.Sub(now) > burst {
+		return tat, false, nil
+	}
+
+	m.tats[key] = newTAT
+	return newTAT, true, nil
+}
+
+// DeleteExpired deletes keys with TAT before now
+func (m *MemoryStore) DeleteExpired(_ context.Context, now time.Time) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for key, tat := range m.tats {
+		if tat.Before(now) {
+			delete(m.tats, key)
+			deleted++
+		}
+	}
+
+	return deleted, nil
+}
@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+// Repository keeps TAT of keys in Postgres
+// One row per key, updated by a single conditional upsert - concurrent
+// requests of one key are serialized by the row lock, no transaction needed
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+var _ Store = (*Repository)(nil)
+
+// NewRepository creates new rate limit repository
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// Take moves TAT of the key forward if the request fits into burst
+//
+// New key: inserted with TAT = now+interval (always fits, burst >= interval)
+// Existing key: updated only if max(tat, now)+interval-burst <= now,
+// otherwise the upsert returns no row and the current TAT is read
+func (r *Repository) Take(ctx context.Context, key string, now time.Time, interval, burst time.Duration) (time.Time, bool, error) {
+	query, args, err := psql.
+		Insert("rate_limits").
+		Columns("key", "tat").
+		Values(key, now.Add(interval)).
+		Suffix(`ON CONFLICT (key) DO UPDATE
+			SET tat = GREATEST(rate_limits.tat, ?::timestamptz) + ?::bigint * INTERVAL '1 microsecond'
+			WHERE GREATEST(rate_limits.tat, ?::timestamptz) + ?::bigint * INTERVAL '1 microsecond' <= ?::timestamptz
+			RETURNING tat`,
+			now, interval.Microseconds(),
+			now, (interval - burst).Microseconds(), now,
+		).
+		ToSql()
+
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to build upsert query: %w", err)
+	}
+
+	var tat time.Time
+	err = r.db.QueryRow(ctx, query, args...).Scan(&tat)
+	if err == nil {
+		return tat, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, false, fmt.Errorf("failed to take rate limit: %w", err)
+	}
+
+	// Limited - report current TAT for Retry-After
+	query, args, err = psql.
+		Select("tat").
+		From("rate_limits").
+		Where(sq.Eq{"key": key}).
+		ToSql()
+
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&tat); err != nil {
+		// Swept between the queries - the request is already refused, retry after one interval
+		if errors.Is(err, pgx.ErrNoRows) {
+			return now.Add(burst), false, nil
+		}
+		return time.Time{}, false, fmt.Errorf("failed to get rate limit: %w", err)
+	}
+
+	return tat, false, nil
+}
+
+// DeleteExpired deletes keys with TAT before now
+func (r *Repository) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	query, args, err := psql.
+		Delete("rate_limits").
+		Where(sq.Lt{"tat": now}).
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired rate limits: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Store keeps TAT (theoretical arrival time) of every key
+// WHY: Limits must be shared by all replicas in production, but local dev
+// and tests shouldn't need a database
+// HOW: Limiter works only through this interface, backends are chosen in NewStore:
+// - Repository: Postgres, one upsert per request
+// - MemoryStore: process memory, counters are per instance and lost on restart
+//
+// Take must be atomic: check and update of one key can't interleave
+type Store interface {
+	// Take moves TAT of the key to max(TAT, now)+interval if the result
+	// stays within burst from now
+	// Returns the new TAT if allowed, the current TAT otherwise
+	Take(ctx context.Context, key string, now time.Time, interval, burst time.Duration) (time.Time, bool, error)
+	// DeleteExpired deletes keys with TAT before now - their buckets are full
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// Store backends (RATE_LIMIT_STORE)
+const (
+	StorePostgres = "postgres"
+	StoreMemory   = "memory"
+)
+
+// NewStore builds rate limit store by name
+func NewStore(name string, db *pgxpool.Pool) (Store, error) {
+	switch name {
+	case StorePostgres:
+		return NewRepository(db), nil
+	case StoreMemory:
+		return NewMemoryStore(), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit store %q", name)
+	}
+}
@@ -6,45 +6,127 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/udisondev/learn-go/internal/apitoken"
+	"github.com/udisondev/learn-go/internal/audit"
+	"github.com/udisondev/learn-go/internal/clientip"
 	"github.com/udisondev/learn-go/internal/handler"
+	"github.com/udisondev/learn-go/internal/impersonation"
 	mw "github.com/udisondev/learn-go/internal/middleware"
+	"github.com/udisondev/learn-go/internal/ratelimit"
 	"github.com/udisondev/learn-go/internal/session"
+	"github.com/udisondev/learn-go/internal/user"
+	"github.com/udisondev/learn-go/pkg/config"
 )
 
 // New creates and configures the HTTP router
-func New(h *handler.Handler, sessionService *session.Service) *chi.Mux {
+func New(h *handler.Handler, sessionService *session.Service, apiTokens *apitoken.Service, impersonations *impersonation.Service, auditLog *audit.Service, limiter *ratelimit.Limiter, ipResolver *clientip.Resolver, cfg *config.Config) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Middleware
 	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
+	r.Use(clientip.Middleware(ipResolver)) // client IP from trusted proxies only, before Logger
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.Timeout(60 * time.Second))
+	r.Use(mw.SecurityHeaders(cfg.App.Env, cfg.Security)) // CSP with per-request nonce, HSTS in production
+	// CSRF - every POST needs a token (form field or X-CSRF-Token), bearer API calls are exempt
+	r.Use(mw.CSRF(&cfg.CSRF, http.HandlerFunc(h.CSRFFailure)))
 	r.Use(mw.Auth(sessionService)) // Auth middleware - adds user to context if session exists
+	// Admin acting as another user - replaces the user in context, after Auth
+	r.Use(mw.Impersonate(impersonations, auditLog))
 
 	// Static files
 	fileServer := http.FileServer(http.Dir("web/static"))
 	r.Handle("/static/*", http.StripPrefix("/static/", fileServer))
 
+	// Rate limits by named policy (RATE_LIMIT_*), refused requests get the 429 flash
+	limit := func(policy string, key mw.KeyFunc) func(http.Handler) http.Handler {
+		return mw.RateLimit(limiter, policy, key, http.HandlerFunc(h.RateLimited))
+	}
+	loginLimit := limit(ratelimit.PolicyLogin, mw.ByIP)
+
+	// Account takeover actions are refused while an admin impersonates the user
+	denyImpersonated := mw.DenyImpersonated(http.HandlerFunc(h.ImpersonationDenied))
+
+	// CSP violation reports from browsers, limited per IP against log flooding
+	r.With(limit(ratelimit.PolicyCSPReport, mw.ByIP)).Post(mw.CSPReportPath, h.PostCSPReport)
+
 	// Public routes
 	r.Get("/", h.HandleLanding)
 	r.Get("/register", h.HandleRegisterPage)
-	r.Post("/register", h.HandleRegisterSubmit)
+	r.With(limit(ratelimit.PolicyRegister, mw.ByIP)).Post("/register", h.HandleRegisterSubmit)
 	r.Get("/login", h.GetLogin)
-	r.Post("/login", h.PostLogin)
+	r.With(loginLimit).Post("/login", h.PostLogin)
+	r.With(loginLimit).Post("/login/2fa", h.PostLoginTwoFactor)
+	r.Get("/login/unlock", h.GetLoginUnlock)
+	r.Get("/login/magic", h.GetMagicLink)
+	r.With(loginLimit).Post("/login/magic", h.PostMagicLink)
+	r.Get("/login/magic/confirm", h.GetMagicLinkConfirm)
+	r.With(loginLimit).Post("/login/magic/confirm", h.PostMagicLinkConfirm)
+	r.Get("/auth/{provider}", h.GetOAuthStart)
+	r.Get("/auth/{provider}/callback", h.GetOAuthCallback)
 	r.Get("/verify-email", h.HandleVerifyEmail)
+	r.With(loginLimit).Post("/verify-email/resend", h.PostResendVerification)
+	r.Get("/forgot-password", h.GetForgotPassword)
+	// Public, but an impersonation session must not reset the user's password
+	r.With(loginLimit, denyImpersonated).Post("/forgot-password", h.PostForgotPassword)
+	r.Get("/reset-password", h.GetResetPassword)
+	r.With(loginLimit, denyImpersonated).Post("/reset-password", h.PostResetPassword)
 	r.Post("/logout", h.HandleLogout)
 
 	// Protected routes (require authentication)
-	// TODO: r.Group(func(r chi.Router) {
-	//   r.Use(middleware.AuthMiddleware)
-	//   r.Get("/course", h.HandleCourse)
-	//   r.Get("/profile", h.HandleProfile)
-	//   r.Post("/logout", h.HandleLogout)
-	//   r.Post("/submit", h.HandleSubmitCode)
-	//   etc...
-	// })
+	r.Group(func(r chi.Router) {
+		r.Use(mw.RequireAuth)
+
+		r.Get("/upgrade", h.GetUpgrade)
+		r.Get("/profile/sessions", h.GetSessions)
+		r.Get("/profile/security", h.GetSecurity)
+		r.Get("/profile/tokens", h.GetTokens)
+		r.Get("/profile/activity", h.GetActivity)
+		r.Post("/impersonation/stop", h.PostStopImpersonation)
+
+		// Security settings - only the owner can change them, not an admin impersonating the user
+		r.Group(func(r chi.Router) {
+			r.Use(denyImpersonated)
+
+			r.Post("/profile/sessions/revoke-others", h.PostRevokeOtherSessions)
+			r.Post("/profile/sessions/{handle}/revoke", h.PostRevokeSession)
+			r.Post("/profile/2fa/setup", h.PostTwoFactorSetup)
+			r.Post("/profile/2fa/confirm", h.PostTwoFactorConfirm)
+			r.Post("/profile/2fa/disable", h.PostTwoFactorDisable)
+			r.Post("/profile/2fa/recovery-codes", h.PostRecoveryCodes)
+			r.Post("/profile/tokens", h.PostCreateToken)
+			r.Post("/profile/tokens/{id}/revoke", h.PostRevokeToken)
+		})
+
+		// TODO: course routes
+		//   r.Use(mw.RequireVerified)
+		//   r.Get("/course", h.HandleCourse)
+		//   r.With(mw.RequireSubPlan(user.SubPlanBasic)).Get("/course/{slug}", h.HandleLesson)
+		//   r.With(limit(ratelimit.PolicySubmit, mw.ByUser)).Post("/submit", h.HandleSubmitCode)
+	})
+
+	// Admin area
+	r.Route("/admin", func(r chi.Router) {
+		r.Use(mw.RequireRole(user.RoleAdmin))
+
+		r.Get("/", http.RedirectHandler("/admin/users", http.StatusSeeOther).ServeHTTP)
+		r.Get("/users", h.GetAdminUsers)
+		r.Get("/users/{id}", h.GetAdminUser)
+		r.Post("/users/{id}/plan", h.PostAdminChangePlan)
+		r.Post("/users/{id}/resend-verification", h.PostAdminResendVerification)
+		r.Post("/users/{id}/suspend", h.PostAdminSuspend)
+		r.Post("/users/{id}/unsuspend", h.PostAdminUnsuspend)
+		r.Post("/users/{id}/impersonate", h.PostAdminImpersonate)
+		r.Get("/audit-events", h.GetAdminAuditEvents)
+	})
+
+	// API for CLI and editor plugins (personal access tokens)
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(mw.Bearer(apiTokens))
+
+		r.With(mw.RequireScope(apitoken.ScopeRead)).Get("/me", h.GetAPIMe)
+	})
 
 	return r
 }
@@ -0,0 +1,192 @@
+package session
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// CachedStore is a read-through LRU cache in front of another Store
+// WHY: Auth middleware loads user and session on every request,
+// the same few sessions are read over and over
+// HOW: GetUserBySessionID results are kept for ttl, at most size entries,
+// least recently used are evicted first. Writes go straight to the backend:
+// - Touch updates the cached copy
+// - Delete* (logout, revocation, password reset) evicts affected entries
+// - InvalidateUser evicts sessions of a user whose profile changed
+//
+// Cache is per process: a session revoked on another instance
+// stays valid here for up to ttl, keep it short
+type CachedStore struct {
+	next Store
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	lru     *list.List // front - most recently used
+	entries map[uuid.UUID]*list.Element
+	gen     uint64 // incremented on every eviction, see GetUserBySessionID
+}
+
+var _ Store = (*CachedStore)(nil)
+
+// cacheEntry is a cached GetUserBySessionID result
+type cacheEntry struct {
+	user      user.User
+	sess      Session
+	expiresAt time.Time
+}
+
+// NewCachedStore wraps store with LRU cache of given size and ttl
+func NewCachedStore(next Store, size int, ttl time.Duration) *CachedStore {
+	return &CachedStore{
+		next:    next,
+		size:    size,
+		ttl:     ttl,
+		lru:     list.New(),
+		entries: make(map[uuid.UUID]*list.Element),
+	}
+}
+
+// Create stores session in the backend
+// Not cached: the first request with the new cookie will load it
+func (c *CachedStore) Create(ctx context.Context, sess *Session) error {
+	return c.next.Create(ctx, sess)
+}
+
+// GetUserBySessionID returns cached user and session or loads them from the backend
+// Returned values are copies, callers may modify them
+func (c *CachedStore) GetUserBySessionID(ctx context.Context, sessionID uuid.UUID) (*user.User, *Session, error) {
+	now := time.Now()
+
+	c.mu.Lock()
+	if el, ok := c.entries[sessionID]; ok {
+		e := el.Value.(*cacheEntry)
+		if now.Before(e.expiresAt) {
+			c.lru.MoveToFront(el)
+			u, sess := e.copy()
+			c.mu.Unlock()
+			return u, sess, nil
+		}
+		c.remove(el)
+	}
+	gen := c.gen
+	c.mu.Unlock()
+
+	u, sess, err := c.next.GetUserBySessionID(ctx, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Session was revoked while we were reading it - caching the
+	// result would resurrect it until ttl
+	if gen != c.gen {
+		return u, sess, nil
+	}
+
+	if _, ok := c.entries[sessionID]; !ok {
+		c.entries[sessionID] = c.lru.PushFront(&cacheEntry{
+			user:      *u,
+			sess:      *sess,
+			expiresAt: now.Add(c.ttl),
+		})
+		for c.lru.Len() > c.size {
+			c.remove(c.lru.Back())
+		}
+	}
+
+	return u, sess, nil
+}
+
+// ListByUserID is not cached: the devices page is rare and must be exact
+func (c *CachedStore) ListByUserID(ctx context.Context, userID int64) ([]Session, error) {
+	return c.next.ListByUserID(ctx, userID)
+}
+
+// Touch updates last activity in the backend and in the cached copy
+func (c *CachedStore) Touch(ctx context.Context, sessionID uuid.UUID, seenAt time.Time) error {
+	if err := c.next.Touch(ctx, sessionID, seenAt); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[sessionID]; ok {
+		el.Value.(*cacheEntry).sess.LastSeenAt = seenAt
+	}
+	return nil
+}
+
+// Delete evicts session and deletes it from the backend
+func (c *CachedStore) Delete(ctx context.Context, sessionID uuid.UUID) error {
+	c.evictWhere(func(e *cacheEntry) bool {
+		return e.sess.ID == sessionID
+	})
+	return c.next.Delete(ctx, sessionID)
+}
+
+// DeleteByUserID evicts all sessions of a user and deletes them from the backend
+func (c *CachedStore) DeleteByUserID(ctx context.Context, userID int64) error {
+	c.InvalidateUser(userID)
+	return c.next.DeleteByUserID(ctx, userID)
+}
+
+// DeleteByUserIDExcept evicts and deletes all sessions of a user except one
+func (c *CachedStore) DeleteByUserIDExcept(ctx context.Context, userID int64, keepID uuid.UUID) error {
+	c.evictWhere(func(e *cacheEntry) bool {
+		return e.sess.UserID == userID && e.sess.ID != keepID
+	})
+	return c.next.DeleteByUserIDExcept(ctx, userID, keepID)
+}
+
+// DeleteExpired deletes expired sessions from the backend
+// Cached entries of expired sessions are rejected by Service and age out with ttl
+func (c *CachedStore) DeleteExpired(ctx context.Context) (int64, error) {
+	return c.next.DeleteExpired(ctx)
+}
+
+// InvalidateUser evicts cached sessions of a user
+// Call after changing user data that is read from context (plan, roles, verification)
+func (c *CachedStore) InvalidateUser(userID int64) {
+	c.evictWhere(func(e *cacheEntry) bool {
+		return e.sess.UserID == userID
+	})
+}
+
+// evictWhere removes entries matching fn
+// Always bumps gen, even if nothing matched: a concurrent miss may be
+// loading one of these sessions right now
+func (c *CachedStore) evictWhere(fn func(*cacheEntry) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.gen++
+	for _, el := range c.entries {
+		if fn(el.Value.(*cacheEntry)) {
+			c.remove(el)
+		}
+	}
+}
+
+// remove deletes element from list and index, c.mu must be held
+func (c *CachedStore) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).sess.ID)
+}
+
+// copy returns copies of cached values so callers can't modify the cache
+func (e *cacheEntry) copy() (*user.User, *Session) {
+	u := e.user
+	u.Roles = slices.Clone(e.user.Roles)
+	sess := e.sess
+	return &u, &sess
+}
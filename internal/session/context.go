@@ -0,0 +1,21 @@
+package session
+
+import "context"
+
+// ctxKey is a type-safe context key for the current session
+type ctxKey struct{}
+
+// WithCtx adds current Session to context
+// WHY: Handlers need to know which session made the request
+// (mark current device, revoke all other sessions)
+// HOW: Set by Auth middleware together with user.WithCtx
+func WithCtx(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, ctxKey{}, s)
+}
+
+// FromCtx retrieves current Session from context
+// Returns (session, true) if request is authenticated, (nil, false) otherwise
+func FromCtx(ctx context.Context) (*Session, bool) {
+	s, ok := ctx.Value(ctxKey{}).(*Session)
+	return s, ok
+}
@@ -0,0 +1,60 @@
+package session
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CookieName is the name of the cookie holding session ID
+const CookieName = "session_id"
+
+// Cookie builds session cookie for the given session
+// WHY: Cookie lifetime must follow session policy (sliding renewal)
+// HOW: MaxAge = time left until session expires by absolute or idle limit
+//
+// Security attributes:
+// - HttpOnly: JavaScript can't read the cookie (XSS)
+// - Secure: HTTPS only in production (from config)
+// - SameSite=Lax: cookie is sent on top-level navigations (links from emails),
+// but not on cross-site POST requests
+func (s *Service) Cookie(sess *Session) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     CookieName,
+		Value:    sess.ID.String(),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   s.secureCookie,
+		SameSite: http.SameSiteLaxMode,
+	}
+
+	// Both limits disabled - browser-session cookie
+	if expiresAt := s.policy.ExpiresAt(sess); !expiresAt.IsZero() {
+		cookie.MaxAge = max(int(time.Until(expiresAt).Seconds()), 1)
+	}
+
+	return cookie
+}
+
+// ClearCookie removes session cookie
+// MaxAge: -1 means "delete immediately"
+func ClearCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+	})
+}
+
+// IDFromRequest extracts session ID from request cookie
+// Returns error if cookie is missing or malformed
+func IDFromRequest(r *http.Request) (uuid.UUID, error) {
+	cookie, err := r.Cookie(CookieName)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return uuid.Parse(cookie.Value)
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"bufio"
+	"fmt"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Locator resolves an IP address to an approximate, human readable location
+type Locator interface {
+	Locate(ip string) string
+}
+
+// geoRange is a single network from the GeoIP file
+type geoRange struct {
+	prefix   netip.Prefix
+	location string
+}
+
+// GeoLocator resolves locations from a local CIDR → location table
+// WHY: Showing "Москва, Россия" next to a session helps users spot foreign logins
+// HOW: Ranges loaded from a CSV file at startup, sorted for binary search
+//
+// File format (one network per line, '#' starts a comment):
+//
+//	203.0.113.0/24,Москва, Россия
+//	2001:db8::/32,Berlin, Germany
+//
+// Such a file can be produced from any GeoIP database export.
+// Without a file only local/private networks are recognized.
+type GeoLocator struct {
+	ranges []geoRange // sorted by prefix start address
+}
+
+// NewGeoLocator loads location ranges from path
+// Empty path is allowed and yields a locator that knows only private networks
+func NewGeoLocator(path string) (*GeoLocator, error) {
+	l := &GeoLocator{}
+	if path == "" {
+		return l, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open geoip file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		cidr, location, ok := strings.Cut(line, ",")
+		if !ok {
+			return nil, fmt.Errorf("geoip file line %d: missing location", lineNum)
+		}
+
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("geoip file line %d: %w", lineNum, err)
+		}
+
+		l.ranges = append(l.ranges, geoRange{
+			prefix:   prefix.Masked(),
+			location: strings.TrimSpace(location),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read geoip file: %w", err)
+	}
+
+	sort.Slice(l.ranges, func(i, j int) bool {
+		return l.ranges[i].prefix.Addr().Less(l.ranges[j].prefix.Addr())
+	})
+
+	return l, nil
+}
+
+// Locate returns approximate location for ip or "" if unknown
+func (l *GeoLocator) Locate(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsLinkLocalUnicast() {
+		return "Локальная сеть"
+	}
+
+	// Find the last range starting at or before addr
+	// GeoIP networks don't overlap, so it is the only candidate
+	idx := sort.Search(len(l.ranges), func(i int) bool {
+		return addr.Less(l.ranges[i].prefix.Addr())
+	}) - 1
+	if idx >= 0 && l.ranges[idx].prefix.Contains(addr) {
+		return l.ranges[idx].location
+	}
+
+	return ""
+}
@@ -0,0 +1,151 @@
+package session
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/udisondev/learn-go/internal/user"
+)
+
+// MemoryStore keeps sessions in process memory
+// WHY: Tests and local dev without a sessions table
+// HOW: Map guarded by RWMutex, users are loaded through UserLoader
+//
+// Sessions are lost on restart and not shared between instances,
+// don't use it in production
+type MemoryStore struct {
+	mu       sync.RWMutex
+	sessions map[uuid.UUID]Session
+	users    UserLoader
+	policy   Policy
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore creates empty in-memory session store
+func NewMemoryStore(users UserLoader, policy Policy) *MemoryStore {
+	return &MemoryStore{
+		sessions: make(map[uuid.UUID]Session),
+		users:    users,
+		policy:   policy,
+	}
+}
+
+// Create stores a new session
+func (m *MemoryStore) Create(_ context.Context, sess *Session) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.sessions[sess.ID] = *sess
+	return nil
+}
+
+// GetUserBySessionID returns alive session and its owner
+// Returns ErrSessionNotFound if session is missing, expired or its user was deleted or suspended
+func (m *MemoryStore) GetUserBySessionID(ctx context.Context, sessionID uuid.UUID) (*user.User, *Session, error) {
+	m.mu.RLock()
+	sess, ok := m.sessions[sessionID]
+	m.mu.RUnlock()
+
+	if !ok || !m.policy.Alive(&sess, time.Now().UTC()) {
+		return nil, nil, ErrSessionNotFound
+	}
+
+	u, err := m.users.GetUserByID(ctx, sess.UserID)
+	if errors.Is(err, user.ErrUserNotFound) {
+		return nil, nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if u.IsSuspended() {
+		return nil, nil, ErrSessionNotFound
+	}
+
+	return u, &sess, nil
+}
+
+// ListByUserID returns alive sessions of a user, most recently used first
+func (m *MemoryStore) ListByUserID(_ context.Context, userID int64) ([]Session, error) {
+	now := time.Now().UTC()
+
+	m.mu.RLock()
+	var sessions []Session
+	for _, sess := range m.sessions {
+		if sess.UserID == userID && m.policy.Alive(&sess, now) {
+			sessions = append(sessions, sess)
+		}
+	}
+	m.mu.RUnlock()
+
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return cmp.Compare(b.LastSeenAt.UnixNano(), a.LastSeenAt.UnixNano())
+	})
+
+	return sessions, nil
+}
+
+// Touch updates last activity time of a session
+func (m *MemoryStore) Touch(_ context.Context, sessionID uuid.UUID, seenAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if sess, ok := m.sessions[sessionID]; ok {
+		sess.LastSeenAt = seenAt
+		m.sessions[sessionID] = sess
+	}
+	return nil
+}
+
+// Delete deletes session by ID
+func (m *MemoryStore) Delete(_ context.Context, sessionID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, sessionID)
+	return nil
+}
+
+// DeleteByUserID deletes all sessions of a user
+func (m *MemoryStore) DeleteByUserID(_ context.Context, userID int64) error {
+	m.deleteWhere(func(sess Session) bool {
+		return sess.UserID == userID
+	})
+	return nil
+}
+
+// DeleteByUserIDExcept deletes all sessions of a user except one
+func (m *MemoryStore) DeleteByUserIDExcept(_ context.Context, userID int64, keepID uuid.UUID) error {
+	m.deleteWhere(func(sess Session) bool {
+		return sess.UserID == userID && sess.ID != keepID
+	})
+	return nil
+}
+
+// DeleteExpired deletes sessions expired by policy
+func (m *MemoryStore) DeleteExpired(_ context.Context) (int64, error) {
+	now := time.Now().UTC()
+	return m.deleteWhere(func(sess Session) bool {
+		return !m.policy.Alive(&sess, now)
+	}), nil
+}
+
+// deleteWhere deletes sessions matching fn and returns their number
+func (m *MemoryStore) deleteWhere(fn func(Session) bool) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var deleted int64
+	for id, sess := range m.sessions {
+		if fn(sess) {
+			delete(m.sessions, id)
+			deleted++
+		}
+	}
+	return deleted
+}
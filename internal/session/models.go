@@ -1,6 +1,8 @@
 package session
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,9 +12,28 @@ import (
 // WHY: Store authentication state in database
 // HOW: Session ID stored in HTTP cookie, used to identify user
 type Session struct {
-	ID        uuid.UUID // session token
-	UserID    int64     // user who owns this session
-	CreatedAt time.Time // when session was created
-	IPAddress string    // IP address for security audit
-	UserAgent string    // user agent for security audit
+	ID         uuid.UUID // session token
+	UserID     int64     // user who owns this session
+	CreatedAt  time.Time // when session was created
+	LastSeenAt time.Time // last request made with this session (throttled)
+	IPAddress  string    // IP address for security audit
+	UserAgent  string    // user agent for security audit
+}
+
+// Handle returns a public identifier of the session
+// WHY: Session ID is the cookie secret and must never be rendered into HTML
+// HOW: First 16 hex chars of SHA256(session ID) - stable, not reversible
+func (s *Session) Handle() string {
+	hash := sha256.Sum256([]byte(s.ID.String()))
+	return hex.EncodeToString(hash[:8])
+}
+
+// Device is a session enriched for the "active devices" page
+type Device struct {
+	Session
+	Handle   string // public session identifier for revoke links
+	Browser  string // parsed from user agent, e.g. "Chrome 120"
+	OS       string // parsed from user agent, e.g. "Windows"
+	Location string // approximate location resolved from IP
+	Current  bool   // session of the request that renders the page
 }
@@ -0,0 +1,89 @@
+package session
+
+import (
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// Policy defines how long a session stays valid
+// WHY: A stolen cookie must not work forever
+// HOW: Two independent limits, session dies when the first one is reached
+//
+//   - AbsoluteTimeout: max lifetime since login, regardless of activity
+//   - IdleTimeout: max time since last request (last_seen_at)
+//
+// Zero value of a limit disables it
+type Policy struct {
+	AbsoluteTimeout time.Duration
+	IdleTimeout     time.Duration
+}
+
+// PolicyFromConfig builds session policy from configuration
+func PolicyFromConfig(cfg config.SessionConfig) Policy {
+	return Policy{
+		AbsoluteTimeout: time.Duration(cfg.MaxAge) * time.Second,
+		IdleTimeout:     cfg.IdleTimeout,
+	}
+}
+
+// ExpiresAt returns the moment the session becomes invalid
+// Returns zero time if both limits are disabled
+func (p Policy) ExpiresAt(s *Session) time.Time {
+	var expiresAt time.Time
+
+	if p.AbsoluteTimeout > 0 {
+		expiresAt = s.CreatedAt.Add(p.AbsoluteTimeout)
+	}
+
+	if p.IdleTimeout > 0 {
+		idleAt := s.LastSeenAt.Add(p.IdleTimeout)
+		if expiresAt.IsZero() || idleAt.Before(expiresAt) {
+			expiresAt = idleAt
+		}
+	}
+
+	return expiresAt
+}
+
+// Alive reports whether the session is still valid at now
+// Go counterpart of aliveCond for stores that don't filter in SQL
+func (p Policy) Alive(s *Session, now time.Time) bool {
+	expiresAt := p.ExpiresAt(s)
+	return expiresAt.IsZero() || now.Before(expiresAt)
+}
+
+// aliveCond returns SQL condition matching sessions still valid at now
+// WHY: Expired rows may exist until the sweeper removes them,
+// every read must filter them out itself
+func (p Policy) aliveCond(alias string, now time.Time) sq.And {
+	cond := sq.And{}
+
+	if p.AbsoluteTimeout > 0 {
+		cond = append(cond, sq.Gt{alias + "created_at": now.Add(-p.AbsoluteTimeout)})
+	}
+	if p.IdleTimeout > 0 {
+		cond = append(cond, sq.Gt{alias + "last_seen_at": now.Add(-p.IdleTimeout)})
+	}
+
+	return cond
+}
+
+// expiredCond returns SQL condition matching sessions dead at now
+// Used by the sweeper; returns nil when both limits are disabled
+func (p Policy) expiredCond(now time.Time) sq.Sqlizer {
+	cond := sq.Or{}
+
+	if p.AbsoluteTimeout > 0 {
+		cond = append(cond, sq.LtOrEq{"created_at": now.Add(-p.AbsoluteTimeout)})
+	}
+	if p.IdleTimeout > 0 {
+		cond = append(cond, sq.LtOrEq{"last_seen_at": now.Add(-p.IdleTimeout)})
+	}
+
+	if len(cond) == 0 {
+		return nil
+	}
+	return cond
+}
@@ -2,7 +2,9 @@ package session
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	"github.com/google/uuid"
@@ -13,48 +15,53 @@ import (
 
 var psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
-// Repository handles session data access operations
+// ErrSessionNotFound is returned when session doesn't exist or was revoked
+var ErrSessionNotFound = errors.New("session not found")
+
+// Repository is the Postgres session Store
 type Repository struct {
-	db *pgxpool.Pool
+	db     *pgxpool.Pool
+	policy Policy
 }
 
+var _ Store = (*Repository)(nil)
+
 // NewRepository creates new session repository
-func NewRepository(db *pgxpool.Pool) *Repository {
-	return &Repository{db: db}
+// Policy is applied to every read, so expired sessions are invisible
+// even before the sweeper deletes them
+func NewRepository(db *pgxpool.Pool, policy Policy) *Repository {
+	return &Repository{db: db, policy: policy}
 }
 
-// Create creates a new session in database
+// Create inserts a new session into database
 // WHY: Store session for authentication
-// HOW: Generate UUID, insert with user_id, ip, user_agent
-//
-// Returns session ID to set in cookie
-func (r *Repository) Create(ctx context.Context, userID int64, ipAddress, userAgent string) (uuid.UUID, error) {
-	sessionID := uuid.New()
-
+// HOW: INSERT with user_id, ip, user_agent, ID is generated by Service
+func (r *Repository) Create(ctx context.Context, sess *Session) error {
 	query, args, err := psql.
 		Insert("sessions").
-		Columns("id", "user_id", "ip_address", "user_agent").
-		Values(sessionID, userID, ipAddress, userAgent).
+		Columns("id", "user_id", "created_at", "last_seen_at", "ip_address", "user_agent").
+		Values(sess.ID, sess.UserID, sess.CreatedAt, sess.LastSeenAt, sess.IPAddress, sess.UserAgent).
 		ToSql()
 
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to build insert query: %w", err)
+		return fmt.Errorf("failed to build insert query: %w", err)
 	}
 
 	_, err = r.db.Exec(ctx, query, args...)
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to create session: %w", err)
+		return fmt.Errorf("failed to create session: %w", err)
 	}
 
-	return sessionID, nil
+	return nil
 }
 
 // GetUserBySessionID retrieves user by session ID
 // WHY: Authenticate user from cookie
-// HOW: JOIN sessions with users table
+// HOW: JOIN sessions with users table, filter out sessions expired by policy
+// and sessions of suspended users, roles are loaded by the same query
 //
-// Returns user if session valid, error if not found
-func (r *Repository) GetUserBySessionID(ctx context.Context, sessionID uuid.UUID) (*user.User, error) {
+// Returns user and session if session valid, ErrSessionNotFound if missing or expired
+func (r *Repository) GetUserBySessionID(ctx context.Context, sessionID uuid.UUID) (*user.User, *Session, error) {
 	query, args, err := psql.
 		Select(
 			"u.id",
@@ -68,17 +75,27 @@ func (r *Repository) GetUserBySessionID(ctx context.Context, sessionID uuid.UUID
 			"u.score",
 			"u.is_verified",
 			"u.avatar_url",
+			"u.suspended_at",
+			user.RolesColumn("u.id"),
+			"s.created_at",
+			"s.last_seen_at",
+			"COALESCE(s.ip_address, '')",
+			"COALESCE(s.user_agent, '')",
 		).
 		From("sessions s").
 		Join("users u ON u.id = s.user_id").
+		Where(sq.Eq{"u.suspended_at": nil}).
 		Where(sq.Eq{"s.id": sessionID}).
+		Where(r.policy.aliveCond("s.", time.Now().UTC())).
 		ToSql()
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to build select query: %w", err)
+		return nil, nil, fmt.Errorf("failed to build select query: %w", err)
 	}
 
 	var u user.User
+	var roles []string
+	s := Session{ID: sessionID}
 	err = r.db.QueryRow(ctx, query, args...).Scan(
 		&u.ID,
 		&u.Name,
@@ -91,16 +108,91 @@ func (r *Repository) GetUserBySessionID(ctx context.Context, sessionID uuid.UUID
 		&u.Score,
 		&u.IsVerified,
 		&u.AvatarURL,
+		&u.SuspendedAt,
+		&roles,
+		&s.CreatedAt,
+		&s.LastSeenAt,
+		&s.IPAddress,
+		&s.UserAgent,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("session not found")
+		return nil, nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user by session: %w", err)
+	}
+
+	u.Roles = user.ParseRoles(roles)
+	s.UserID = u.ID
+
+	return &u, &s, nil
+}
+
+// ListByUserID returns all alive sessions of a user, most recently used first
+// WHY: "Active devices" page
+// HOW: SELECT ... WHERE user_id = ? ORDER BY last_seen_at DESC
+func (r *Repository) ListByUserID(ctx context.Context, userID int64) ([]Session, error) {
+	query, args, err := psql.
+		Select(
+			"id",
+			"user_id",
+			"created_at",
+			"last_seen_at",
+			"COALESCE(ip_address, '')",
+			"COALESCE(user_agent, '')",
+		).
+		From("sessions").
+		Where(sq.Eq{"user_id": userID}).
+		Where(r.policy.aliveCond("", time.Now().UTC())).
+		OrderBy("last_seen_at DESC").
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		var s Session
+		if err := rows.Scan(&s.ID, &s.UserID, &s.CreatedAt, &s.LastSeenAt, &s.IPAddress, &s.UserAgent); err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate sessions: %w", err)
 	}
+
+	return sessions, nil
+}
+
+// Touch updates last_seen_at of a session
+// WHY: Show when each device was last used
+// HOW: UPDATE sessions SET last_seen_at = ? WHERE id = ?
+func (r *Repository) Touch(ctx context.Context, sessionID uuid.UUID, seenAt time.Time) error {
+	query, args, err := psql.
+		Update("sessions").
+		Set("last_seen_at", seenAt).
+		Where(sq.Eq{"id": sessionID}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	_, err = r.db.Exec(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user by session: %w", err)
+		return fmt.Errorf("failed to touch session: %w", err)
 	}
 
-	return &u, nil
+	return nil
 }
 
 // Delete deletes session by ID (for logout)
@@ -123,3 +215,74 @@ func (r *Repository) Delete(ctx context.Context, sessionID uuid.UUID) error {
 
 	return nil
 }
+
+// DeleteByUserID deletes all sessions of a user
+// WHY: Log out everywhere after password reset or account compromise
+// HOW: DELETE FROM sessions WHERE user_id = ?
+func (r *Repository) DeleteByUserID(ctx context.Context, userID int64) error {
+	query, args, err := psql.
+		Delete("sessions").
+		Where(sq.Eq{"user_id": userID}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	_, err = r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete user sessions: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteByUserIDExcept deletes all sessions of a user except one
+// WHY: "Log out all other devices" keeps the current session alive
+// HOW: DELETE FROM sessions WHERE user_id = ? AND id <> ?
+func (r *Repository) DeleteByUserIDExcept(ctx context.Context, userID int64, keepID uuid.UUID) error {
+	query, args, err := psql.
+		Delete("sessions").
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.NotEq{"id": keepID}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	_, err = r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to delete other sessions: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired deletes sessions expired by policy
+// WHY: Keep sessions table small, expired rows are useless
+// HOW: DELETE FROM sessions WHERE created_at <= ? OR last_seen_at <= ?
+//
+// Returns number of deleted sessions
+func (r *Repository) DeleteExpired(ctx context.Context) (int64, error) {
+	cond := r.policy.expiredCond(time.Now().UTC())
+	if cond == nil {
+		return 0, nil
+	}
+
+	query, args, err := psql.
+		Delete("sessions").
+		Where(cond).
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired sessions: %w", err)
+	}
+
+	return tag.RowsAffected(), nil
+}
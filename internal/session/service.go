@@ -2,43 +2,210 @@ package session
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/google/uuid"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/udisondev/learn-go/internal/user"
+	"github.com/udisondev/learn-go/pkg/config"
 )
 
 // Service handles session business logic
 type Service struct {
-	repo *Repository
+	store         Store
+	locator       Locator
+	policy        Policy
+	touchInterval time.Duration
+	secureCookie  bool
 }
 
-// NewService creates new session service
-func NewService(db *pgxpool.Pool) *Service {
+// NewService creates new session service on top of the given store (see NewStore)
+func NewService(store Store, cfg config.SessionConfig, locator Locator) *Service {
+	policy := PolicyFromConfig(cfg)
+
+	// last_seen_at is only written once per touchInterval,
+	// so it must be refreshed well before the idle timeout fires
+	touchInterval := cfg.TouchInterval
+	if policy.IdleTimeout > 0 && touchInterval > policy.IdleTimeout/2 {
+		touchInterval = policy.IdleTimeout / 2
+	}
+
 	return &Service{
-		repo: NewRepository(db),
+		store:         store,
+		locator:       locator,
+		policy:        policy,
+		touchInterval: touchInterval,
+		secureCookie:  cfg.Secure,
 	}
 }
 
 // CreateSession creates new session for user
 // WHY: Called after successful login or email verification
-// HOW: Generate UUID session token and store in DB
+// HOW: Generate UUID v7 session token and save it to the store
+// UUID v7 is time-ordered (compact B-tree index) and has 74 random bits
 //
-// Returns session ID to set in cookie
-func (s *Service) CreateSession(ctx context.Context, userID int64, ipAddress, userAgent string) (uuid.UUID, error) {
-	return s.repo.Create(ctx, userID, ipAddress, userAgent)
+// Returns created session, use Cookie to build the session cookie
+func (s *Service) CreateSession(ctx context.Context, userID int64, ipAddress, userAgent string) (*Session, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session ID: %w", err)
+	}
+
+	now := time.Now().UTC()
+	sess := &Session{
+		ID:         id,
+		UserID:     userID,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+	}
+
+	if err := s.store.Create(ctx, sess); err != nil {
+		return nil, err
+	}
+
+	return sess, nil
 }
 
-// GetUserBySessionID retrieves user by session ID
+// GetUserBySessionID retrieves user and session by session ID
 // WHY: Authenticate user in middleware
-// HOW: Query DB for session and return associated user
-func (s *Service) GetUserBySessionID(ctx context.Context, sessionID uuid.UUID) (*user.User, error) {
-	return s.repo.GetUserBySessionID(ctx, sessionID)
+// HOW: Load session with its user from the store, then check policy again -
+// a cached copy may have outlived the session
+func (s *Service) GetUserBySessionID(ctx context.Context, sessionID uuid.UUID) (*user.User, *Session, error) {
+	u, sess, err := s.store.GetUserBySessionID(ctx, sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !s.policy.Alive(sess, time.Now().UTC()) {
+		return nil, nil, ErrSessionNotFound
+	}
+
+	return u, sess, nil
+}
+
+// Touch records activity of a session
+// WHY: Keep last_seen_at fresh without writing to DB on every request,
+// it drives both the "active devices" page and the idle timeout
+// HOW: Update only if last_seen_at is older than touchInterval
+//
+// Returns true if the row was actually updated - caller should renew the cookie
+func (s *Service) Touch(ctx context.Context, sess *Session) (bool, error) {
+	now := time.Now().UTC()
+	if now.Sub(sess.LastSeenAt) < s.touchInterval {
+		return false, nil
+	}
+
+	if err := s.store.Touch(ctx, sess.ID, now); err != nil {
+		return false, err
+	}
+	sess.LastSeenAt = now
+
+	return true, nil
+}
+
+// ListDevices returns user's sessions prepared for the "active devices" page
+// WHY: Let users review where they are logged in
+// HOW: Load sessions, parse user agents, resolve locations, mark current one
+func (s *Service) ListDevices(ctx context.Context, userID int64, currentID uuid.UUID) ([]Device, error) {
+	sessions, err := s.store.ListByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]Device, 0, len(sessions))
+	for _, sess := range sessions {
+		browser, os := ParseUserAgent(sess.UserAgent)
+		devices = append(devices, Device{
+			Session:  sess,
+			Handle:   sess.Handle(),
+			Browser:  browser,
+			OS:       os,
+			Location: s.locator.Locate(sess.IPAddress),
+			Current:  sess.ID == currentID,
+		})
+	}
+
+	return devices, nil
+}
+
+// RevokeDevice deletes one of user's sessions by its public handle
+// WHY: "Log out this device" button on the active devices page
+// HOW: Find session among user's own sessions (ownership check), delete by ID
+//
+// Returns ErrSessionNotFound if handle doesn't belong to the user
+func (s *Service) RevokeDevice(ctx context.Context, userID int64, handle string) error {
+	sessions, err := s.store.ListByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, sess := range sessions {
+		if sess.Handle() == handle {
+			return s.store.Delete(ctx, sess.ID)
+		}
+	}
+
+	return ErrSessionNotFound
+}
+
+// RevokeOtherDevices deletes all user's sessions except the current one
+// WHY: "Log out all other devices" button
+// HOW: Single DELETE with exclusion of current session ID
+func (s *Service) RevokeOtherDevices(ctx context.Context, userID int64, currentID uuid.UUID) error {
+	return s.store.DeleteByUserIDExcept(ctx, userID, currentID)
 }
 
 // DeleteSession deletes session (logout)
 // WHY: Invalidate current session on logout
-// HOW: Remove session from DB by ID
+// HOW: Remove session from the store by ID
 func (s *Service) DeleteSession(ctx context.Context, sessionID uuid.UUID) error {
-	return s.repo.Delete(ctx, sessionID)
+	return s.store.Delete(ctx, sessionID)
+}
+
+// DeleteUserSessions deletes all sessions of a user
+// WHY: Revoke every device after password reset
+// HOW: Remove all sessions with given user_id from the store
+func (s *Service) DeleteUserSessions(ctx context.Context, userID int64) error {
+	return s.store.DeleteByUserID(ctx, userID)
+}
+
+// InvalidateUser drops cached sessions of a user
+// WHY: User is cached together with the session, changes to the user
+// (email verified, plan, roles) must be visible on the next request
+// HOW: No-op unless the store is cached
+func (s *Service) InvalidateUser(userID int64) {
+	if c, ok := s.store.(*CachedStore); ok {
+		c.InvalidateUser(userID)
+	}
+}
+
+// RunSweeper periodically deletes expired sessions until ctx is cancelled
+// WHY: Expired sessions are already rejected on read, but rows pile up forever
+// HOW: Ticker with configured interval, errors are logged and retried next tick
+func (s *Service) RunSweeper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			deleted, err := s.store.DeleteExpired(ctx)
+			if err != nil {
+				slog.Error("Failed to sweep expired sessions", "error", err)
+				continue
+			}
+			if deleted > 0 {
+				slog.Info("Expired sessions swept", "count", deleted)
+			}
+		}
+	}
 }
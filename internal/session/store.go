@@ -0,0 +1,66 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/udisondev/learn-go/internal/user"
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+// Store persists sessions
+// WHY: Postgres is the source of truth in production, but tests and local
+// dev shouldn't need a database, and the per-request lookup is worth caching
+// HOW: Service works only through this interface, backends are swapped in NewStore:
+// - Repository: Postgres
+// - MemoryStore: process memory, sessions are lost on restart
+// - CachedStore: read-through LRU in front of any other Store
+//
+// Reads must skip sessions expired by Policy, the sweeper deletes them later
+type Store interface {
+	Create(ctx context.Context, sess *Session) error
+	GetUserBySessionID(ctx context.Context, sessionID uuid.UUID) (*user.User, *Session, error)
+	ListByUserID(ctx context.Context, userID int64) ([]Session, error)
+	Touch(ctx context.Context, sessionID uuid.UUID, seenAt time.Time) error
+	Delete(ctx context.Context, sessionID uuid.UUID) error
+	DeleteByUserID(ctx context.Context, userID int64) error
+	DeleteByUserIDExcept(ctx context.Context, userID int64, keepID uuid.UUID) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+// UserLoader loads session owners for stores that don't keep users themselves
+// Implemented by user.Service
+type UserLoader interface {
+	GetUserByID(ctx context.Context, userID int64) (*user.User, error)
+}
+
+// Store backends (SESSION_STORE)
+const (
+	StorePostgres = "postgres"
+	StoreMemory   = "memory"
+)
+
+// NewStore builds session store from configuration
+// Cache is added on top of the backend when SESSION_CACHE_SIZE > 0
+func NewStore(cfg config.SessionConfig, db *pgxpool.Pool, users UserLoader) (Store, error) {
+	policy := PolicyFromConfig(cfg)
+
+	var store Store
+	switch cfg.Store {
+	case StorePostgres:
+		store = NewRepository(db, policy)
+	case StoreMemory:
+		store = NewMemoryStore(users, policy)
+	default:
+		return nil, fmt.Errorf("unknown session store %q", cfg.Store)
+	}
+
+	if cfg.CacheSize > 0 && cfg.CacheTTL > 0 {
+		store = NewCachedStore(store, cfg.CacheSize, cfg.CacheTTL)
+	}
+
+	return store, nil
+}
@@ -0,0 +1,77 @@
+package session
+
+import (
+	"strings"
+)
+
+// uaRule maps a user agent token to a human readable name
+type uaRule struct {
+	token string // substring to look for in the user agent
+	name  string // display name
+}
+
+// browserRules are checked in order - more specific tokens first
+// WHY: Chrome-based browsers also contain "Chrome" and "Safari" tokens,
+// Chrome itself contains "Safari", so generic names must come last
+var browserRules = []uaRule{
+	{token: "Edg/", name: "Edge"},
+	{token: "OPR/", name: "Opera"},
+	{token: "YaBrowser/", name: "Яндекс Браузер"},
+	{token: "Vivaldi/", name: "Vivaldi"},
+	{token: "Firefox/", name: "Firefox"},
+	{token: "FxiOS/", name: "Firefox"},
+	{token: "CriOS/", name: "Chrome"},
+	{token: "Chrome/", name: "Chrome"},
+	{token: "Version/", name: "Safari"},
+	{token: "curl/", name: "curl"},
+}
+
+// osRules are checked in order - mobile platforms before desktop ones
+// WHY: Android user agents contain "Linux", iOS ones contain "Mac OS X"
+var osRules = []uaRule{
+	{token: "Android", name: "Android"},
+	{token: "iPhone", name: "iOS"},
+	{token: "iPad", name: "iPadOS"},
+	{token: "Windows", name: "Windows"},
+	{token: "Mac OS X", name: "macOS"},
+	{token: "CrOS", name: "ChromeOS"},
+	{token: "Linux", name: "Linux"},
+}
+
+// ParseUserAgent extracts browser (with major version) and OS from user agent
+// WHY: Raw user agent strings are unreadable on the "active devices" page
+// HOW: Ordered substring rules; unknown agents are reported as "Неизвестно"
+//
+// This is intentionally not a full UA parser - we only need a hint
+// that lets the user recognize their own device
+func ParseUserAgent(ua string) (browser, os string) {
+	browser, os = "Неизвестно", "Неизвестно"
+
+	for _, rule := range browserRules {
+		if idx := strings.Index(ua, rule.token); idx >= 0 {
+			browser = rule.name
+			if version := majorVersion(ua[idx+len(rule.token):]); version != "" {
+				browser += " " + version
+			}
+			break
+		}
+	}
+
+	for _, rule := range osRules {
+		if strings.Contains(ua, rule.token) {
+			os = rule.name
+			break
+		}
+	}
+
+	return browser, os
+}
+
+// majorVersion returns leading digits of a version string ("120.0.1" -> "120")
+func majorVersion(s string) string {
+	end := 0
+	for end < len(s) && s[end] >= '0' && s[end] <= '9' {
+		end++
+	}
+	return s[:end]
+}
@@ -3,15 +3,34 @@ package templates
 import (
 	"html/template"
 	"net/http"
+	"time"
 
 	"github.com/Masterminds/sprig/v3"
+	"github.com/udisondev/learn-go/internal/apitoken"
+	"github.com/udisondev/learn-go/internal/impersonation"
+	"github.com/udisondev/learn-go/internal/middleware"
+	"github.com/udisondev/learn-go/internal/oauth"
+	"github.com/udisondev/learn-go/internal/session"
+	"github.com/udisondev/learn-go/internal/twofactor"
 	"github.com/udisondev/learn-go/internal/user"
 )
 
 type Templates struct {
-	landingTmpl  *template.Template
-	registerTmpl *template.Template
-	loginTmpl    *template.Template
+	landingTmpl        *template.Template
+	registerTmpl       *template.Template
+	loginTmpl          *template.Template
+	verifyEmailTmpl    *template.Template
+	forgotPasswordTmpl *template.Template
+	resetPasswordTmpl  *template.Template
+	sessionsTmpl       *template.Template
+	securityTmpl       *template.Template
+	upgradeTmpl        *template.Template
+	tokensTmpl         *template.Template
+	magicLinkTmpl      *template.Template
+	activityTmpl       *template.Template
+	adminUsersTmpl     *template.Template
+	adminUserTmpl      *template.Template
+	flashTmpl          *template.Template
 }
 
 // Init parses and loads all templates
@@ -42,6 +61,7 @@ func Init() (*Templates, error) {
 	registerTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
 		"web/templates/layouts/auth.html",
 		"web/templates/components/register-form.html",
+		"web/templates/components/oauth-buttons.html",
 		"web/templates/pages/register.html",
 	)
 	if err != nil {
@@ -52,26 +72,165 @@ func Init() (*Templates, error) {
 	loginTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
 		"web/templates/layouts/auth.html",
 		"web/templates/components/login-form.html",
+		"web/templates/components/two-factor-form.html",
+		"web/templates/components/resend-verification.html",
+		"web/templates/components/oauth-buttons.html",
 		"web/templates/pages/login.html",
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// Parse expired verification link page templates
+	verifyEmailTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
+		"web/templates/layouts/auth.html",
+		"web/templates/components/resend-verification.html",
+		"web/templates/pages/verify-email.html",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse forgot password page templates
+	forgotPasswordTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
+		"web/templates/layouts/auth.html",
+		"web/templates/components/forgot-password-form.html",
+		"web/templates/pages/forgot-password.html",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse reset password page templates
+	resetPasswordTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
+		"web/templates/layouts/auth.html",
+		"web/templates/components/reset-password-form.html",
+		"web/templates/pages/reset-password.html",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse magic link sign-in page templates
+	magicLinkTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
+		"web/templates/layouts/auth.html",
+		"web/templates/components/magic-link-form.html",
+		"web/templates/pages/magic-link.html",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse active devices page templates
+	sessionsTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
+		"web/templates/layouts/base.html",
+		"web/templates/components/header.html",
+		"web/templates/components/sessions-list.html",
+		"web/templates/pages/sessions.html",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse security settings page templates
+	securityTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
+		"web/templates/layouts/base.html",
+		"web/templates/components/header.html",
+		"web/templates/components/two-factor-section.html",
+		"web/templates/pages/security.html",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse upgrade prompt page templates
+	upgradeTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
+		"web/templates/layouts/base.html",
+		"web/templates/components/header.html",
+		"web/templates/pages/upgrade.html",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse access tokens page templates
+	tokensTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
+		"web/templates/layouts/base.html",
+		"web/templates/components/header.html",
+		"web/templates/components/tokens-section.html",
+		"web/templates/pages/tokens.html",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse recent security activity page templates
+	activityTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
+		"web/templates/layouts/base.html",
+		"web/templates/components/header.html",
+		"web/templates/pages/activity.html",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse admin user search page templates
+	adminUsersTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
+		"web/templates/layouts/base.html",
+		"web/templates/components/header.html",
+		"web/templates/pages/admin-users.html",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse admin user card page templates
+	adminUserTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
+		"web/templates/layouts/base.html",
+		"web/templates/components/header.html",
+		"web/templates/components/admin-user-card.html",
+		"web/templates/pages/admin-user.html",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse flash messages (shown above any page, e.g. expired CSRF token)
+	flashTmpl, err := template.New("").Funcs(funcMap).ParseFiles(
+		"web/templates/components/csrf-error.html",
+		"web/templates/components/impersonation-denied.html",
+		"web/templates/components/rate-limited.html",
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Templates{
-		landingTmpl:  landingTmpl,
-		registerTmpl: registerTmpl,
-		loginTmpl:    loginTmpl,
+		landingTmpl:        landingTmpl,
+		registerTmpl:       registerTmpl,
+		loginTmpl:          loginTmpl,
+		verifyEmailTmpl:    verifyEmailTmpl,
+		forgotPasswordTmpl: forgotPasswordTmpl,
+		resetPasswordTmpl:  resetPasswordTmpl,
+		sessionsTmpl:       sessionsTmpl,
+		securityTmpl:       securityTmpl,
+		upgradeTmpl:        upgradeTmpl,
+		tokensTmpl:         tokensTmpl,
+		magicLinkTmpl:      magicLinkTmpl,
+		activityTmpl:       activityTmpl,
+		adminUsersTmpl:     adminUsersTmpl,
+		adminUserTmpl:      adminUserTmpl,
+		flashTmpl:          flashTmpl,
 	}, nil
 }
 
 // RenderLanding renders the landing page
-func (t *Templates) RenderLanding(w http.ResponseWriter, data *LandingData) error {
+func (t *Templates) RenderLanding(w http.ResponseWriter, r *http.Request, data *LandingData) error {
 	// Set content type
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
 	// Execute base layout template with landing content
-	if err := t.landingTmpl.ExecuteTemplate(w, "base.html", data); err != nil {
+	if err := t.landingTmpl.ExecuteTemplate(w, "base.html", newPage(r, data)); err != nil {
 		return err
 	}
 
@@ -79,7 +238,8 @@ func (t *Templates) RenderLanding(w http.ResponseWriter, data *LandingData) erro
 }
 
 // Render renders a full page
-func (t *Templates) Render(w http.ResponseWriter, page string, data interface{}) error {
+// Request is needed for per-request values of the layout (CSRF token)
+func (t *Templates) Render(w http.ResponseWriter, r *http.Request, page string, data interface{}) error {
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
 	var tmpl *template.Template
@@ -90,16 +250,39 @@ func (t *Templates) Render(w http.ResponseWriter, page string, data interface{})
 		tmpl = t.registerTmpl
 	case "login.html":
 		tmpl = t.loginTmpl
+	case "verify-email.html":
+		tmpl = t.verifyEmailTmpl
+	case "forgot-password.html":
+		tmpl = t.forgotPasswordTmpl
+	case "reset-password.html":
+		tmpl = t.resetPasswordTmpl
+	case "magic-link.html":
+		tmpl = t.magicLinkTmpl
+	case "sessions.html":
+		tmpl = t.sessionsTmpl
+	case "security.html":
+		tmpl = t.securityTmpl
+	case "upgrade.html":
+		tmpl = t.upgradeTmpl
+	case "tokens.html":
+		tmpl = t.tokensTmpl
+	case "activity.html":
+		tmpl = t.activityTmpl
+	case "admin-users.html":
+		tmpl = t.adminUsersTmpl
+	case "admin-user.html":
+		tmpl = t.adminUserTmpl
 	default:
 		return nil
 	}
 
-	// Use auth layout for login/register, base layout for others
+	// Use auth layout for auth pages, base layout for others
 	layoutName := "base.html"
-	if page == "login.html" || page == "register.html" {
+	switch page {
+	case "login.html", "register.html", "verify-email.html", "forgot-password.html", "reset-password.html", "magic-link.html":
 		layoutName = "auth.html"
 	}
-	return tmpl.ExecuteTemplate(w, layoutName, data)
+	return tmpl.ExecuteTemplate(w, layoutName, newPage(r, data))
 }
 
 // RenderComponent renders a component (for HTMX partial updates)
@@ -116,6 +299,42 @@ func (t *Templates) RenderComponent(w http.ResponseWriter, component string, dat
 	case "login-form.html":
 		tmpl = t.loginTmpl
 		componentName = "login-form"
+	case "resend-verification.html":
+		tmpl = t.verifyEmailTmpl
+		componentName = "resend-verification"
+	case "forgot-password-form.html":
+		tmpl = t.forgotPasswordTmpl
+		componentName = "forgot-password-form"
+	case "reset-password-form.html":
+		tmpl = t.resetPasswordTmpl
+		componentName = "reset-password-form"
+	case "magic-link-form.html":
+		tmpl = t.magicLinkTmpl
+		componentName = "magic-link-form"
+	case "sessions-list.html":
+		tmpl = t.sessionsTmpl
+		componentName = "sessions-list"
+	case "two-factor-form.html":
+		tmpl = t.loginTmpl
+		componentName = "two-factor-form"
+	case "two-factor-section.html":
+		tmpl = t.securityTmpl
+		componentName = "two-factor-section"
+	case "tokens-section.html":
+		tmpl = t.tokensTmpl
+		componentName = "tokens-section"
+	case "admin-user-card.html":
+		tmpl = t.adminUserTmpl
+		componentName = "admin-user-card"
+	case "csrf-error.html":
+		tmpl = t.flashTmpl
+		componentName = "csrf-error"
+	case "impersonation-denied.html":
+		tmpl = t.flashTmpl
+		componentName = "impersonation-denied"
+	case "rate-limited.html":
+		tmpl = t.flashTmpl
+		componentName = "rate-limited"
 	default:
 		return nil
 	}
@@ -124,12 +343,12 @@ func (t *Templates) RenderComponent(w http.ResponseWriter, component string, dat
 }
 
 // RenderRegister renders the register page
-func (t *Templates) RenderRegister(w http.ResponseWriter, data *RegisterData) error {
+func (t *Templates) RenderRegister(w http.ResponseWriter, r *http.Request, data *RegisterData) error {
 	// Set content type
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
 	// Execute auth layout template with register content
-	if err := t.registerTmpl.ExecuteTemplate(w, "auth.html", data); err != nil {
+	if err := t.registerTmpl.ExecuteTemplate(w, "auth.html", newPage(r, data)); err != nil {
 		return err
 	}
 
@@ -149,6 +368,33 @@ func (t *Templates) RenderRegisterForm(w http.ResponseWriter, data *RegisterData
 	return nil
 }
 
+// Page is what layouts are executed with
+// WHY: Layouts need per-request values (CSRF token for the meta tag and
+// hx-headers, CSP nonce for scripts, impersonation banner) that don't belong to every page's data struct
+// HOW: Layouts read Page fields and pass Data to "title", "header" and "content",
+// so pages and components keep working with their own data as before.
+// Page scripts go to the "scripts" block, it gets the whole Page:
+//
+//	{{define "scripts"}}<script nonce="{{.Nonce}}">...</script>{{end}}
+type Page struct {
+	CSRFToken     string                // Masked CSRF token, empty if CSRF middleware is not mounted
+	Nonce         string                // CSP nonce, every <script> must carry it
+	Impersonation *impersonation.Active // Admin acts as another user - base layout shows a banner
+	Data          interface{}           // Page data (LoginData, SessionsData, ...)
+}
+
+// newPage wraps page data with per-request values
+func newPage(r *http.Request, data interface{}) *Page {
+	imp, _ := impersonation.FromCtx(r.Context())
+
+	return &Page{
+		CSRFToken:     middleware.CSRFToken(r),
+		Nonce:         middleware.CSPNonce(r),
+		Impersonation: imp,
+		Data:          data,
+	}
+}
+
 // Data structures
 
 type LandingData struct {
@@ -156,13 +402,130 @@ type LandingData struct {
 }
 
 type LoginData struct {
-	Email  string            // Preserved email on validation error
-	Errors map[string]string // Field-specific errors
+	Email      string               // Preserved email on validation error
+	Errors     map[string]string    // Field-specific errors, "form" - general error
+	Providers  []oauth.ProviderInfo // Enabled social login providers
+	TwoFactor  *TwoFactorLoginData  // Second factor pending after social login
+	Unverified bool                 // Password is correct but email is not verified - offer resend
+	Notice     string               // Informational message above the form
+	ReturnTo   string               // Safe local path to open after login
 }
 
 type RegisterData struct {
-	Errors map[string]string
+	Errors            map[string]string
+	Name              string
+	Email             string
+	Phone             string
+	Providers         []oauth.ProviderInfo // Enabled social login providers
+	PasswordMinLength int                  // For the password field hint
+}
+
+type ResendVerificationData struct {
+	Pending bool              // Logged in but not verified yet (not an expired link)
+	Sent    bool              // Request accepted (shown even if email is unknown)
+	Errors  map[string]string // Field-specific errors
+}
+
+type UpgradeData struct {
+	User     *user.User     // Authenticated user (for header)
+	Required user.SubPlan   // Minimal plan for the requested page
+	Plans    []user.SubPlan // All plans in ascending order
+}
+
+type ForgotPasswordData struct {
+	Email  string            // Preserved email
+	Sent   bool              // Request accepted, show "check your inbox"
+	Errors map[string]string // Field-specific errors
+}
+
+type ResetPasswordData struct {
+	Token             string            // Reset token from the email link
+	InvalidToken      bool              // Token expired, used or unknown
+	Done              bool              // Password changed successfully
+	Errors            map[string]string // Field-specific errors
+	PasswordMinLength int               // For the password field hint
+}
+
+type MagicLinkData struct {
+	Email        string            // Preserved email
+	Sent         bool              // Request accepted, show "check your inbox"
+	Confirm      bool              // Opened link from the email, show "sign in" button
+	Token        string            // Magic link token from the email
+	InvalidToken bool              // Token expired, used or unknown
+	Errors       map[string]string // Field-specific errors
+}
+
+type SessionsData struct {
+	User    *user.User       // Authenticated user (for header)
+	Devices []session.Device // All user's sessions, current one marked
+}
+
+type TwoFactorLoginData struct {
+	Token    string            // Pending login challenge token
+	Errors   map[string]string // Field-specific errors
+	ReturnTo string            // Safe local path to open after login
+}
+
+type SecurityData struct {
+	User          *user.User          // Authenticated user (for header)
+	TwoFactor     *twofactor.Status   // Current two-factor state
+	Setup         *TwoFactorSetupView // Pending enrollment (QR code), nil if not started
+	RecoveryCodes []string            // Freshly issued recovery codes, shown once
+	Errors        map[string]string   // Field-specific errors
+}
+
+type TwoFactorSetupView struct {
+	Secret string       // Base32 secret for manual entry
+	URI    string       // otpauth:// provisioning URI
+	QRCode template.URL // QR code as data URI
+}
+
+type TokensData struct {
+	User          *user.User        // Authenticated user (for header)
+	Tokens        []apitoken.Token  // All user's tokens, newest first
+	Scopes        []string          // Scope names for the create form
+	ExpiryOptions []int             // Expiry choices in days, 0 - never
+	Now           time.Time         // To mark expired tokens
+	Created       *CreatedTokenView // Freshly issued token, secret shown once
+	Name          string            // Preserved name on validation error
+	Errors        map[string]string // Field-specific errors, "form" - general error
+}
+
+type ActivityData struct {
+	User   *user.User          // Authenticated user (for header)
+	Events []ActivityEventView // Latest audit events, newest first
+}
+
+type ActivityEventView struct {
+	Label     string // Event name, e.g. "Вход в аккаунт"
+	Detail    string // Login method, failure reason, token name...
+	Warning   bool   // Highlight (failed login)
+	IP        string
+	Device    string // Browser and OS parsed from user agent
+	CreatedAt time.Time
+}
+
+type AdminUsersData struct {
+	User  *user.User  // Authenticated admin (for header)
+	Query string      // Search query: ID, part of email or name
+	Users []user.User // Found users, newest first
+}
+
+type AdminUserData struct {
+	User     *user.User          // Authenticated admin (for header)
+	Target   *user.User          // User the card is about
+	Plans    []user.SubPlan      // All plans in ascending order
+	Progress *user.Progress      // Exercises progress (page only)
+	Devices  []session.Device    // Sessions of the target user (page only)
+	Events   []ActivityEventView // Recent audit events of the target user (page only)
+	Notice   string              // Result of the last action (card only)
+}
+
+type RateLimitedData struct {
+	Wait string // When to retry, e.g. "через 40 сек."
+}
+
+type CreatedTokenView struct {
 	Name   string
-	Email  string
-	Phone  string
+	Secret string
 }
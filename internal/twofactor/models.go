@@ -0,0 +1,45 @@
+package twofactor
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrInvalidCode - TOTP code or recovery code didn't match
+	ErrInvalidCode = errors.New("invalid two-factor code")
+
+	// ErrNotEnabled - user has no confirmed two-factor setup
+	ErrNotEnabled = errors.New("two-factor authentication is not enabled")
+
+	// ErrAlreadyEnabled - enrollment requested while 2FA is already on
+	ErrAlreadyEnabled = errors.New("two-factor authentication is already enabled")
+
+	// ErrSetupNotStarted - confirm called without BeginSetup
+	ErrSetupNotStarted = errors.New("two-factor setup not started")
+
+	// ErrChallengeNotFound - login challenge expired, used or exhausted attempts
+	ErrChallengeNotFound = errors.New("two-factor challenge not found or expired")
+)
+
+// Status describes user's two-factor configuration for the profile page
+type Status struct {
+	Enabled           bool
+	EnabledAt         *time.Time
+	RecoveryCodesLeft int
+}
+
+// Setup holds data for the enrollment step (shown once, never stored in plain)
+type Setup struct {
+	Secret    string // base32 secret for manual entry
+	URI       string // otpauth:// provisioning URI
+	QRCodePNG []byte // URI encoded as QR code image
+}
+
+// record is a row of user_two_factor
+type record struct {
+	UserID          int64
+	SecretEncrypted string
+	EnabledAt       *time.Time
+	LastUsedStep    int64
+}
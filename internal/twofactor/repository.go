@@ -0,0 +1,308 @@
+package twofactor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+var psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
+
+// Repository handles two-factor data access operations
+type Repository struct {
+	db *pgxpool.Pool
+}
+
+// NewRepository creates new two-factor repository
+func NewRepository(db *pgxpool.Pool) *Repository {
+	return &Repository{db: db}
+}
+
+// Get returns user's two-factor record or ErrNotEnabled if there is none
+func (r *Repository) Get(ctx context.Context, userID int64) (*record, error) {
+	query, args, err := psql.
+		Select("user_id", "secret_encrypted", "enabled_at", "last_used_step").
+		From("user_two_factor").
+		Where(sq.Eq{"user_id": userID}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var rec record
+	err = r.db.QueryRow(ctx, query, args...).Scan(&rec.UserID, &rec.SecretEncrypted, &rec.EnabledAt, &rec.LastUsedStep)
+	if err == pgx.ErrNoRows {
+		return nil, ErrNotEnabled
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get two-factor record: %w", err)
+	}
+
+	return &rec, nil
+}
+
+// SavePending stores a new unconfirmed secret
+// WHY: Enrollment can be restarted any number of times until confirmed
+// HOW: Upsert, but never overwrite an already enabled setup
+func (r *Repository) SavePending(ctx context.Context, userID int64, secretEncrypted string) error {
+	query, args, err := psql.
+		Insert("user_two_factor").
+		Columns("user_id", "secret_encrypted", "created_at").
+		Values(userID, secretEncrypted, time.Now().UTC()).
+		Suffix(`ON CONFLICT (user_id) DO UPDATE
+			SET secret_encrypted = EXCLUDED.secret_encrypted,
+			    created_at = EXCLUDED.created_at,
+			    last_used_step = 0
+			WHERE user_two_factor.enabled_at IS NULL`).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to save pending secret: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAlreadyEnabled
+	}
+
+	return nil
+}
+
+// Enable confirms enrollment and stores fresh recovery codes
+// WHY: Confirmation and recovery codes must appear atomically -
+// 2FA without recovery codes would lock out users who lose their phone
+func (r *Repository) Enable(ctx context.Context, userID, step int64, codeHashes []string) error {
+	return pgx.BeginTxFunc(ctx, r.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		query, args, err := psql.
+			Update("user_two_factor").
+			Set("enabled_at", time.Now().UTC()).
+			Set("last_used_step", step).
+			Where(sq.Eq{"user_id": userID}).
+			Where(sq.Eq{"enabled_at": nil}).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build update query: %w", err)
+		}
+
+		tag, err := tx.Exec(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to enable two-factor: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrSetupNotStarted
+		}
+
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+}
+
+// ReplaceRecoveryCodes invalidates all recovery codes and stores new ones
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	return pgx.BeginTxFunc(ctx, r.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		return replaceRecoveryCodes(ctx, tx, userID, codeHashes)
+	})
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int64, codeHashes []string) error {
+	deleteQuery, deleteArgs, err := psql.
+		Delete("user_recovery_codes").
+		Where(sq.Eq{"user_id": userID}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, deleteQuery, deleteArgs...); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	now := time.Now().UTC()
+	insert := psql.
+		Insert("user_recovery_codes").
+		Columns("user_id", "code_hash", "created_at")
+	for _, hash := range codeHashes {
+		insert = insert.Values(userID, hash, now)
+	}
+
+	insertQuery, insertArgs, err := insert.ToSql()
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, insertQuery, insertArgs...); err != nil {
+		return fmt.Errorf("failed to insert recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+// AdvanceStep stores the time step of an accepted TOTP code
+// WHY: Replay protection - a code seen once must not work again,
+// even from a parallel request
+// HOW: Conditional UPDATE, returns false if a newer or same step is already stored
+func (r *Repository) AdvanceStep(ctx context.Context, userID, step int64) (bool, error) {
+	query, args, err := psql.
+		Update("user_two_factor").
+		Set("last_used_step", step).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Lt{"last_used_step": step}).
+		ToSql()
+
+	if err != nil {
+		return false, fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to advance step: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used
+// Returns false if code doesn't exist or was already used
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) (bool, error) {
+	query, args, err := psql.
+		Update("user_recovery_codes").
+		Set("used_at", time.Now().UTC()).
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"code_hash": codeHash}).
+		Where(sq.Eq{"used_at": nil}).
+		ToSql()
+
+	if err != nil {
+		return false, fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
+
+// CountRecoveryCodes returns number of unused recovery codes
+func (r *Repository) CountRecoveryCodes(ctx context.Context, userID int64) (int, error) {
+	query, args, err := psql.
+		Select("COUNT(*)").
+		From("user_recovery_codes").
+		Where(sq.Eq{"user_id": userID}).
+		Where(sq.Eq{"used_at": nil}).
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return count, nil
+}
+
+// Delete removes two-factor setup and recovery codes of a user
+func (r *Repository) Delete(ctx context.Context, userID int64) error {
+	return pgx.BeginTxFunc(ctx, r.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		for _, table := range []string{"user_recovery_codes", "two_factor_challenges", "user_two_factor"} {
+			query, args, err := psql.
+				Delete(table).
+				Where(sq.Eq{"user_id": userID}).
+				ToSql()
+
+			if err != nil {
+				return fmt.Errorf("failed to build delete query: %w", err)
+			}
+
+			if _, err := tx.Exec(ctx, query, args...); err != nil {
+				return fmt.Errorf("failed to delete from %s: %w", table, err)
+			}
+		}
+		return nil
+	})
+}
+
+// CreateChallenge stores a pending login waiting for the second factor
+func (r *Repository) CreateChallenge(ctx context.Context, userID int64, tokenHash string, expiresAt time.Time) error {
+	query, args, err := psql.
+		Insert("two_factor_challenges").
+		Columns("token_hash", "user_id", "expires_at", "created_at").
+		Values(tokenHash, userID, expiresAt, time.Now().UTC()).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to create challenge: %w", err)
+	}
+
+	return nil
+}
+
+// AttemptChallenge counts an attempt against a live challenge
+// WHY: Limits brute force of 6-digit codes within one challenge
+// HOW: Atomic increment, only for non-expired challenges under the limit
+//
+// Returns user_id of the challenge or ErrChallengeNotFound
+func (r *Repository) AttemptChallenge(ctx context.Context, tokenHash string, maxAttempts int) (int64, error) {
+	query, args, err := psql.
+		Update("two_factor_challenges").
+		Set("attempts", sq.Expr("attempts + 1")).
+		Where(sq.Eq{"token_hash": tokenHash}).
+		Where(sq.Gt{"expires_at": time.Now().UTC()}).
+		Where(sq.Lt{"attempts": maxAttempts}).
+		Suffix("RETURNING user_id").
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	var userID int64
+	err = r.db.QueryRow(ctx, query, args...).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		return 0, ErrChallengeNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to attempt challenge: %w", err)
+	}
+
+	return userID, nil
+}
+
+// DeleteChallenge removes a challenge after successful login
+// Also deletes expired challenges to keep the table small
+func (r *Repository) DeleteChallenge(ctx context.Context, tokenHash string) error {
+	query, args, err := psql.
+		Delete("two_factor_challenges").
+		Where(sq.Or{
+			sq.Eq{"token_hash": tokenHash},
+			sq.LtOrEq{"expires_at": time.Now().UTC()},
+		}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build delete query: %w", err)
+	}
+
+	if _, err := r.db.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to delete challenge: %w", err)
+	}
+
+	return nil
+}
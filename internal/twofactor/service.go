@@ -0,0 +1,308 @@
+package twofactor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/skip2/go-qrcode"
+	"github.com/udisondev/learn-go/pkg/config"
+)
+
+const (
+	// recoveryCodesCount - number of recovery codes issued at once
+	recoveryCodesCount = 10
+
+	// challengeMaxAttempts - wrong codes allowed per login challenge
+	challengeMaxAttempts = 5
+)
+
+// Service handles TOTP two-factor authentication
+// WHY: Optional second factor for accounts (premium users, mentors)
+// HOW: RFC 6238 TOTP secrets encrypted at rest, hashed single-use recovery codes,
+// short-lived login challenges between password check and session creation
+type Service struct {
+	repo         *Repository
+	sealer       *sealer
+	issuer       string
+	challengeTTL time.Duration
+}
+
+// NewService creates new two-factor service
+func NewService(db *pgxpool.Pool, cfg config.TwoFactorConfig) (*Service, error) {
+	s, err := newSealer(cfg.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Service{
+		repo:         NewRepository(db),
+		sealer:       s,
+		issuer:       cfg.Issuer,
+		challengeTTL: cfg.ChallengeTTL,
+	}, nil
+}
+
+// Status returns two-factor status for the profile page
+func (s *Service) Status(ctx context.Context, userID int64) (*Status, error) {
+	rec, err := s.repo.Get(ctx, userID)
+	if errors.Is(err, ErrNotEnabled) {
+		return &Status{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if rec.EnabledAt == nil {
+		return &Status{}, nil
+	}
+
+	left, err := s.repo.CountRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Status{
+		Enabled:           true,
+		EnabledAt:         rec.EnabledAt,
+		RecoveryCodesLeft: left,
+	}, nil
+}
+
+// IsEnabled reports whether login requires the second factor
+func (s *Service) IsEnabled(ctx context.Context, userID int64) (bool, error) {
+	status, err := s.Status(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	return status.Enabled, nil
+}
+
+// BeginSetup generates a new secret and stores it as pending
+// WHY: Secret becomes active only after the user proves the app is
+// configured (Confirm), otherwise a mistyped setup would lock them out
+//
+// account is shown in the authenticator app next to the issuer (usually email)
+func (s *Service) BeginSetup(ctx context.Context, userID int64, account string) (*Setup, error) {
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	sealed, err := s.sealer.seal(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.SavePending(ctx, userID, sealed); err != nil {
+		return nil, err
+	}
+
+	return s.newSetup(secret, account)
+}
+
+// ResumeSetup rebuilds setup data for a pending (unconfirmed) secret
+// WHY: When the confirmation code is wrong, the QR code must be shown again
+// without generating a new secret the user has already scanned
+func (s *Service) ResumeSetup(ctx context.Context, userID int64, account string) (*Setup, error) {
+	rec, err := s.repo.Get(ctx, userID)
+	if errors.Is(err, ErrNotEnabled) {
+		return nil, ErrSetupNotStarted
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rec.EnabledAt != nil {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := s.sealer.open(rec.SecretEncrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.newSetup(secret, account)
+}
+
+// Confirm enables two-factor after checking the first code from the app
+// Returns plain recovery codes - they are shown to the user exactly once
+func (s *Service) Confirm(ctx context.Context, userID int64, code string) ([]string, error) {
+	rec, err := s.repo.Get(ctx, userID)
+	if errors.Is(err, ErrNotEnabled) {
+		return nil, ErrSetupNotStarted
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rec.EnabledAt != nil {
+		return nil, ErrAlreadyEnabled
+	}
+
+	secret, err := s.sealer.open(rec.SecretEncrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := validateCode(secret, normalizeCode(code), time.Now(), rec.LastUsedStep)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Enable(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Verify checks a TOTP code or a recovery code of a user with enabled 2FA
+// WHY: Single entry point for login and sensitive profile actions
+// HOW: 6 digits → TOTP with replay protection, anything else → recovery code
+func (s *Service) Verify(ctx context.Context, userID int64, code string) error {
+	rec, err := s.repo.Get(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if rec.EnabledAt == nil {
+		return ErrNotEnabled
+	}
+
+	code = normalizeCode(code)
+
+	if isTOTPCode(code) {
+		secret, err := s.sealer.open(rec.SecretEncrypted)
+		if err != nil {
+			return err
+		}
+
+		step, ok := validateCode(secret, code, time.Now(), rec.LastUsedStep)
+		if !ok {
+			return ErrInvalidCode
+		}
+
+		// Parallel request may have used the same code a moment ago
+		advanced, err := s.repo.AdvanceStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !advanced {
+			return ErrInvalidCode
+		}
+
+		return nil
+	}
+
+	used, err := s.repo.UseRecoveryCode(ctx, userID, hashToken(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+
+	return nil
+}
+
+// Disable turns two-factor off after verifying a current code
+func (s *Service) Disable(ctx context.Context, userID int64, code string) error {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return err
+	}
+	return s.repo.Delete(ctx, userID)
+}
+
+// RegenerateRecoveryCodes issues a new set of recovery codes, old ones stop working
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// StartChallenge creates a pending login after the password was verified
+// Returns token that the "enter your code" form sends back
+func (s *Service) StartChallenge(ctx context.Context, userID int64) (string, error) {
+	token := generateToken()
+	expiresAt := time.Now().UTC().Add(s.challengeTTL)
+
+	if err := s.repo.CreateChallenge(ctx, userID, hashToken(token), expiresAt); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// CompleteChallenge verifies the second factor for a pending login
+// Returns user_id to create session for
+//
+// Errors:
+// - ErrChallengeNotFound: token expired, used or too many attempts - start over
+// - ErrInvalidCode: wrong code, user may try again (user_id is returned as well)
+func (s *Service) CompleteChallenge(ctx context.Context, token, code string) (int64, error) {
+	tokenHash := hashToken(token)
+
+	userID, err := s.repo.AttemptChallenge(ctx, tokenHash, challengeMaxAttempts)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.Verify(ctx, userID, code); err != nil {
+		return userID, err
+	}
+
+	if err := s.repo.DeleteChallenge(ctx, tokenHash); err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// newSetup builds provisioning URI and QR code for secret
+func (s *Service) newSetup(secret, account string) (*Setup, error) {
+	uri := provisioningURI(s.issuer, account, secret)
+
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, fmt.Errorf("encode qr code: %w", err)
+	}
+
+	return &Setup{
+		Secret:    secret,
+		URI:       uri,
+		QRCodePNG: png,
+	}, nil
+}
+
+// newRecoveryCodes generates plain codes and their hashes for storage
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodesCount)
+	hashes := make([]string, 0, recoveryCodesCount)
+
+	for range recoveryCodesCount {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeCode(code)))
+	}
+
+	return codes, hashes, nil
+}
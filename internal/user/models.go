@@ -1,6 +1,9 @@
 package user
 
-import "time"
+import (
+	"slices"
+	"time"
+)
 
 //go:generate go-enum --sql
 
@@ -8,6 +11,11 @@ import "time"
 // ENUM(free, basic, standard, premium)
 type SubPlan int
 
+// Role represents user role
+// Роли дополняют друг друга: автор курса может быть и ментором
+// ENUM(student, author, mentor, admin)
+type Role int
+
 // User represents a user in the system
 type User struct {
 	ID           int64
@@ -21,4 +29,64 @@ type User struct {
 	Score        int
 	IsVerified   bool
 	AvatarURL    *string
+	Roles        []Role     // Роли сверх обычного студента (author, mentor, admin)
+	SuspendedAt  *time.Time // Аккаунт заблокирован администратором (nil - активен)
+}
+
+// HasRole проверяет что у пользователя есть хотя бы одна из ролей
+// Студент - роль по умолчанию, она есть у всех
+func (u *User) HasRole(roles ...Role) bool {
+	for _, want := range roles {
+		if want == RoleStudent || slices.Contains(u.Roles, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin проверяет что пользователь - администратор (ссылка на админку в шапке)
+func (u *User) IsAdmin() bool {
+	return u.HasRole(RoleAdmin)
+}
+
+// IsSuspended проверяет что аккаунт заблокирован
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// ParseRoles преобразует значения user_roles.role в роли
+// Неизвестные роли (удаленные из кода) пропускаются, а не выдаются
+// Студент не хранится в БД - он есть у всех
+func ParseRoles(names []string) []Role {
+	roles := make([]Role, 0, len(names))
+	for _, name := range names {
+		if r, err := ParseRole(name); err == nil && r != RoleStudent {
+			roles = append(roles, r)
+		}
+	}
+	return roles
+}
+
+// HasSubPlan проверяет что тариф пользователя не ниже требуемого
+// Тарифы упорядочены: free < basic < standard < premium
+func (u *User) HasSubPlan(min SubPlan) bool {
+	return u.SubPlan >= min
+}
+
+// Progress - прогресс пользователя по упражнениям курса (для админки)
+type Progress struct {
+	Completed int // Решено упражнений
+	Started   int // Упражнений с хотя бы одной попыткой
+	Attempts  int // Всего попыток
+}
+
+// ExternalIdentity - профиль пользователя у внешнего провайдера (GitHub, OIDC)
+// Заполняется провайдером после OAuth2 обмена кода на токен
+type ExternalIdentity struct {
+	Provider      string  // "github"
+	Subject       string  // Стабильный ID пользователя у провайдера (не email - email может смениться)
+	Email         string  // Основной email у провайдера
+	EmailVerified bool    // Провайдер подтвердил владение email
+	Name          string  // Отображаемое имя
+	AvatarURL     *string // Аватар у провайдера (если есть)
 }
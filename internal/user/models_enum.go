@@ -11,6 +11,131 @@ import (
 	"fmt"
 )
 
+const (
+	// RoleStudent is a Role of type Student.
+	RoleStudent Role = iota
+	// RoleAuthor is a Role of type Author.
+	RoleAuthor
+	// RoleMentor is a Role of type Mentor.
+	RoleMentor
+	// RoleAdmin is a Role of type Admin.
+	RoleAdmin
+)
+
+var ErrInvalidRole = errors.New("not a valid Role")
+
+const _RoleName = "studentauthormentoradmin"
+
+var _RoleMap = map[Role]string{
+	RoleStudent: _RoleName[0:7],
+	RoleAuthor:  _RoleName[7:13],
+	RoleMentor:  _RoleName[13:19],
+	RoleAdmin:   _RoleName[19:24],
+}
+
+// String implements the Stringer interface.
+func (x Role) String() string {
+	if str, ok := _RoleMap[x]; ok {
+		return str
+	}
+	return fmt.Sprintf("Role(%d)", x)
+}
+
+// IsValid provides a quick way to determine if the typed value is
+// part of the allowed enumerated values
+func (x Role) IsValid() bool {
+	_, ok := _RoleMap[x]
+	return ok
+}
+
+var _RoleValue = map[string]Role{
+	_RoleName[0:7]:   RoleStudent,
+	_RoleName[7:13]:  RoleAuthor,
+	_RoleName[13:19]: RoleMentor,
+	_RoleName[19:24]: RoleAdmin,
+}
+
+// ParseRole attempts to convert a string to a Role.
+func ParseRole(name string) (Role, error) {
+	if x, ok := _RoleValue[name]; ok {
+		return x, nil
+	}
+	return Role(0), fmt.Errorf("%s is %w", name, ErrInvalidRole)
+}
+
+var errRoleNilPtr = errors.New("value pointer is nil") // one per type for package clashes
+
+// Scan implements the Scanner interface.
+func (x *Role) Scan(value interface{}) (err error) {
+	if value == nil {
+		*x = Role(0)
+		return
+	}
+
+	// A wider range of scannable types.
+	// driver.Value values at the top of the list for expediency
+	switch v := value.(type) {
+	case int64:
+		*x = Role(v)
+	case string:
+		*x, err = ParseRole(v)
+	case []byte:
+		*x, err = ParseRole(string(v))
+	case Role:
+		*x = v
+	case int:
+		*x = Role(v)
+	case *Role:
+		if v == nil {
+			return errRoleNilPtr
+		}
+		*x = *v
+	case uint:
+		*x = Role(v)
+	case uint64:
+		*x = Role(v)
+	case *int:
+		if v == nil {
+			return errRoleNilPtr
+		}
+		*x = Role(*v)
+	case *int64:
+		if v == nil {
+			return errRoleNilPtr
+		}
+		*x = Role(*v)
+	case float64: // json marshals everything as a float64 if it's a number
+		*x = Role(v)
+	case *float64: // json marshals everything as a float64 if it's a number
+		if v == nil {
+			return errRoleNilPtr
+		}
+		*x = Role(*v)
+	case *uint:
+		if v == nil {
+			return errRoleNilPtr
+		}
+		*x = Role(*v)
+	case *uint64:
+		if v == nil {
+			return errRoleNilPtr
+		}
+		*x = Role(*v)
+	case *string:
+		if v == nil {
+			return errRoleNilPtr
+		}
+		*x, err = ParseRole(*v)
+	}
+
+	return
+}
+
+// Value implements the driver Valuer interface.
+func (x Role) Value() (driver.Value, error) {
+	return x.String(), nil
+}
+
 const (
 	// SubPlanFree is a SubPlan of type Free.
 	SubPlanFree SubPlan = iota
@@ -6,6 +6,8 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
@@ -15,6 +17,14 @@ import (
 
 var psql = sq.StatementBuilder.PlaceholderFormat(sq.Dollar)
 
+// passwordResetTTL - время жизни ссылки для сброса пароля
+// Совпадает с текстом в web/templates/email/password_reset.html
+const passwordResetTTL = time.Hour
+
+// MagicLinkTTL - время жизни ссылки для входа без пароля
+// Короче сброса пароля: ссылка сразу дает сессию
+const MagicLinkTTL = 15 * time.Minute
+
 // Repository handles user data access operations
 // Изолирует бизнес-логику от деталей работы с БД
 // Использует squirrel для type-safe построения SQL запросов
@@ -146,6 +156,40 @@ func (r *Repository) CreateEmailVerification(ctx context.Context, tx pgx.Tx, use
 	return emailToken, nil
 }
 
+// ReplaceEmailVerification выпускает новую ссылку верификации взамен старых
+// Вызывается при повторной отправке письма
+//
+// Почему удаляем старые токены:
+// - Действительна только ссылка из последнего письма
+// - Удаление и вставка в одной транзакции - нет окна без токена или с двумя
+func (r *Repository) ReplaceEmailVerification(ctx context.Context, userID int64) (string, error) {
+	var emailToken string
+
+	err := pgx.BeginTxFunc(ctx, r.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		query, args, err := psql.
+			Delete("email_verifications").
+			Where(sq.Eq{"user_id": userID}).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build delete query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to delete old verifications: %w", err)
+		}
+
+		emailToken, err = r.CreateEmailVerification(ctx, tx, userID)
+		return err
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return emailToken, nil
+}
+
 // GetUserByEmail получает пользователя по email
 // Используется при email verification для обновления is_verified
 //
@@ -155,7 +199,7 @@ func (r *Repository) CreateEmailVerification(ctx context.Context, tx pgx.Tx, use
 // - Централизованное место для загрузки пользователя
 func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	query, args, err := psql.
-		Select("id", "name", "email", "password_hash", "phone", "registered_at", "updated_at", "sub_plan", "score", "is_verified", "avatar_url").
+		Select(userColumns...).
 		From("users").
 		Where(sq.Eq{"email": email}).
 		ToSql()
@@ -165,22 +209,10 @@ func (r *Repository) GetUserByEmail(ctx context.Context, email string) (*User, e
 	}
 
 	user := &User{}
-	err = r.db.QueryRow(ctx, query, args...).Scan(
-		&user.ID,
-		&user.Name,
-		&user.Email,
-		&user.PasswordHash,
-		&user.Phone,
-		&user.RegisteredAt,
-		&user.UpdatedAt,
-		&user.SubPlan,
-		&user.Score,
-		&user.IsVerified,
-		&user.AvatarURL,
-	)
+	err = scanUser(r.db.QueryRow(ctx, query, args...), user)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, ErrUserNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -232,7 +264,7 @@ func (r *Repository) VerifyEmail(ctx context.Context, emailToken string) (int64,
 
 		err = tx.QueryRow(ctx, query, args...).Scan(&userID)
 		if err == pgx.ErrNoRows {
-			return fmt.Errorf("invalid or expired token")
+			return ErrInvalidToken
 		}
 		if err != nil {
 			return fmt.Errorf("failed to find verification: %w", err)
@@ -280,6 +312,612 @@ func (r *Repository) VerifyEmail(ctx context.Context, emailToken string) (int64,
 	return userID, nil
 }
 
+// GetUserByID получает пользователя по ID
+// Используется после операций с токенами, когда известен только user_id
+func (r *Repository) GetUserByID(ctx context.Context, userID int64) (*User, error) {
+	query, args, err := psql.
+		Select(userColumns...).
+		From("users").
+		Where(sq.Eq{"id": userID}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	user := &User{}
+	err = scanUser(r.db.QueryRow(ctx, query, args...), user)
+
+	if err == pgx.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return user, nil
+}
+
+// SearchUsers ищет пользователей для админки
+// query - ID, часть email или имени (без учета регистра), пустой query - последние зарегистрированные
+func (r *Repository) SearchUsers(ctx context.Context, query string, limit int) ([]User, error) {
+	q := psql.
+		Select(userColumns...).
+		From("users").
+		OrderBy("id DESC").
+		Limit(uint64(limit))
+
+	if query != "" {
+		pattern := "%" + escapeLike(query) + "%"
+		cond := sq.Or{
+			sq.ILike{"email": pattern},
+			sq.ILike{"name": pattern},
+		}
+		if id, err := strconv.ParseInt(query, 10, 64); err == nil {
+			cond = append(cond, sq.Eq{"id": id})
+		}
+		q = q.Where(cond)
+	}
+
+	sqlQuery, args, err := q.ToSql()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	rows, err := r.db.Query(ctx, sqlQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var u User
+		if err := scanUser(rows, &u); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate users: %w", err)
+	}
+
+	return users, nil
+}
+
+// GetProgress считает прогресс пользователя по упражнениям
+func (r *Repository) GetProgress(ctx context.Context, userID int64) (*Progress, error) {
+	query, args, err := psql.
+		Select(
+			"COUNT(*) FILTER (WHERE is_completed)",
+			"COUNT(*)",
+			"COALESCE(SUM(attempts), 0)",
+		).
+		From("user_progress").
+		Where(sq.Eq{"user_id": userID}).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var p Progress
+	if err := r.db.QueryRow(ctx, query, args...).Scan(&p.Completed, &p.Started, &p.Attempts); err != nil {
+		return nil, fmt.Errorf("failed to get progress: %w", err)
+	}
+
+	return &p, nil
+}
+
+// UpdateSubPlan меняет тариф пользователя и возвращает прежний
+//
+// Почему FOR UPDATE: прежний тариф попадает в журнал аудита,
+// два параллельных изменения не должны записать одинаковый "from"
+func (r *Repository) UpdateSubPlan(ctx context.Context, userID int64, plan SubPlan) (SubPlan, error) {
+	var previous SubPlan
+
+	err := pgx.BeginTxFunc(ctx, r.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		query, args, err := psql.
+			Select("sub_plan").
+			From("users").
+			Where(sq.Eq{"id": userID}).
+			Suffix("FOR UPDATE").
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build select query: %w", err)
+		}
+
+		err = tx.QueryRow(ctx, query, args...).Scan(&previous)
+		if err == pgx.ErrNoRows {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get sub plan: %w", err)
+		}
+
+		updateQuery, updateArgs, err := psql.
+			Update("users").
+			Set("sub_plan", plan.String()).
+			Set("updated_at", time.Now().UTC()).
+			Where(sq.Eq{"id": userID}).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build update query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, updateQuery, updateArgs...); err != nil {
+			return fmt.Errorf("failed to update sub plan: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return previous, nil
+}
+
+// UpdatePasswordHash заменяет хеш пароля, если он не менялся с момента чтения (oldHash)
+// Возвращает false, если пароль успели сменить (сброс пароля в другом запросе) -
+// новый пароль нельзя перезаписывать хешем старого
+func (r *Repository) UpdatePasswordHash(ctx context.Context, userID int64, oldHash, newHash string) (bool, error) {
+	query, args, err := psql.
+		Update("users").
+		Set("password_hash", newHash).
+		Where(sq.Eq{"id": userID, "password_hash": oldHash}).
+		ToSql()
+
+	if err != nil {
+		return false, fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return false, fmt.Errorf("failed to update password hash: %w", err)
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// SetSuspended блокирует (suspended = true) или разблокирует аккаунт
+// Возвращает ErrUserNotFound если пользователя нет
+func (r *Repository) SetSuspended(ctx context.Context, userID int64, suspended bool) error {
+	now := time.Now().UTC()
+
+	var suspendedAt *time.Time
+	if suspended {
+		suspendedAt = &now
+	}
+
+	query, args, err := psql.
+		Update("users").
+		Set("suspended_at", suspendedAt).
+		Set("updated_at", now).
+		Where(sq.Eq{"id": userID}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	tag, err := r.db.Exec(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update suspended_at: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// CreatePasswordReset создает токен для сброса пароля
+// Вызывается из формы "Забыли пароль?"
+//
+// Почему удаляем старые неиспользованные токены:
+// - В любой момент времени действительна только последняя ссылка
+// - Старые письма в почтовом ящике перестают работать
+// - Удаление и вставка в одной транзакции - нет окна с двумя токенами
+//
+// Почему 1 час:
+// - Сброс пароля - чувствительная операция, окно атаки должно быть коротким
+// - Пользователь обычно переходит по ссылке сразу после запроса
+//
+// Хеширование токена такое же, как в CreateEmailVerification:
+// в письмо уходит emailToken, в БД хранится только его hash
+func (r *Repository) CreatePasswordReset(ctx context.Context, userID int64) (string, error) {
+	emailToken := generateEmailToken()
+	dbTokenHash := hashEmailToken(emailToken)
+
+	now := time.Now().UTC()
+	expiresAt := now.Add(passwordResetTTL)
+
+	err := pgx.BeginTxFunc(ctx, r.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		deleteQuery, deleteArgs, err := psql.
+			Delete("password_resets").
+			Where(sq.Eq{"user_id": userID}).
+			Where(sq.Eq{"used_at": nil}).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build delete query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, deleteQuery, deleteArgs...); err != nil {
+			return fmt.Errorf("failed to delete old password resets: %w", err)
+		}
+
+		insertQuery, insertArgs, err := psql.
+			Insert("password_resets").
+			Columns("user_id", "token_hash", "created_at", "expires_at").
+			Values(userID, dbTokenHash, now, expiresAt).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build insert query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, insertQuery, insertArgs...); err != nil {
+			return fmt.Errorf("failed to create password reset: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return emailToken, nil
+}
+
+// CheckPasswordReset проверяет что токен сброса пароля существует,
+// не истек и еще не был использован
+// Используется перед показом формы нового пароля, чтобы сразу
+// сообщить пользователю об устаревшей ссылке
+func (r *Repository) CheckPasswordReset(ctx context.Context, emailToken string) error {
+	query, args, err := psql.
+		Select("1").
+		From("password_resets").
+		Where(sq.Eq{"token_hash": hashEmailToken(emailToken)}).
+		Where(sq.Eq{"used_at": nil}).
+		Where(sq.Gt{"expires_at": time.Now().UTC()}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var exists int
+	err = r.db.QueryRow(ctx, query, args...).Scan(&exists)
+	if err == pgx.ErrNoRows {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check password reset: %w", err)
+	}
+
+	return nil
+}
+
+// GetUserByPasswordResetToken возвращает владельца действующего токена сброса пароля
+// Нужен для проверки нового пароля (имя и email в пароле запрещены)
+// Возвращает ErrInvalidToken если токен не найден, истек или уже использован
+func (r *Repository) GetUserByPasswordResetToken(ctx context.Context, emailToken string) (*User, error) {
+	query, args, err := psql.
+		Select(userColumns...).
+		From("users").
+		Where(sq.Expr(
+			"id = (SELECT user_id FROM password_resets WHERE token_hash = ? AND used_at IS NULL AND expires_at > ?)",
+			hashEmailToken(emailToken), time.Now().UTC(),
+		)).
+		ToSql()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	user := &User{}
+	err = scanUser(r.db.QueryRow(ctx, query, args...), user)
+	if err == pgx.ErrNoRows {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password reset user: %w", err)
+	}
+
+	return user, nil
+}
+
+// ResetPassword меняет пароль пользователя по токену сброса
+//
+// Почему транзакция и FOR UPDATE:
+// - Токен одноразовый: два параллельных запроса с одной ссылкой не должны оба поменять пароль
+// - Строка блокируется до commit, второй запрос увидит used_at != NULL
+//
+// Почему не удаляем запись, а ставим used_at:
+// - Остается след для разбора инцидентов (когда и какой токен использован)
+//
+// Возвращает user_id, чтобы handler мог отозвать все сессии пользователя
+func (r *Repository) ResetPassword(ctx context.Context, emailToken, passwordHash string) (int64, error) {
+	dbTokenHash := hashEmailToken(emailToken)
+
+	var userID int64
+
+	err := pgx.BeginTxFunc(ctx, r.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		now := time.Now().UTC()
+
+		query, args, err := psql.
+			Select("user_id").
+			From("password_resets").
+			Where(sq.Eq{"token_hash": dbTokenHash}).
+			Where(sq.Eq{"used_at": nil}).
+			Where(sq.Gt{"expires_at": now}).
+			Suffix("FOR UPDATE").
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build select query: %w", err)
+		}
+
+		err = tx.QueryRow(ctx, query, args...).Scan(&userID)
+		if err == pgx.ErrNoRows {
+			return ErrInvalidToken
+		}
+		if err != nil {
+			return fmt.Errorf("failed to find password reset: %w", err)
+		}
+
+		updateUserQuery, updateUserArgs, err := psql.
+			Update("users").
+			Set("password_hash", passwordHash).
+			Set("updated_at", now).
+			Where(sq.Eq{"id": userID}).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build update query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, updateUserQuery, updateUserArgs...); err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+
+		updateResetQuery, updateResetArgs, err := psql.
+			Update("password_resets").
+			Set("used_at", now).
+			Where(sq.Eq{"token_hash": dbTokenHash}).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build update query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, updateResetQuery, updateResetArgs...); err != nil {
+			return fmt.Errorf("failed to mark password reset as used: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// CreateMagicLink создает одноразовую ссылку для входа без пароля
+// Старые неиспользованные ссылки удаляются в той же транзакции -
+// действительна только последняя (как в CreatePasswordReset)
+func (r *Repository) CreateMagicLink(ctx context.Context, userID int64) (string, error) {
+	emailToken := generateEmailToken()
+	dbTokenHash := hashEmailToken(emailToken)
+
+	now := time.Now().UTC()
+
+	err := pgx.BeginTxFunc(ctx, r.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		deleteQuery, deleteArgs, err := psql.
+			Delete("magic_links").
+			Where(sq.Eq{"user_id": userID}).
+			Where(sq.Eq{"used_at": nil}).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build delete query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, deleteQuery, deleteArgs...); err != nil {
+			return fmt.Errorf("failed to delete old magic links: %w", err)
+		}
+
+		insertQuery, insertArgs, err := psql.
+			Insert("magic_links").
+			Columns("user_id", "token_hash", "created_at", "expires_at").
+			Values(userID, dbTokenHash, now, now.Add(MagicLinkTTL)).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build insert query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, insertQuery, insertArgs...); err != nil {
+			return fmt.Errorf("failed to create magic link: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return emailToken, nil
+}
+
+// CheckMagicLink проверяет что ссылка для входа существует,
+// не истекла и еще не была использована
+// Ничего не меняет - вызывается при открытии ссылки, до подтверждения входа
+func (r *Repository) CheckMagicLink(ctx context.Context, emailToken string) error {
+	query, args, err := psql.
+		Select("1").
+		From("magic_links").
+		Where(sq.Eq{"token_hash": hashEmailToken(emailToken)}).
+		Where(sq.Eq{"used_at": nil}).
+		Where(sq.Gt{"expires_at": time.Now().UTC()}).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build select query: %w", err)
+	}
+
+	var exists int
+	err = r.db.QueryRow(ctx, query, args...).Scan(&exists)
+	if err == pgx.ErrNoRows {
+		return ErrInvalidToken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check magic link: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeMagicLink отмечает ссылку использованной и возвращает user_id
+//
+// Почему FOR UPDATE: два параллельных запроса с одной ссылкой
+// не должны оба создать сессию (как в ResetPassword)
+//
+// Почему is_verified = true: письмо со ссылкой дошло до ящика,
+// это такое же подтверждение email, как ссылка из письма верификации
+func (r *Repository) ConsumeMagicLink(ctx context.Context, emailToken string) (int64, error) {
+	dbTokenHash := hashEmailToken(emailToken)
+
+	var userID int64
+
+	err := pgx.BeginTxFunc(ctx, r.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		now := time.Now().UTC()
+
+		query, args, err := psql.
+			Select("user_id").
+			From("magic_links").
+			Where(sq.Eq{"token_hash": dbTokenHash}).
+			Where(sq.Eq{"used_at": nil}).
+			Where(sq.Gt{"expires_at": now}).
+			Suffix("FOR UPDATE").
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build select query: %w", err)
+		}
+
+		err = tx.QueryRow(ctx, query, args...).Scan(&userID)
+		if err == pgx.ErrNoRows {
+			return ErrInvalidToken
+		}
+		if err != nil {
+			return fmt.Errorf("failed to find magic link: %w", err)
+		}
+
+		updateLinkQuery, updateLinkArgs, err := psql.
+			Update("magic_links").
+			Set("used_at", now).
+			Where(sq.Eq{"token_hash": dbTokenHash}).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build update query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, updateLinkQuery, updateLinkArgs...); err != nil {
+			return fmt.Errorf("failed to mark magic link as used: %w", err)
+		}
+
+		updateUserQuery, updateUserArgs, err := psql.
+			Update("users").
+			Set("is_verified", true).
+			Set("updated_at", now).
+			Where(sq.Eq{"id": userID}).
+			Where(sq.Eq{"is_verified": false}).
+			ToSql()
+
+		if err != nil {
+			return fmt.Errorf("failed to build update query: %w", err)
+		}
+
+		if _, err := tx.Exec(ctx, updateUserQuery, updateUserArgs...); err != nil {
+			return fmt.Errorf("failed to verify user: %w", err)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return userID, nil
+}
+
+// userColumns - колонки users в порядке scanUser
+var userColumns = []string{
+	"id",
+	"name",
+	"email",
+	"password_hash",
+	"phone",
+	"registered_at",
+	"updated_at",
+	"sub_plan",
+	"score",
+	"is_verified",
+	"avatar_url",
+	"suspended_at",
+	RolesColumn("id"),
+}
+
+// scanUser читает строку, выбранную по userColumns
+func scanUser(row pgx.Row, u *User) error {
+	var roles []string
+	err := row.Scan(
+		&u.ID,
+		&u.Name,
+		&u.Email,
+		&u.PasswordHash,
+		&u.Phone,
+		&u.RegisteredAt,
+		&u.UpdatedAt,
+		&u.SubPlan,
+		&u.Score,
+		&u.IsVerified,
+		&u.AvatarURL,
+		&u.SuspendedAt,
+		&roles,
+	)
+	if err != nil {
+		return err
+	}
+
+	u.Roles = ParseRoles(roles)
+	return nil
+}
+
+// RolesColumn - подзапрос ролей для SELECT с пользователем
+// Роли загружаются тем же запросом, что и пользователь (в том числе по сессии и токену)
+// userID - колонка с id пользователя в запросе: "id", "u.id"
+func RolesColumn(userID string) string {
+	return "ARRAY(SELECT role FROM user_roles WHERE user_id = " + userID + " ORDER BY role)"
+}
+
+// escapeLike экранирует спецсимволы LIKE, чтобы поиск "a_b" не совпадал с "axb"
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 // generateEmailToken генерирует токен для отправки в email
 // WHY: Маскирует rand.Text() чтобы токен выглядел как обычный hex hash
 // HOW: rand.Text() → SHA256 → hex string
@@ -303,3 +941,85 @@ func hashEmailToken(emailToken string) string {
 	hash := sha256.Sum256([]byte(emailToken))
 	return hex.EncodeToString(hash[:])
 }
+
+// LoginIdentity находит пользователя по внешнему аккаунту и обновляет last_login_at
+// Возвращает ErrUserNotFound если аккаунт провайдера еще не привязан
+//
+// Почему ищем по (provider, subject), а не по email:
+// - Email у провайдера может смениться, subject - нет
+// - Смена email на GitHub не должна отвязывать аккаунт
+func (r *Repository) LoginIdentity(ctx context.Context, provider, subject string) (int64, error) {
+	query, args, err := psql.
+		Update("user_identities").
+		Set("last_login_at", time.Now().UTC()).
+		Where(sq.Eq{"provider": provider, "subject": subject}).
+		Suffix("RETURNING user_id").
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to build update query: %w", err)
+	}
+
+	var userID int64
+	err = r.db.QueryRow(ctx, query, args...).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get identity: %w", err)
+	}
+
+	return userID, nil
+}
+
+// CreateIdentity привязывает внешний аккаунт к пользователю
+// Принимает tx - при регистрации через провайдера выполняется вместе с CreateExternalUser
+func (r *Repository) CreateIdentity(ctx context.Context, tx pgx.Tx, userID int64, identity ExternalIdentity) error {
+	now := time.Now().UTC()
+
+	query, args, err := psql.
+		Insert("user_identities").
+		Columns("user_id", "provider", "subject", "email", "created_at", "last_login_at").
+		Values(userID, identity.Provider, identity.Subject, identity.Email, now, now).
+		ToSql()
+
+	if err != nil {
+		return fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to create identity: %w", err)
+	}
+
+	return nil
+}
+
+// CreateExternalUser создает пользователя, зарегистрированного через провайдера
+//
+// Отличия от CreateUser:
+// - is_verified: true - email подтвержден провайдером, письмо не нужно
+// - password_hash: пустой - вход по паролю невозможен (password.Hasher не примет пустой hash),
+// пароль можно задать через "Забыли пароль?"
+// - phone: NULL - провайдеры телефон не отдают
+func (r *Repository) CreateExternalUser(ctx context.Context, tx pgx.Tx, identity ExternalIdentity) (int64, error) {
+	now := time.Now().UTC()
+
+	query, args, err := psql.
+		Insert("users").
+		Columns("name", "email", "password_hash", "registered_at", "updated_at", "sub_plan", "score", "is_verified", "avatar_url").
+		Values(identity.Name, identity.Email, "", now, now, SubPlanFree.String(), 0, true, identity.AvatarURL).
+		Suffix("RETURNING id").
+		ToSql()
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to build insert query: %w", err)
+	}
+
+	var userID int64
+	err = tx.QueryRow(ctx, query, args...).Scan(&userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return userID, nil
+}
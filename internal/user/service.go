@@ -2,28 +2,60 @@ package user
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"regexp"
 	"strings"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/udisondev/learn-go/internal/password"
+)
+
+var (
+	// ErrUserNotFound - пользователь с указанным email/ID не существует
+	ErrUserNotFound = errors.New("user not found")
+
+	// ErrInvalidToken - токен из письма не найден, истек или уже использован
+	// Handlers показывают по нему страницу "ссылка недействительна"
+	ErrInvalidToken = errors.New("invalid or expired token")
+
+	// ErrInvalidCredentials - email не зарегистрирован или пароль не подходит
+	// Одна ошибка для обоих случаев - ответ не должен выдавать, есть ли аккаунт
+	ErrInvalidCredentials = errors.New("invalid email or password")
+
+	// ErrIdentityEmailUnverified - провайдер не подтвердил email пользователя
+	// Такому email нельзя доверять ни для регистрации, ни для привязки
+	ErrIdentityEmailUnverified = errors.New("identity email is not verified by provider")
+
+	// ErrIdentityLinkUnverified - аккаунт с таким email есть, но email в нем не подтвержден
+	// Автопривязка запрещена: иначе атакующий, заранее зарегистрировавший
+	// чужой email со своим паролем, получил бы доступ к аккаунту жертвы
+	ErrIdentityLinkUnverified = errors.New("existing account email is not verified")
+
+	// ErrAccountSuspended - аккаунт заблокирован администратором, входить нельзя
+	// никаким способом: паролем, по ссылке, через провайдера
+	ErrAccountSuspended = errors.New("account is suspended")
 )
 
 // Service содержит бизнес-логику для работы с пользователями
 // Отделяет валидацию и бизнес-правила от HTTP handlers
 // Использует Repository для доступа к данным
 type Service struct {
-	repo *Repository
-	db   *pgxpool.Pool
+	repo      *Repository
+	db        *pgxpool.Pool
+	passwords *password.Hasher
+	policy    *password.Policy
 }
 
 // NewService создает новый экземпляр сервиса
-func NewService(db *pgxpool.Pool) *Service {
+func NewService(db *pgxpool.Pool, passwords *password.Hasher, policy *password.Policy) *Service {
 	return &Service{
-		repo: NewRepository(db),
-		db:   db,
+		repo:      NewRepository(db),
+		db:        db,
+		passwords: passwords,
+		policy:    policy,
 	}
 }
 
@@ -70,7 +102,7 @@ type RegisterResult struct {
 // Процесс регистрации:
 // 1. Валидация всех полей (name, email, password, phone)
 // 2. Проверка уникальности email
-// 3. Хеширование пароля с bcrypt
+// 3. Хеширование пароля (argon2id, см. password.Hasher)
 // 4. Создание пользователя в БД (в транзакции)
 // 5. Создание email verification token (в той же транзакции)
 // 6. Возврат user_id и token для отправки email
@@ -101,13 +133,12 @@ func (s *Service) RegisterUser(ctx context.Context, input RegisterInput) (*Regis
 		}
 	}
 
-	// Хешируем пароль с bcrypt
-	// Почему bcrypt:
+	// Хешируем пароль с argon2id
+	// Почему argon2id:
 	// - Специально создан для хеширования паролей (медленный by design)
-	// - Автоматически добавляет salt
-	// - Параметр cost позволяет увеличивать время при росте мощности CPU
-	// - Стандарт для Go (golang.org/x/crypto/bcrypt)
-	passwordHash, err := bcrypt.GenerateFromPassword([]byte(input.Password), bcrypt.DefaultCost)
+	// - Требует много памяти - перебор на GPU становится дорогим
+	// - Salt и параметры хранятся в самом хеше (формат PHC) - их можно менять
+	passwordHash, err := s.passwords.Hash(input.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
@@ -118,7 +149,7 @@ func (s *Service) RegisterUser(ctx context.Context, input RegisterInput) (*Regis
 	// Используем pgx.BeginTxFunc для автоматического commit/rollback
 	err = pgx.BeginTxFunc(ctx, s.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
 		// Создаем пользователя
-		userID, err := s.repo.CreateUser(ctx, tx, input.Name, input.Email, string(passwordHash), input.Phone)
+		userID, err := s.repo.CreateUser(ctx, tx, input.Name, input.Email, passwordHash, input.Phone)
 		if err != nil {
 			return fmt.Errorf("failed to create user: %w", err)
 		}
@@ -141,11 +172,105 @@ func (s *Service) RegisterUser(ctx context.Context, input RegisterInput) (*Regis
 	return &result, nil
 }
 
+// Authenticate проверяет email и пароль
+//
+// При неверном пароле возвращает ErrInvalidCredentials вместе с найденным
+// пользователем (nil, если email не зарегистрирован) - вызывающему коду аккаунт
+// нужен для журнала аудита и письма о блокировке, но ответ клиенту должен быть одинаковым
+//
+// Почему проверка здесь, а не в handler:
+// - Для неизвестного email выполняется та же работа (фиктивный хеш) -
+// по времени ответа нельзя перебирать зарегистрированные адреса
+// - Хеш с устаревшими параметрами (или bcrypt) перехешируется, пока известен пароль
+func (s *Service) Authenticate(ctx context.Context, email, pass string) (*User, error) {
+	u, err := s.repo.GetUserByEmail(ctx, email)
+	if errors.Is(err, ErrUserNotFound) {
+		s.passwords.VerifyDummy(pass)
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ok, needsRehash, err := s.passwords.Verify(u.PasswordHash, pass)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify password of user %d: %w", u.ID, err)
+	}
+	if !ok {
+		return u, ErrInvalidCredentials
+	}
+
+	if needsRehash {
+		s.rehashPassword(ctx, u, pass)
+	}
+
+	return u, nil
+}
+
+// rehashPassword заменяет хеш пароля на хеш с текущими параметрами
+// Ошибка только логируется: вход уже состоялся, старый хеш продолжает работать
+func (s *Service) rehashPassword(ctx context.Context, u *User, pass string) {
+	newHash, err := s.passwords.Hash(pass)
+	if err != nil {
+		slog.Error("Failed to rehash password", "error", err, "user_id", u.ID)
+		return
+	}
+
+	updated, err := s.repo.UpdatePasswordHash(ctx, u.ID, u.PasswordHash, newHash)
+	if err != nil {
+		slog.Error("Failed to store rehashed password", "error", err, "user_id", u.ID)
+		return
+	}
+	if updated {
+		u.PasswordHash = newHash
+		slog.Info("Password hash upgraded", "user_id", u.ID)
+	}
+}
+
 // GetUserByEmail возвращает пользователя по email
 func (s *Service) GetUserByEmail(ctx context.Context, email string) (*User, error) {
 	return s.repo.GetUserByEmail(ctx, email)
 }
 
+// GetUserByID возвращает пользователя по ID
+func (s *Service) GetUserByID(ctx context.Context, userID int64) (*User, error) {
+	return s.repo.GetUserByID(ctx, userID)
+}
+
+// SearchUsers ищет пользователей по ID, email или имени (для админки)
+func (s *Service) SearchUsers(ctx context.Context, query string, limit int) ([]User, error) {
+	return s.repo.SearchUsers(ctx, strings.TrimSpace(query), limit)
+}
+
+// GetProgress возвращает прогресс пользователя по упражнениям
+func (s *Service) GetProgress(ctx context.Context, userID int64) (*Progress, error) {
+	return s.repo.GetProgress(ctx, userID)
+}
+
+// ChangeSubPlan меняет тариф пользователя
+// Возвращает прежний тариф - для журнала аудита
+//
+// Вызывающий код обязан сбросить кеш сессий пользователя (session.Service.InvalidateUser),
+// иначе новый тариф не будет виден до истечения кеша
+func (s *Service) ChangeSubPlan(ctx context.Context, userID int64, plan SubPlan) (SubPlan, error) {
+	if !plan.IsValid() {
+		return 0, fmt.Errorf("invalid sub plan %d", plan)
+	}
+	return s.repo.UpdateSubPlan(ctx, userID, plan)
+}
+
+// Suspend блокирует аккаунт
+// Сессии и токены заблокированного пользователя перестают приниматься при чтении,
+// но вызывающий код все равно удаляет его сессии - чтобы не висели на странице устройств
+func (s *Service) Suspend(ctx context.Context, userID int64) error {
+	return s.repo.SetSuspended(ctx, userID, true)
+}
+
+// Unsuspend снимает блокировку аккаунта
+func (s *Service) Unsuspend(ctx context.Context, userID int64) error {
+	return s.repo.SetSuspended(ctx, userID, false)
+}
+
 // VerifyEmail верифицирует email пользователя по токену
 // Вызывается когда пользователь переходит по ссылке из письма
 //
@@ -176,13 +301,317 @@ func (s *Service) VerifyEmail(ctx context.Context, emailToken string) (int64, er
 	return userID, nil
 }
 
+// VerificationRequest содержит данные для повторной отправки письма верификации
+type VerificationRequest struct {
+	UserID   int64
+	UserName string
+	Email    string
+	Token    string
+}
+
+// ResendVerification выпускает новую ссылку верификации для email
+// Старые ссылки перестают работать
+//
+// Почему возвращаем (nil, nil) для несуществующего или уже подтвержденного email:
+// - Как и в RequestPasswordReset - ответ не должен раскрывать, зарегистрирован ли email
+// - Handler показывает одинаковое сообщение "если аккаунт есть - письмо отправлено"
+func (s *Service) ResendVerification(ctx context.Context, email string) (*VerificationRequest, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, ValidationErrors{
+			{Field: "email", Message: "Email обязателен для заполнения"},
+		}
+	}
+	if !isValidEmail(email) {
+		return nil, ValidationErrors{
+			{Field: "email", Message: "Некорректный формат email"},
+		}
+	}
+
+	u, err := s.repo.GetUserByEmail(ctx, email)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if u.IsVerified {
+		return nil, nil
+	}
+
+	token, err := s.repo.ReplaceEmailVerification(ctx, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create verification: %w", err)
+	}
+
+	return &VerificationRequest{
+		UserID:   u.ID,
+		UserName: u.Name,
+		Email:    u.Email,
+		Token:    token,
+	}, nil
+}
+
+// PasswordResetRequest содержит данные для отправки письма со ссылкой сброса
+type PasswordResetRequest struct {
+	UserID   int64
+	UserName string
+	Email    string
+	Token    string
+}
+
+// RequestPasswordReset создает токен сброса пароля для пользователя с данным email
+//
+// Почему возвращаем (nil, nil) для несуществующего email:
+// - Handler всегда показывает одинаковый ответ "письмо отправлено"
+// - По ответу нельзя определить, зарегистрирован ли email (user enumeration)
+// - Отличить случай "письмо не нужно" handler может по nil результату
+func (s *Service) RequestPasswordReset(ctx context.Context, email string) (*PasswordResetRequest, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, ValidationErrors{
+			{Field: "email", Message: "Email обязателен для заполнения"},
+		}
+	}
+	if !isValidEmail(email) {
+		return nil, ValidationErrors{
+			{Field: "email", Message: "Некорректный формат email"},
+		}
+	}
+
+	u, err := s.repo.GetUserByEmail(ctx, email)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	token, err := s.repo.CreatePasswordReset(ctx, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create password reset: %w", err)
+	}
+
+	return &PasswordResetRequest{
+		UserID:   u.ID,
+		UserName: u.Name,
+		Email:    u.Email,
+		Token:    token,
+	}, nil
+}
+
+// MagicLinkRequest содержит данные для письма со ссылкой для входа
+type MagicLinkRequest struct {
+	UserID   int64
+	UserName string
+	Email    string
+	Token    string
+}
+
+// RequestMagicLink создает ссылку для входа без пароля
+// Как и RequestPasswordReset, возвращает (nil, nil) для несуществующего email -
+// ответ пользователю одинаковый, перебрать аккаунты нельзя
+func (s *Service) RequestMagicLink(ctx context.Context, email string) (*MagicLinkRequest, error) {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil, ValidationErrors{
+			{Field: "email", Message: "Email обязателен для заполнения"},
+		}
+	}
+	if !isValidEmail(email) {
+		return nil, ValidationErrors{
+			{Field: "email", Message: "Некорректный формат email"},
+		}
+	}
+
+	u, err := s.repo.GetUserByEmail(ctx, email)
+	if errors.Is(err, ErrUserNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	token, err := s.repo.CreateMagicLink(ctx, u.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create magic link: %w", err)
+	}
+
+	return &MagicLinkRequest{
+		UserID:   u.ID,
+		UserName: u.Name,
+		Email:    u.Email,
+		Token:    token,
+	}, nil
+}
+
+// CheckMagicLinkToken проверяет что ссылка для входа еще действительна
+// Возвращает ErrInvalidToken если токен не найден, истек или уже использован
+func (s *Service) CheckMagicLinkToken(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrInvalidToken
+	}
+	return s.repo.CheckMagicLink(ctx, token)
+}
+
+// ConsumeMagicLink использует ссылку для входа и возвращает user_id
+// Вызывающий код создает сессию (или запрашивает второй фактор)
+// Для заблокированного аккаунта ссылка тратится, но возвращается ErrAccountSuspended
+func (s *Service) ConsumeMagicLink(ctx context.Context, token string) (int64, error) {
+	if token == "" {
+		return 0, ErrInvalidToken
+	}
+
+	userID, err := s.repo.ConsumeMagicLink(ctx, token)
+	if err != nil {
+		return 0, err
+	}
+
+	u, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get user: %w", err)
+	}
+	if u.IsSuspended() {
+		return 0, ErrAccountSuspended
+	}
+
+	return userID, nil
+}
+
+// CheckPasswordResetToken проверяет что ссылка сброса пароля еще действительна
+// Возвращает ErrInvalidToken если токен не найден, истек или уже использован
+func (s *Service) CheckPasswordResetToken(ctx context.Context, token string) error {
+	if token == "" {
+		return ErrInvalidToken
+	}
+	return s.repo.CheckPasswordReset(ctx, token)
+}
+
+// ResetPasswordInput содержит данные формы установки нового пароля
+type ResetPasswordInput struct {
+	Token           string
+	Password        string
+	PasswordConfirm string
+}
+
+// ResetPassword устанавливает новый пароль по токену из письма
+//
+// Процесс:
+// 1. Валидация нового пароля (те же правила, что при регистрации, с именем и email владельца токена)
+// 2. Хеширование пароля
+// 3. В транзакции: проверка токена (expires_at, used_at), смена пароля, used_at = now
+//
+// Возвращает user_id - вызывающий код обязан отозвать все сессии пользователя,
+// иначе украденная сессия переживет смену пароля
+func (s *Service) ResetPassword(ctx context.Context, input ResetPasswordInput) (int64, error) {
+	if input.Token == "" {
+		return 0, ErrInvalidToken
+	}
+
+	u, err := s.repo.GetUserByPasswordResetToken(ctx, input.Token)
+	if err != nil {
+		return 0, err
+	}
+
+	personal := password.Personal{Name: u.Name, Email: u.Email}
+	if errs := s.validatePassword(input.Password, input.PasswordConfirm, personal); len(errs) > 0 {
+		return 0, errs
+	}
+
+	passwordHash, err := s.passwords.Hash(input.Password)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	userID, err := s.repo.ResetPassword(ctx, input.Token, passwordHash)
+	if err != nil {
+		return 0, fmt.Errorf("reset password failed: %w", err)
+	}
+
+	return userID, nil
+}
+
+// SignInWithIdentity выполняет вход через внешнего провайдера (GitHub, OIDC)
+// Вызывается после успешного OAuth2 обмена кода на токен
+//
+// Порядок поиска аккаунта:
+// 1. Внешний аккаунт уже привязан (provider, subject) - вход в привязанный аккаунт
+// 2. Есть пользователь с тем же подтвержденным email - привязываем и входим
+// 3. Иначе - регистрируем нового пользователя, email считается подтвержденным
+//
+// Почему 2 и 3 требуют email, подтвержденный провайдером:
+// - Иначе любой мог бы указать у провайдера чужой email и войти в чужой аккаунт
+// - Именно поэтому подтвержденный провайдером email заменяет письмо с верификацией
+//
+// Заблокированный аккаунт (случаи 1 и 2) - ErrAccountSuspended
+func (s *Service) SignInWithIdentity(ctx context.Context, identity ExternalIdentity) (*User, error) {
+	userID, err := s.repo.LoginIdentity(ctx, identity.Provider, identity.Subject)
+	if err == nil {
+		u, err := s.repo.GetUserByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if u.IsSuspended() {
+			return nil, ErrAccountSuspended
+		}
+		return u, nil
+	}
+	if !errors.Is(err, ErrUserNotFound) {
+		return nil, err
+	}
+
+	if !identity.EmailVerified || identity.Email == "" {
+		return nil, ErrIdentityEmailUnverified
+	}
+
+	existing, err := s.repo.GetUserByEmail(ctx, identity.Email)
+	if err != nil && !errors.Is(err, ErrUserNotFound) {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if existing != nil {
+		if !existing.IsVerified {
+			return nil, ErrIdentityLinkUnverified
+		}
+		if existing.IsSuspended() {
+			return nil, ErrAccountSuspended
+		}
+
+		err = pgx.BeginTxFunc(ctx, s.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+			return s.repo.CreateIdentity(ctx, tx, existing.ID, identity)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to link identity: %w", err)
+		}
+
+		return existing, nil
+	}
+
+	if strings.TrimSpace(identity.Name) == "" {
+		identity.Name = strings.Split(identity.Email, "@")[0]
+	}
+
+	err = pgx.BeginTxFunc(ctx, s.db, pgx.TxOptions{}, func(tx pgx.Tx) error {
+		userID, err = s.repo.CreateExternalUser(ctx, tx, identity)
+		if err != nil {
+			return err
+		}
+		return s.repo.CreateIdentity(ctx, tx, userID, identity)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register identity: %w", err)
+	}
+
+	return s.repo.GetUserByID(ctx, userID)
+}
+
 // validateRegisterInput валидирует все поля регистрации
 // Возвращает список ошибок (может быть несколько ошибок одновременно)
 //
 // Правила валидации:
 // - Name: обязательное, 2-100 символов
 // - Email: обязательное, валидный email формат
-// - Password: обязательное, минимум 8 символов, содержит буквы и цифры
+// - Password: обязательное, правила password.Policy (длина, стойкость, без имени и email, не из утечек)
 // - Phone: опциональное, если указан - валидный формат телефона
 func (s *Service) validateRegisterInput(input RegisterInput) ValidationErrors {
 	var errors ValidationErrors
@@ -220,47 +649,80 @@ func (s *Service) validateRegisterInput(input RegisterInput) ValidationErrors {
 		})
 	}
 
-	// Валидация пароля
-	if input.Password == "" {
+	// Валидация пароля и его подтверждения
+	personal := password.Personal{Name: input.Name, Email: input.Email}
+	errors = append(errors, s.validatePassword(input.Password, input.PasswordConfirm, personal)...)
+
+	// Валидация телефона (опциональное поле)
+	input.Phone = strings.TrimSpace(input.Phone)
+	if input.Phone != "" && !isValidPhone(input.Phone) {
 		errors = append(errors, ValidationError{
-			Field:   "password",
-			Message: "Пароль обязателен для заполнения",
+			Field:   "phone",
+			Message: "Некорректный формат телефона",
 		})
-	} else if len(input.Password) < 8 {
+	}
+
+	return errors
+}
+
+// validatePassword валидирует пароль и его подтверждение
+// Общие правила для регистрации и сброса пароля
+// Для поля password возвращается одна ошибка - первое нарушенное правило
+func (s *Service) validatePassword(pass, passwordConfirm string, personal password.Personal) ValidationErrors {
+	var errors ValidationErrors
+
+	if pass == "" {
 		errors = append(errors, ValidationError{
 			Field:   "password",
-			Message: "Пароль должен содержать минимум 8 символов",
+			Message: "Пароль обязателен для заполнения",
 		})
-	} else if !isValidPassword(input.Password) {
+	} else if msg := s.passwordPolicyMessage(pass, personal); msg != "" {
 		errors = append(errors, ValidationError{
 			Field:   "password",
-			Message: "Пароль должен содержать буквы и цифры",
+			Message: msg,
 		})
 	}
 
-	// Валидация подтверждения пароля
-	if input.PasswordConfirm == "" {
+	if passwordConfirm == "" {
 		errors = append(errors, ValidationError{
 			Field:   "password_confirm",
 			Message: "Подтверждение пароля обязательно для заполнения",
 		})
-	} else if input.Password != input.PasswordConfirm {
+	} else if pass != passwordConfirm {
 		errors = append(errors, ValidationError{
 			Field:   "password_confirm",
 			Message: "Пароли не совпадают",
 		})
 	}
 
-	// Валидация телефона (опциональное поле)
-	input.Phone = strings.TrimSpace(input.Phone)
-	if input.Phone != "" && !isValidPhone(input.Phone) {
-		errors = append(errors, ValidationError{
-			Field:   "phone",
-			Message: "Некорректный формат телефона",
-		})
+	return errors
+}
+
+// passwordPolicyMessage проверяет пароль по password.Policy
+// Возвращает текст ошибки для пользователя, пустую строку - если пароль подходит
+//
+// Если базу утекших паролей не удалось прочитать, проверка пропускается:
+// остальные правила уже выполнены, а регистрация не должна ломаться из-за файла
+func (s *Service) passwordPolicyMessage(pass string, personal password.Personal) string {
+	violation, err := s.policy.Check(pass, personal)
+	if err != nil {
+		slog.Error("Password policy check failed", "error", err)
 	}
 
-	return errors
+	switch violation {
+	case password.ViolationTooShort:
+		return fmt.Sprintf("Пароль должен содержать минимум %d символов", s.policy.MinLength())
+	case password.ViolationTooLong:
+		return "Пароль слишком длинный"
+	case password.ViolationPersonalInfo:
+		return "Пароль не должен содержать ваше имя или email"
+	case password.ViolationTooWeak:
+		return "Пароль слишком простой: сделайте его длиннее или добавьте заглавные буквы, цифры и символы"
+	case password.ViolationBreached:
+		return "Этот пароль встречается в утечках данных и легко подбирается. Выберите другой"
+	default:
+		return ""
+	}
 }
 
 // isValidEmail проверяет формат email
@@ -275,17 +737,6 @@ func isValidEmail(email string) bool {
 	return emailRegex.MatchString(email)
 }
 
-// isValidPassword проверяет что пароль содержит буквы и цифры
-// Почему такая проверка:
-// - Баланс между безопасностью и UX
-// - Требование спецсимволов часто раздражает пользователей
-// - Длина (8+ символов) + буквы + цифры = достаточная энтропия
-func isValidPassword(password string) bool {
-	hasLetter := regexp.MustCompile(`[a-zA-Z]`).MatchString(password)
-	hasDigit := regexp.MustCompile(`[0-9]`).MatchString(password)
-	return hasLetter && hasDigit
-}
-
 // isValidPhone проверяет формат телефона
 // Принимает форматы: +7XXXXXXXXXX, 8XXXXXXXXXX, 7XXXXXXXXXX
 // Почему такая валидация:
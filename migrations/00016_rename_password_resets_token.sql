@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE password_resets RENAME COLUMN token TO token_hash;
+ALTER INDEX idx_password_resets_token RENAME TO idx_password_resets_token_hash;
+CREATE INDEX idx_password_resets_user_id ON password_resets(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_password_resets_user_id;
+ALTER INDEX idx_password_resets_token_hash RENAME TO idx_password_resets_token;
+ALTER TABLE password_resets RENAME COLUMN token_hash TO token;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- Session lifetime policy
+-- WHY: Sessions expire by absolute lifetime (created_at) and idle timeout (last_seen_at)
+-- HOW: Sweeper deletes by these columns, index them to avoid full scans
+
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX idx_sessions_created_at ON sessions(created_at);
+CREATE INDEX idx_sessions_last_seen_at ON sessions(last_seen_at);
+
+COMMENT ON TABLE sessions IS 'Active user sessions, expire by absolute lifetime and idle timeout';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_sessions_last_seen_at;
+DROP INDEX IF EXISTS idx_sessions_created_at;
+
+COMMENT ON TABLE sessions IS 'Active user sessions for authentication (permanent until logout)';
+-- +goose StatementEnd
@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- TOTP secret per user
+-- enabled_at IS NULL means enrollment started but not confirmed with a code
+-- last_used_step protects against replaying the same code twice
+CREATE TABLE user_two_factor (
+    user_id BIGINT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_encrypted TEXT NOT NULL,
+    enabled_at TIMESTAMPTZ,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Single-use recovery codes, stored hashed
+CREATE TABLE user_recovery_codes (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_user_recovery_codes_user_id ON user_recovery_codes(user_id, code_hash);
+
+-- Pending logins: password verified, waiting for the second factor
+CREATE TABLE two_factor_challenges (
+    token_hash VARCHAR PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_two_factor_challenges_expires_at ON two_factor_challenges(expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS two_factor_challenges;
+DROP TABLE IF EXISTS user_recovery_codes;
+DROP TABLE IF EXISTS user_two_factor;
+-- +goose StatementEnd
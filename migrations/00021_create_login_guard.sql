@@ -0,0 +1,32 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Failed password attempts, keyed by account (normalized email) and by IP
+-- Stored for any email, even unknown ones - otherwise throttling would reveal
+-- which accounts exist
+CREATE TABLE failed_logins (
+    id BIGSERIAL PRIMARY KEY,
+    email VARCHAR NOT NULL,
+    ip_address TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_failed_logins_email ON failed_logins(email, created_at);
+CREATE INDEX idx_failed_logins_ip_address ON failed_logins(ip_address, created_at);
+
+-- Temporary account lockouts with a one-click unlock link sent by email
+CREATE TABLE login_lockouts (
+    email VARCHAR PRIMARY KEY,
+    locked_until TIMESTAMPTZ NOT NULL,
+    unlock_token_hash VARCHAR NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_login_lockouts_unlock_token_hash ON login_lockouts(unlock_token_hash);
+CREATE INDEX idx_login_lockouts_locked_until ON login_lockouts(locked_until);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS login_lockouts;
+DROP TABLE IF EXISTS failed_logins;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Personal access tokens for API and CLI clients
+-- Only SHA256 of the token is stored, the token itself is shown once on creation
+CREATE TABLE access_tokens (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(64) NOT NULL,
+    token_hash VARCHAR NOT NULL UNIQUE,
+    token_prefix VARCHAR NOT NULL,
+    scopes TEXT[] NOT NULL,
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    last_used_ip TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_access_tokens_user_id ON access_tokens(user_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS access_tokens;
+-- +goose StatementEnd
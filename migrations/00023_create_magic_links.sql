@@ -0,0 +1,21 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Single-use passwordless sign-in links sent by email
+-- Same token scheme as password_resets: only SHA256 of the emailed token is stored
+CREATE TABLE magic_links (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_magic_links_user_id ON magic_links(user_id);
+CREATE INDEX idx_magic_links_expires_at ON magic_links(expires_at);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS magic_links;
+-- +goose StatementEnd
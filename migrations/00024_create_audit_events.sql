@@ -0,0 +1,25 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Security audit log: logins, logouts, password and session changes
+-- user_id is NULL for failed logins with unknown email (the email is in details)
+-- ON DELETE SET NULL keeps the trail after the account is deleted
+CREATE TABLE audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+    event_type VARCHAR(64) NOT NULL,
+    ip_address TEXT,
+    user_agent TEXT,
+    request_id TEXT,
+    details JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_events_user_id ON audit_events(user_id, id DESC);
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
+CREATE INDEX idx_audit_events_ip_address ON audit_events(ip_address);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS audit_events;
+-- +goose StatementEnd
@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Staff roles on top of the default student role (author, mentor, admin)
+-- Student is implicit and never stored
+-- The first admin is granted by hand:
+-- INSERT INTO user_roles (user_id, role) VALUES (<id>, 'admin');
+CREATE TABLE user_roles (
+    user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(16) NOT NULL,
+    granted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, role)
+);
+
+CREATE INDEX idx_user_roles_role ON user_roles(role);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_roles;
+-- +goose StatementEnd
@@ -0,0 +1,10 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Suspended accounts can't log in, their sessions and access tokens stop working
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMPTZ;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN suspended_at;
+-- +goose StatementEnd
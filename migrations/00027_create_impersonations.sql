@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Admin sessions that act as another user (support looking at what the learner sees)
+-- session_id has no FK: sessions may live in memory (SESSION_STORE=memory)
+CREATE TABLE impersonations (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    target_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    session_id UUID NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    ended_at TIMESTAMPTZ
+);
+
+-- At most one active impersonation per admin session
+CREATE UNIQUE INDEX idx_impersonations_active ON impersonations(session_id) WHERE ended_at IS NULL;
+CREATE INDEX idx_impersonations_target_id ON impersonations(target_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS impersonations;
+-- +goose StatementEnd
@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Rate limit buckets, one row per "policy:key" (GCRA)
+-- tat - theoretical arrival time: when the bucket is full again,
+-- rows with tat in the past are swept
+CREATE TABLE rate_limits (
+    key TEXT PRIMARY KEY,
+    tat TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX idx_rate_limits_tat ON rate_limits(tat);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS rate_limits;
+-- +goose StatementEnd
@@ -0,0 +1,24 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Lease of a 'processing' task: the worker holding it and until when.
+-- A worker extends locked_until while sending; once it passes (worker crashed),
+-- the task is dequeued again
+ALTER TABLE email_queue
+    ADD COLUMN locked_until TIMESTAMPTZ,
+    ADD COLUMN worker_id TEXT;
+
+-- Tasks stuck in 'processing' before leases existed - reclaim right away
+UPDATE email_queue SET locked_until = NOW() WHERE status = 'processing';
+
+CREATE INDEX idx_email_queue_locked_until
+ON email_queue(locked_until)
+WHERE status = 'processing';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_email_queue_locked_until;
+ALTER TABLE email_queue
+    DROP COLUMN IF EXISTS worker_id,
+    DROP COLUMN IF EXISTS locked_until;
+-- +goose StatementEnd
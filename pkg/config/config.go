@@ -1,6 +1,9 @@
 package config
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/caarlos0/env/v11"
@@ -9,12 +12,23 @@ import (
 
 // Config holds application configuration
 type Config struct {
-	App      AppConfig
-	DB       DBConfig
-	Session  SessionConfig
-	CSRF     CSRFConfig
-	Email    EmailConfig
-	Executor ExecutorConfig
+	App           AppConfig
+	Proxy         ProxyConfig
+	Security      SecurityConfig
+	DB            DBConfig
+	Session       SessionConfig
+	TwoFactor     TwoFactorConfig
+	OAuth         OAuthConfig
+	Password      PasswordConfig
+	Login         LoginGuardConfig
+	APIToken      APITokenConfig
+	Audit         AuditConfig
+	Impersonation ImpersonationConfig
+	RateLimit     RateLimitConfig
+	CSRF          CSRFConfig
+	Email         EmailConfig
+	EmailQueue    EmailQueueConfig
+	Executor      ExecutorConfig
 }
 
 type AppConfig struct {
@@ -22,39 +36,224 @@ type AppConfig struct {
 	Port     string `env:"APP_PORT" envDefault:"8080"`
 	Host     string `env:"APP_HOST" envDefault:"localhost"`
 	LogLevel string `env:"APP_LOG_LEVEL" envDefault:"info"`
+	BaseURL  string `env:"BASE_URL" envDefault:"http://localhost:8080"` // public URL for links and OAuth callbacks
+}
+
+// ProxyConfig - which reverse proxies may report the client IP
+// With nothing set forwarding headers are ignored (direct exposure)
+type ProxyConfig struct {
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:"," envDefault:""` // CIDRs or IPs of own proxies/load balancers
+	Hops           int      `env:"TRUSTED_PROXY_HOPS" envDefault:"0"`              // proxies in front of the app (0 - trust by CIDR only)
+	Header         string   `env:"CLIENT_IP_HEADER" envDefault:"x-forwarded-for"`  // x-forwarded-for, x-real-ip or forwarded
+}
+
+// SecurityConfig - browser security headers, the header set itself depends on APP_ENV
+type SecurityConfig struct {
+	HSTSMaxAge    time.Duration `env:"SECURITY_HSTS_MAX_AGE" envDefault:"8760h"` // production only, 0 - don't send HSTS
+	CSPReportOnly bool          `env:"CSP_REPORT_ONLY" envDefault:"false"`       // report violations without blocking (policy rollout)
 }
 
 type DBConfig struct {
-	Host            string        `env:"DB_HOST" envDefault:"localhost"`
-	Port            string        `env:"DB_PORT" envDefault:"5432"`
-	User            string        `env:"DB_USER" envDefault:"postgres"`
-	Password        string        `env:"DB_PASSWORD" envDefault:"postgres"`
-	Name            string        `env:"DB_NAME" envDefault:"learn_go"`
-	SSLMode         string        `env:"DB_SSL_MODE" envDefault:"disable"`
-	MaxOpenConns    int           `env:"DB_MAX_OPEN_CONNS" envDefault:"25"`
-	MaxIdleConns    int           `env:"DB_MAX_IDLE_CONNS" envDefault:"5"`
-	ConnMaxLifetime time.Duration `env:"DB_CONN_MAX_LIFETIME" envDefault:"5m"`
-	ConnMaxIdleTime time.Duration `env:"DB_CONN_MAX_IDLE_TIME" envDefault:"30m"`
+	Host              string        `env:"DB_HOST" envDefault:"localhost"`
+	Port              string        `env:"DB_PORT" envDefault:"5432"`
+	User              string        `env:"DB_USER" envDefault:"postgres"`
+	Password          string        `env:"DB_PASSWORD" envDefault:"postgres"`
+	Name              string        `env:"DB_NAME" envDefault:"learn_go"`
+	SSLMode           string        `env:"DB_SSL_MODE" envDefault:"disable"`
+	MaxOpenConns      int           `env:"DB_MAX_OPEN_CONNS" envDefault:"25"`
+	MaxIdleConns      int           `env:"DB_MAX_IDLE_CONNS" envDefault:"5"`
+	ConnMaxLifetime   time.Duration `env:"DB_CONN_MAX_LIFETIME" envDefault:"5m"`
+	ConnMaxIdleTime   time.Duration `env:"DB_CONN_MAX_IDLE_TIME" envDefault:"30m"`
 	HealthCheckPeriod time.Duration `env:"DB_HEALTH_CHECK_PERIOD" envDefault:"1m"`
 }
 
 type SessionConfig struct {
-	Secret string `env:"SESSION_SECRET" envDefault:"change-me"`
-	MaxAge int    `env:"SESSION_MAX_AGE" envDefault:"86400"` // seconds
-	Secure bool   `env:"SESSION_SECURE" envDefault:"false"`  // true in production for HTTPS
+	MaxAge        int           `env:"SESSION_MAX_AGE" envDefault:"86400"`      // absolute session lifetime, seconds (0 - unlimited)
+	IdleTimeout   time.Duration `env:"SESSION_IDLE_TIMEOUT" envDefault:"12h"`   // session dies after this long without requests (0 - disabled)
+	Secure        bool          `env:"SESSION_SECURE" envDefault:"false"`       // true in production for HTTPS
+	TouchInterval time.Duration `env:"SESSION_TOUCH_INTERVAL" envDefault:"5m"`  // min interval between last_seen_at updates and cookie renewals
+	SweepInterval time.Duration `env:"SESSION_SWEEP_INTERVAL" envDefault:"10m"` // how often expired sessions are deleted
+	GeoIPFile     string        `env:"SESSION_GEOIP_FILE" envDefault:""`        // CIDR,location CSV for "active devices" page
+	Store         string        `env:"SESSION_STORE" envDefault:"postgres"`     // postgres or memory (tests, local dev)
+	CacheSize     int           `env:"SESSION_CACHE_SIZE" envDefault:"10000"`   // max cached sessions per instance (0 - no cache)
+	CacheTTL      time.Duration `env:"SESSION_CACHE_TTL" envDefault:"30s"`      // how long a revoked session may stay valid on other instances
+}
+
+// DefaultTOTPEncryptionKey - envDefault of TOTP_ENCRYPTION_KEY; it is public,
+// secrets encrypted with it are as good as plaintext - development only
+const DefaultTOTPEncryptionKey = "change-me-totp-encryption-key"
+
+type TwoFactorConfig struct {
+	Issuer        string        `env:"TOTP_ISSUER" envDefault:"Learn Go"`                              // shown in authenticator apps
+	EncryptionKey string        `env:"TOTP_ENCRYPTION_KEY" envDefault:"change-me-totp-encryption-key"` // encrypts TOTP secrets at rest
+	ChallengeTTL  time.Duration `env:"TOTP_CHALLENGE_TTL" envDefault:"5m"`                             // time to enter the code after password
+}
+
+// PasswordConfig - password policy and argon2id parameters for new password hashes
+// Hashes with other parameters (or bcrypt) are upgraded on the next successful login
+type PasswordConfig struct {
+	MinLength   int     `env:"PASSWORD_MIN_LENGTH" envDefault:"10"`  // characters
+	MinEntropy  float64 `env:"PASSWORD_MIN_ENTROPY" envDefault:"50"` // estimated bits, see password.Entropy
+	BreachedDir string  `env:"PASSWORD_BREACHED_DIR"`                // breached password hash ranges (<PREFIX>.txt), empty - check disabled
+
+	Memory      uint32 `env:"PASSWORD_ARGON2_MEMORY" envDefault:"65536"`   // KiB per hash (64 MiB)
+	Iterations  uint32 `env:"PASSWORD_ARGON2_ITERATIONS" envDefault:"3"`   // passes over memory
+	Parallelism uint8  `env:"PASSWORD_ARGON2_PARALLELISM" envDefault:"2"`  // lanes (threads)
+	SaltLength  uint32 `env:"PASSWORD_ARGON2_SALT_LENGTH" envDefault:"16"` // bytes
+	KeyLength   uint32 `env:"PASSWORD_ARGON2_KEY_LENGTH" envDefault:"32"`  // bytes
+}
+
+// LoginGuardConfig - brute-force protection for password login
+// Delay doubles with every failure after DelayAfter: 1s, 2s, 4s ... up to DelayMax
+type LoginGuardConfig struct {
+	Window           time.Duration `env:"LOGIN_ATTEMPT_WINDOW" envDefault:"15m"`   // failures older than this are forgotten
+	DelayAfter       int           `env:"LOGIN_DELAY_AFTER" envDefault:"3"`        // failures per account before delays start
+	DelayBase        time.Duration `env:"LOGIN_DELAY_BASE" envDefault:"1s"`        // first delay
+	DelayMax         time.Duration `env:"LOGIN_DELAY_MAX" envDefault:"30s"`        // delay cap
+	LockoutThreshold int           `env:"LOGIN_LOCKOUT_THRESHOLD" envDefault:"10"` // failures per account before lockout
+	LockoutDuration  time.Duration `env:"LOGIN_LOCKOUT_DURATION" envDefault:"30m"` // lockout length (or until unlock link is used)
+	IPMaxFailures    int           `env:"LOGIN_IP_MAX_FAILURES" envDefault:"50"`   // failures per IP (any accounts) before IP is blocked for Window
+	SweepInterval    time.Duration `env:"LOGIN_SWEEP_INTERVAL" envDefault:"10m"`   // how often old attempts are deleted
+}
+
+// APITokenConfig - personal access tokens for API and CLI clients
+type APITokenConfig struct {
+	MaxPerUser    int           `env:"API_TOKEN_MAX_PER_USER" envDefault:"20"`   // tokens per user, expired included (0 - unlimited)
+	TouchInterval time.Duration `env:"API_TOKEN_TOUCH_INTERVAL" envDefault:"1m"` // min interval between last_used_at updates
+}
+
+// AuditConfig - security audit log (logins, password and session changes)
+type AuditConfig struct {
+	Retention     time.Duration `env:"AUDIT_RETENTION" envDefault:"8760h"`     // events older than this are deleted (0 - keep forever)
+	SweepInterval time.Duration `env:"AUDIT_SWEEP_INTERVAL" envDefault:"24h"`  // how often old events are deleted
+	RecentLimit   int           `env:"AUDIT_RECENT_LIMIT" envDefault:"50"`     // events on the user's "recent activity" page
+	MaxQueryLimit int           `env:"AUDIT_MAX_QUERY_LIMIT" envDefault:"500"` // max events per admin query
+}
+
+// ImpersonationConfig - admins acting as another user (support)
+type ImpersonationConfig struct {
+	TTL time.Duration `env:"IMPERSONATION_TTL" envDefault:"30m"` // impersonation ends automatically after this time
+}
+
+// RateLimitConfig - named per-route rate limit policies, "count/period" each
+type RateLimitConfig struct {
+	Store         string        `env:"RATE_LIMIT_STORE" envDefault:"postgres"`     // postgres (shared by all replicas) or memory
+	SweepInterval time.Duration `env:"RATE_LIMIT_SWEEP_INTERVAL" envDefault:"10m"` // how often replenished keys are deleted
+	Login         Rate          `env:"RATE_LIMIT_LOGIN" envDefault:"20/1m"`        // login, 2FA, magic link and email forms, per IP
+	Register      Rate          `env:"RATE_LIMIT_REGISTER" envDefault:"5/1h"`      // registrations, per IP
+	Submit        Rate          `env:"RATE_LIMIT_SUBMIT" envDefault:"30/1m"`       // solution submissions, per user
+	ResendEmail   Rate          `env:"RATE_LIMIT_RESEND_EMAIL" envDefault:"1/2m"`  // verification and login link emails, per address
+	CSPReport     Rate          `env:"RATE_LIMIT_CSP_REPORT" envDefault:"30/1m"`   // CSP violation reports, per IP
+}
+
+// Rate is a rate limit "count/period", e.g. "20/1m"
+// All count requests may come at once, then they are replenished evenly over period
+type Rate struct {
+	Count  int
+	Period time.Duration
+}
+
+// UnmarshalText parses "count/period" from environment
+func (r *Rate) UnmarshalText(text []byte) error {
+	count, period, ok := strings.Cut(string(text), "/")
+	if !ok {
+		return fmt.Errorf("invalid rate %q: want count/period, e.g. 20/1m", text)
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || n <= 0 {
+		return fmt.Errorf("invalid rate %q: count must be a positive integer", text)
+	}
+
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return fmt.Errorf("invalid rate %q: period must be a positive duration", text)
+	}
+
+	r.Count = n
+	r.Period = d
+	return nil
+}
+
+type OAuthConfig struct {
+	StateTTL time.Duration `env:"OAUTH_STATE_TTL" envDefault:"10m"` // time to finish login at the provider
+	GitHub   GitHubOAuthConfig
+}
+
+// GitHubOAuthConfig - provider is enabled when ClientID is set
+// Endpoints are configurable to run against a fake IdP locally
+type GitHubOAuthConfig struct {
+	ClientID     string `env:"GITHUB_CLIENT_ID" envDefault:""`
+	ClientSecret string `env:"GITHUB_CLIENT_SECRET" envDefault:""`
+	AuthURL      string `env:"GITHUB_AUTH_URL" envDefault:"https://github.com/login/oauth/authorize"`
+	TokenURL     string `env:"GITHUB_TOKEN_URL" envDefault:"https://github.com/login/oauth/access_token"`
+	APIURL       string `env:"GITHUB_API_URL" envDefault:"https://api.github.com"`
 }
 
 type CSRFConfig struct {
-	Secret string `env:"CSRF_SECRET" envDefault:"32-byte-long-csrf-secret-key-change-in-production"`
-	Secure bool   `env:"CSRF_SECURE" envDefault:"false"` // true in production
+	Secret         string   `env:"CSRF_SECRET" envDefault:"32-byte-long-csrf-secret-key-change-in-production"`
+	Secure         bool     `env:"CSRF_SECURE" envDefault:"false"`                      // true in production
+	TrustedOrigins []string `env:"CSRF_TRUSTED_ORIGINS" envSeparator:"," envDefault:""` // extra hosts allowed in Origin/Referer (e.g. "learn-go.dev")
 }
 
+// EmailConfig - how the verificator delivers emails
+// Driver: smtp (Mailhog locally), api (provider's HTTP sending API),
+// outbox (maildir on disk, nothing is sent) or memory (tests)
 type EmailConfig struct {
-	Host     string `env:"SMTP_HOST" envDefault:"localhost"`      // Mailhog default: localhost
-	Port     int    `env:"SMTP_PORT" envDefault:"1025"`           // Mailhog default: 1025
-	Username string `env:"SMTP_USERNAME" envDefault:""`           // Mailhog doesn't need auth
-	Password string `env:"SMTP_PASSWORD" envDefault:""`           // Mailhog doesn't need auth
-	From     string `env:"SMTP_FROM" envDefault:"noreply@learn-go.local"`
+	Driver     string        `env:"EMAIL_DRIVER" envDefault:"smtp"`
+	From       string        `env:"EMAIL_FROM" envDefault:"noreply@learn-go.local"`
+	FromName   string        `env:"EMAIL_FROM_NAME" envDefault:"Learn Go"` // display name in From
+	SMTP       SMTPConfig    // EMAIL_DRIVER=smtp
+	APIURL     string        `env:"EMAIL_API_URL" envDefault:"https://send.api.mailtrap.io"` // EMAIL_DRIVER=api, base URL of the sending API
+	APIToken   string        `env:"EMAIL_API_TOKEN"`                                         // EMAIL_DRIVER=api, bearer token
+	APITimeout time.Duration `env:"EMAIL_API_TIMEOUT" envDefault:"30s"`                      // EMAIL_DRIVER=api, per request
+	OutboxDir  string        `env:"EMAIL_OUTBOX_DIR" envDefault:"tmp/outbox"`                // EMAIL_DRIVER=outbox, maildir with new/, cur/, tmp/
+}
+
+// EmailQueueConfig - how the verificator takes tasks from email_queue
+// Workers wake on NOTIFY from Enqueue; PollInterval is only the fallback
+// for a lost LISTEN connection and for retries becoming due
+type EmailQueueConfig struct {
+	Workers      int           `env:"EMAIL_QUEUE_WORKERS" envDefault:"4"`        // concurrent sends
+	BatchSize    int           `env:"EMAIL_QUEUE_BATCH_SIZE" envDefault:"10"`    // max tasks leased per query
+	PollInterval time.Duration `env:"EMAIL_QUEUE_POLL_INTERVAL" envDefault:"5s"` // check for due tasks without a notification
+	// Lease of a task being sent, extended every Lease/3 while sending;
+	// a task of a crashed worker is sent again after it expires
+	Lease           time.Duration `env:"EMAIL_QUEUE_LEASE" envDefault:"2m"`
+	ShutdownTimeout time.Duration `env:"EMAIL_QUEUE_SHUTDOWN_TIMEOUT" envDefault:"30s"` // in-flight sends are cut off after
+}
+
+// MinEmailQueueLease - shortest EMAIL_QUEUE_LEASE: the heartbeat must get
+// a few tries to extend the lease before it expires
+const MinEmailQueueLease = 3 * time.Second
+
+// Validate rejects values the worker pool can't run with
+func (c EmailQueueConfig) Validate() error {
+	switch {
+	case c.Workers < 1:
+		return fmt.Errorf("EMAIL_QUEUE_WORKERS must be at least 1, got %d", c.Workers)
+	case c.BatchSize < 1:
+		return fmt.Errorf("EMAIL_QUEUE_BATCH_SIZE must be at least 1, got %d", c.BatchSize)
+	case c.PollInterval <= 0:
+		return fmt.Errorf("EMAIL_QUEUE_POLL_INTERVAL must be positive, got %v", c.PollInterval)
+	case c.Lease < MinEmailQueueLease:
+		return fmt.Errorf("EMAIL_QUEUE_LEASE must be at least %v, got %v", MinEmailQueueLease, c.Lease)
+	case c.ShutdownTimeout < 0:
+		return fmt.Errorf("EMAIL_QUEUE_SHUTDOWN_TIMEOUT must not be negative, got %v", c.ShutdownTimeout)
+	}
+	return nil
+}
+
+type SMTPConfig struct {
+	Host     string `env:"SMTP_HOST" envDefault:"localhost"` // Mailhog default: localhost
+	Port     int    `env:"SMTP_PORT" envDefault:"1025"`      // Mailhog default: 1025
+	Username string `env:"SMTP_USERNAME" envDefault:""`      // Mailhog doesn't need auth
+	Password string `env:"SMTP_PASSWORD" envDefault:""`      // Mailhog doesn't need auth
+	// TLS: none (Mailhog), starttls (required, usually port 587) or implicit (port 465)
+	TLS          string        `env:"SMTP_TLS" envDefault:"none"`
+	Timeout      time.Duration `env:"SMTP_TIMEOUT" envDefault:"30s"`      // dial and every command
+	IdleTimeout  time.Duration `env:"SMTP_IDLE_TIMEOUT" envDefault:"1m"`  // idle connection is closed after
+	MaxIdleConns int           `env:"SMTP_MAX_IDLE_CONNS" envDefault:"2"` // connections kept open between emails
 }
 
 type ExecutorConfig struct {
@@ -79,5 +278,9 @@ func Load() (*Config, error) {
 		return nil, err
 	}
 
+	if err := cfg.EmailQueue.Validate(); err != nil {
+		return nil, err
+	}
+
 	return cfg, nil
 }